@@ -15,9 +15,11 @@ import (
 	db "github.com/theduke/go-dukedb"
 
 	kit "github.com/app-kit/go-appkit"
+	"github.com/app-kit/go-appkit/app/methods"
 	"github.com/app-kit/go-appkit/caches/fs"
 	"github.com/app-kit/go-appkit/crawler"
 	"github.com/app-kit/go-appkit/files"
+	"github.com/app-kit/go-appkit/ratelimit"
 	"github.com/app-kit/go-appkit/resources"
 	"github.com/app-kit/go-appkit/tasks"
 	"github.com/app-kit/go-appkit/users"
@@ -96,6 +98,8 @@ func (a *App) Defaults() {
 	// Register fs cache.
 	a.BuildDefaultCache()
 
+	a.BuildDefaultRateLimiter()
+
 	a.BuildDefaultFrontends()
 	a.BuildDefaultMethods()
 }
@@ -105,7 +109,21 @@ func (a *App) BuildDefaultMethods() {
 	a.RegisterMethod(updateMethod)
 	a.RegisterMethod(deleteMethod)
 	a.RegisterMethod(queryMethod)
+	a.RegisterMethod(deleteByQueryMethod)
 	a.RegisterMethod(findOneMethod)
+	a.RegisterMethod(listMethodsMethod)
+	a.RegisterMethod(batchMethod)
+	a.RegisterMethod(taskStatusMethod)
+	a.RegisterMethod(restoreMethod)
+	a.RegisterMethod(purgeMethod)
+	a.RegisterMethod(listVersionsMethod)
+	a.RegisterMethod(diffVersionsMethod)
+	a.RegisterMethod(revertVersionMethod)
+	a.RegisterMethod(cacheStatsMethod)
+	a.RegisterMethod(listGrantsMethod)
+	a.RegisterMethod(shareMethod)
+	a.RegisterMethod(unshareMethod)
+	a.RegisterMethod(schemaMethod)
 }
 
 func (a *App) BuildDefaultFrontends() {
@@ -117,7 +135,14 @@ func (a *App) BuildDefaultFrontends() {
 }
 
 func (a *App) BuildDefaultSerializers() {
-	a.RegisterSerializer(jsonapiserializer.New(a.registry.Backends()))
+	serializer := jsonapiserializer.New(a.registry.Backends())
+
+	// Computed fields are read-only.
+	for _, res := range a.registry.Resources() {
+		serializer.SetReadOnlyAttributes(res.Collection(), res.ComputedFieldNames())
+	}
+
+	a.RegisterSerializer(serializer)
 }
 
 func (a *App) BuildDefaultLogger() {
@@ -142,6 +167,47 @@ func (a *App) BuildDefaultTaskService(b db.Backend) {
 	a.registry.SetTaskService(s)
 }
 
+func (a *App) BuildDefaultRateLimiter() {
+	a.registry.SetRateLimiter(ratelimit.New(ratelimit.NewMemoryStore()))
+}
+
+// configureRateLimiter applies the rateLimit config settings to the rate
+// limiter.
+// The limiter is switched to a cache store if rateLimit.store is "cache".
+// The cache specified by rateLimit.cache or the default cache is used.
+// This is done right before running, since the cache might be registered
+// after the app was created.
+// rateLimit.trustedProxies lists the ips or CIDR ranges of the proxies
+// whose X-Forwarded-For header is used to find the client ip.
+func (a *App) configureRateLimiter() {
+	limiter := a.registry.RateLimiter()
+	if limiter == nil {
+		return
+	}
+
+	proxies := make([]string, 0)
+	for _, proxy := range a.Config().UList("rateLimit.trustedProxies") {
+		proxies = append(proxies, fmt.Sprintf("%v", proxy))
+	}
+	if err := limiter.SetTrustedProxies(proxies); err != nil {
+		a.Logger().Panicf("Invalid rateLimit.trustedProxies setting: %v", err)
+	}
+
+	if a.Config().UString("rateLimit.store", "memory") != "cache" {
+		return
+	}
+
+	cache := a.registry.DefaultCache()
+	if name := a.Config().UString("rateLimit.cache"); name != "" {
+		cache = a.registry.Cache(name)
+	}
+	if cache == nil {
+		a.Logger().Panicf("Rate limiter is configured to use a cache, but the cache does not exist")
+	}
+
+	limiter.SetStore(ratelimit.NewCacheStore(cache))
+}
+
 func (a *App) BuildDefaultCache() {
 	// Build cache.
 	dir := a.registry.Config().UString("caches.fs.dir")
@@ -308,8 +374,14 @@ func (a *App) PrepareForRun() {
 		}
 	}
 
+	// Serve the OpenAPI document.
+	if a.registry.HttpFrontend() != nil {
+		a.RegisterHttpHandler("GET", "/"+a.Config().UString("api.prefix", "api")+"/openapi.json", openApiHandler)
+	}
+
 	if a.defaults {
 		a.BuildDefaultSerializers()
+		a.configureRateLimiter()
 	}
 }
 
@@ -319,12 +391,17 @@ func (a *App) Run() {
 	// Run taskrunner if possible.
 	if service := a.Registry().TaskService(); service != nil {
 		if runner, ok := service.(kit.TaskRunner); ok {
+			a.registerAsyncMethods(runner)
+			a.registerPurgeDeletedTask(runner)
+			a.registerImportTask(runner)
 			if err := runner.Run(); err != nil {
 				panic("Could not start task runner: " + err.Error())
 			}
 		}
 	}
 
+	a.subscribeMethodCaches()
+
 	// Run the session manager.
 	a.sessionManager = NewSessionManager(a)
 	a.sessionManager.Run()
@@ -489,6 +566,10 @@ func (a *App) TemplateEngine() kit.TemplateEngine {
  */
 
 func (a *App) RegisterMethod(method kit.Method) {
+	// Build the argument schema, so that invalid arg tags panic on
+	// registration instead of on the first call.
+	method.GetArguments()
+
 	a.registry.AddMethod(method)
 }
 
@@ -501,6 +582,46 @@ func (a *App) RunMethod(name string, r kit.Request, responder func(kit.Response)
 		}
 	}
 
+	// Verify permissions and validate the arguments before the method is queued.
+	rawData, err := a.prepareMethod(method, r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Async methods are run by the task runner.
+	// The responder immediately receives the task id.
+	if method.IsAsync() {
+		response, err := a.queueAsyncMethod(method, r, rawData)
+		if err != nil {
+			return nil, err
+		}
+		return respondImmediately(response, responder, withFinishedChannel), nil
+	}
+
+	// Serve cached results without queuing the method.
+	if method.GetCache() != nil && methods.MethodCache(a.registry, method) != nil {
+		key, err := methods.CacheKey(method, r, rawData)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := methods.CachedResponse(a.registry, method, key)
+		if err != nil {
+			a.Logger().Errorf("Could not read cached result of method %v: %v", name, err)
+		} else if response != nil {
+			return respondImmediately(response, responder, withFinishedChannel), nil
+		}
+
+		// Cache the result once the method has finished.
+		methodResponder := responder
+		responder = func(response kit.Response) {
+			if err := methods.CacheResponse(a.registry, method, key, response); err != nil {
+				a.Logger().Errorf("Could not cache result of method %v: %v", name, err)
+			}
+			methodResponder(response)
+		}
+	}
+
 	if r.GetSession() == nil {
 		session, err := a.UserService().StartSession(r.GetUser(), "")
 		if err != nil {
@@ -520,6 +641,56 @@ func (a *App) RunMethod(name string, r kit.Request, responder func(kit.Response)
 	}
 }
 
+// prepareMethod applies the rate limit, the permissions and the argument
+// schema of a method to a request.
+// Returns the request data as it was before the arguments were decoded.
+func (a *App) prepareMethod(method kit.Method, r kit.Request) (interface{}, apperror.Error) {
+	if policy := method.GetRateLimit(); policy != nil && a.registry.RateLimiter() != nil {
+		if err := a.registry.RateLimiter().Check(policy, r); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := methods.CheckPermissions(method, r.GetUser()); err != nil {
+		return nil, err
+	}
+
+	rawData := r.GetData()
+	if err := methods.PrepareArguments(method, r); err != nil {
+		return nil, err
+	}
+
+	return rawData, nil
+}
+
+// respondImmediately passes a response to the responder of a method that
+// is not queued.
+func respondImmediately(response kit.Response, responder func(kit.Response), withFinishedChannel bool) chan bool {
+	responder(response)
+
+	if !withFinishedChannel {
+		return nil
+	}
+	c := make(chan bool, 1)
+	c <- true
+	return c
+}
+
+// subscribeMethodCaches clears the cached method results that depend on a
+// collection whenever a model of the collection is written.
+func (a *App) subscribeMethodCaches() {
+	handler := func(data interface{}) {
+		if model, ok := data.(kit.Model); ok {
+			methods.ClearCollectionCaches(a.registry, model.Collection())
+		}
+	}
+
+	bus := a.registry.EventBus()
+	bus.Subscribe(kit.EventResourceCreated, handler)
+	bus.Subscribe(kit.EventResourceUpdated, handler)
+	bus.Subscribe(kit.EventResourceDeleted, handler)
+}
+
 /**
  * Http routes.
  */
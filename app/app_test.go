@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -19,6 +20,7 @@ import (
 
 	kit "github.com/app-kit/go-appkit"
 	. "github.com/app-kit/go-appkit/app"
+	"github.com/app-kit/go-appkit/app/methods"
 	"github.com/app-kit/go-appkit/users"
 	"github.com/app-kit/go-appkit/users/auth/oauth"
 )
@@ -94,6 +96,8 @@ func buildApp() kit.App {
 	fbService := oauth.NewFacebook(appId, appSecret)
 	userService.AuthAdaptor("oauth").(*oauth.AuthAdaptorOauth).RegisterService(fbService)
 
+	registerQueueMethods(app)
+
 	// Persist log messages in logMessages.
 	app.Logger().Hooks.Add(LoggerHook{})
 
@@ -102,6 +106,47 @@ func buildApp() kit.App {
 	return app
 }
 
+// slowRunning is set while the handler of the test.slow method runs, and
+// slowOverlapped records if test.next started during that time.
+var slowLock sync.Mutex
+var slowRunning bool
+var slowOverlapped bool
+
+// registerQueueMethods registers a blocking method that ignores its timeout,
+// and a blocking method that records whether it overlaps with the first one.
+func registerQueueMethods(app kit.App) {
+	app.RegisterMethod(&methods.Method{
+		Name:     "test.slow",
+		Blocking: true,
+		Timeout:  50 * time.Millisecond,
+		Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
+			slowLock.Lock()
+			slowRunning = true
+			slowLock.Unlock()
+
+			time.Sleep(300 * time.Millisecond)
+
+			slowLock.Lock()
+			slowRunning = false
+			slowLock.Unlock()
+
+			return &kit.AppResponse{Data: "slow"}
+		},
+	})
+
+	app.RegisterMethod(&methods.Method{
+		Name:     "test.next",
+		Blocking: true,
+		Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
+			slowLock.Lock()
+			slowOverlapped = slowRunning
+			slowLock.Unlock()
+
+			return &kit.AppResponse{Data: "next"}
+		},
+	})
+}
+
 type Data struct {
 	Data   interface{}            `json:"data"`
 	Errors []apperror.Error       `json:"errors"`
@@ -360,6 +405,88 @@ var _ = Describe("App", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(user).ToNot(BeNil())
 			})
+
+			It("Should run batch calls with references", func() {
+				if skipUser {
+					Skip("Previous error")
+				}
+
+				js := fmt.Sprintf(`{"data": {"calls": [
+					{"method": "find_one", "data": {"collection": "users", "id": "%v"}},
+					{"method": "find_one", "data": {"collection": "users", "id": {"$ref": "0.data.id"}}}
+				]}}`, currentUser.GetStrId())
+				status, data, err := client.PostJson("/api/batch", js)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status).To(Equal(200))
+
+				results := data.Data.([]interface{})
+				Expect(results).To(HaveLen(2))
+				Expect(GetNested(results[1], "data.id")).To(Equal(currentUser.GetStrId()))
+			})
+		})
+	})
+
+	Describe("Method queue", func() {
+		It("Should time out a method but keep blocking until its handler returns", func() {
+			session, err := registry.UserService().StartSession(nil, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			run := func(name string) (chan bool, *kit.Response) {
+				r := kit.NewRequest()
+				r.SetSession(session)
+
+				var response kit.Response
+				finished, err := app.RunMethod(name, r, func(resp kit.Response) {
+					response = resp
+				}, true)
+				Expect(err).ToNot(HaveOccurred())
+				return finished, &response
+			}
+
+			slowFinished, slowResponse := run("test.slow")
+			nextFinished, nextResponse := run("test.next")
+
+			<-slowFinished
+			Expect((*slowResponse).GetError()).ToNot(BeNil())
+			Expect((*slowResponse).GetError().GetCode()).To(Equal("method_timeout"))
+
+			<-nextFinished
+			Expect((*nextResponse).GetError()).To(BeNil())
+
+			slowLock.Lock()
+			defer slowLock.Unlock()
+			Expect(slowOverlapped).To(BeFalse())
+		})
+
+		It("Should run concurrent batches that fill the queue", func() {
+			session, err := registry.UserService().StartSession(nil, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			// methods.maxRunning defaults to 5.
+			channels := make([]chan bool, 0)
+			responses := make([]kit.Response, 5)
+			for i := 0; i < 5; i++ {
+				index := i
+
+				r := kit.NewRequest()
+				r.SetSession(session)
+				r.SetData(map[string]interface{}{
+					"calls": []interface{}{
+						map[string]interface{}{"method": "test.next"},
+					},
+				})
+
+				finished, err := app.RunMethod("batch", r, func(resp kit.Response) {
+					responses[index] = resp
+				}, true)
+				Expect(err).ToNot(HaveOccurred())
+				channels = append(channels, finished)
+			}
+
+			for index, finished := range channels {
+				Eventually(finished, 2).Should(Receive())
+				Expect(responses[index].GetError()).To(BeNil())
+			}
 		})
 	})
 
@@ -0,0 +1,151 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/theduke/go-apperror"
+	"github.com/theduke/go-reflector"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/app/methods"
+	"github.com/app-kit/go-appkit/tasks"
+)
+
+// asyncTaskName returns the name of the task that runs an async method.
+func asyncTaskName(method kit.Method) string {
+	return "methods." + method.GetName()
+}
+
+// asyncMethodTask builds the task spec that runs an async method.
+func asyncMethodTask(method kit.Method) kit.TaskSpec {
+	return &tasks.TaskSpec{
+		Name: asyncTaskName(method),
+		Handler: func(registry kit.Registry, task kit.Task, progressChan chan kit.Task) (interface{}, apperror.Error, bool) {
+			data, _ := task.GetData().(map[string]interface{})
+
+			r := kit.NewRequest()
+			r.SetFrontend("tasks")
+			r.SetPath("/method/" + method.GetName())
+			r.SetData(data["data"])
+			r.SetGoContext(WithTask(r.GetGoContext(), task, progressChan))
+
+			if userId := task.GetUserId(); userId != nil && !reflector.R(userId).IsZero() {
+				user, err := registry.UserService().FindUser(userId)
+				if err != nil {
+					return nil, err, false
+				} else if user == nil {
+					return nil, apperror.New("user_not_found", "The user who started the task does not exist"), false
+				}
+				r.SetUser(user)
+			}
+
+			if err := PrepareArguments(method, r); err != nil {
+				return nil, err, false
+			}
+
+			response := method.GetHandler()(registry, r, func() {})
+
+			// Serialize the result with the serializer of the frontend that
+			// started the method.
+			serializer := registry.DefaultSerializer()
+			if name, _ := data["serializer"].(string); name != "" {
+				if s := registry.Serializer(name); s != nil {
+					serializer = s
+				}
+			}
+			result, _ := serializer.MustSerializeResponse(response)
+
+			err := response.GetError()
+
+			// Publish the final result.
+			task.SetResult(result)
+			task.SetIsComplete(true)
+			task.SetIsSuccess(err == nil)
+			if err != nil {
+				task.SetError(err.Error())
+			}
+			PublishTaskStatus(registry, task)
+
+			return result, err, false
+		},
+	}
+}
+
+// queueAsyncMethod creates a task for an async method and returns a
+// response with the task id.
+func (a *App) queueAsyncMethod(method kit.Method, r kit.Request, rawData interface{}) (kit.Response, apperror.Error) {
+	service := a.registry.TaskService()
+	if service == nil {
+		return nil, &apperror.Err{
+			Code:    "tasks_disabled",
+			Message: fmt.Sprintf("The method %v requires the task service, which is not enabled", method.GetName()),
+			Public:  true,
+		}
+	}
+
+	serializer := r.GetContext().String("response-serializer")
+	if serializer == "" && a.registry.DefaultSerializer() != nil {
+		serializer = a.registry.DefaultSerializer().Name()
+	}
+
+	task := service.NewTask()
+	task.SetName(asyncTaskName(method))
+	task.SetData(map[string]interface{}{
+		"method":     method.GetName(),
+		"data":       rawData,
+		"serializer": serializer,
+	})
+	if user := r.GetUser(); user != nil {
+		task.SetUserId(user.GetId())
+	}
+
+	if err := service.Queue(task); err != nil {
+		return nil, err
+	}
+
+	return &kit.AppResponse{
+		Data: map[string]interface{}{
+			"taskId": task.GetStrId(),
+		},
+	}, nil
+}
+
+// registerAsyncMethods registers the tasks for all async methods with the
+// task runner.
+func (a *App) registerAsyncMethods(runner kit.TaskRunner) {
+	for _, method := range a.registry.Methods() {
+		if method.IsAsync() {
+			runner.RegisterTask(asyncMethodTask(method))
+		}
+	}
+}
+
+var taskStatusMethod kit.Method = &Method{
+	Name:     "tasks.status",
+	Blocking: false,
+	Arguments: []*kit.Argument{
+		{Name: "id", Type: kit.ArgumentTypeString, Required: true},
+	},
+	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
+		service := registry.TaskService()
+		if service == nil {
+			return kit.NewErrorResponse("tasks_disabled", "The task service is not enabled", true)
+		}
+
+		id := r.GetData().(map[string]interface{})["id"].(string)
+		task, err := service.GetTask(id)
+		if err != nil {
+			return kit.NewErrorResponse(err)
+		} else if task == nil {
+			return kit.NewErrorResponse("not_found", "Task does not exist.", true)
+		}
+
+		if !CanAccessTask(r.GetUser(), task) {
+			return kit.NewErrorResponse("permission_denied", true)
+		}
+
+		return &kit.AppResponse{
+			Data: TaskStatus(task),
+		}
+	},
+}
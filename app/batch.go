@@ -0,0 +1,307 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/theduke/go-apperror"
+	db "github.com/theduke/go-dukedb"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/app/methods"
+	"github.com/app-kit/go-appkit/resources"
+)
+
+// requestResource returns the resource for a collection.
+// If the request runs inside a transaction, the returned resource uses
+// the transaction as its backend.
+func requestResource(registry kit.Registry, collection string, r kit.Request) kit.Resource {
+	res := registry.Resource(collection)
+	if res == nil {
+		return nil
+	}
+
+	if rawTx, ok := r.GetContext().Get("transaction"); ok {
+		if tx, ok := rawTx.(db.Transaction); ok && res.Backend() == registry.DefaultBackend() {
+			return res.WithBackend(tx)
+		}
+	}
+
+	return res
+}
+
+type batchCall struct {
+	Method string                 `json:"method" arg:"required"`
+	Data   map[string]interface{} `json:"data"`
+}
+
+type batchArguments struct {
+	Calls  []batchCall `json:"calls" arg:"required;min:1"`
+	Atomic bool        `json:"atomic"`
+}
+
+// resolveReferences replaces all {"$ref": "<index>.<path>"} values in data
+// with the referenced value from the results of previous calls.
+func resolveReferences(data interface{}, results []interface{}) (interface{}, apperror.Error) {
+	switch val := data.(type) {
+	case map[string]interface{}:
+		if ref, ok := val["$ref"].(string); ok && len(val) == 1 {
+			return resolveReference(ref, results)
+		}
+
+		resolved := make(map[string]interface{})
+		for key, item := range val {
+			r, err := resolveReferences(item, results)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = r
+		}
+		return resolved, nil
+
+	case []interface{}:
+		resolved := make([]interface{}, 0)
+		for _, item := range val {
+			r, err := resolveReferences(item, results)
+			if err != nil {
+				return nil, err
+			}
+			resolved = append(resolved, r)
+		}
+		return resolved, nil
+	}
+
+	return data, nil
+}
+
+func resolveReference(ref string, results []interface{}) (interface{}, apperror.Error) {
+	invalid := func(msg string) apperror.Error {
+		return &apperror.Err{
+			Code:    "invalid_reference",
+			Message: fmt.Sprintf("Invalid reference %v: %v", ref, msg),
+			Public:  true,
+			Status:  400,
+		}
+	}
+
+	parts := strings.Split(ref, ".")
+	index, err := strconv.Atoi(parts[0])
+	if err != nil || index < 0 || index >= len(results) {
+		return nil, invalid("must start with the index of a previous call")
+	}
+
+	var current interface{} = results[index]
+	for _, part := range parts[1:] {
+		switch val := current.(type) {
+		case map[string]interface{}:
+			item, ok := val[part]
+			if !ok {
+				return nil, invalid(fmt.Sprintf("key %v does not exist", part))
+			}
+			current = item
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(val) {
+				return nil, invalid(fmt.Sprintf("index %v does not exist", part))
+			}
+			current = val[i]
+		default:
+			return nil, invalid(fmt.Sprintf("can not access %v", part))
+		}
+	}
+
+	return current, nil
+}
+
+// runBatchCall runs a single call of a batch through the method queue of
+// the session, so that the limits and the blocking of methods apply.
+// The call runs inside the transaction of atomic batches.
+func runBatchCall(registry kit.Registry, r kit.Request, call batchCall, tx db.Transaction) kit.Response {
+	if call.Method == "batch" {
+		return kit.NewErrorResponse("nested_batch", "Batches can not be nested", true)
+	}
+
+	method := registry.Method(call.Method)
+	if method == nil {
+		return kit.NewErrorResponse(&apperror.Err{
+			Code:    "unknown_method",
+			Message: fmt.Sprintf("The method %v does not exist", call.Method),
+			Public:  true,
+		})
+	}
+
+	request := kit.NewRequest()
+	request.SetFrontend(r.GetFrontend())
+	request.SetPath("/method/" + call.Method)
+	request.SetGoContext(r.GetGoContext())
+	request.SetSession(r.GetSession())
+	request.SetUser(r.GetUser())
+	request.SetData(map[string]interface{}{"data": call.Data})
+
+	if tx != nil {
+		request.GetContext().Set("transaction", tx)
+	}
+
+	if err := request.Unserialize(registry.DefaultSerializer()); err != nil {
+		return kit.NewErrorResponse(err, "request_unserialize_error", true)
+	}
+
+	app := registry.App().(*App)
+
+	rawData, err := app.prepareMethod(method, request)
+	if err != nil {
+		return kit.NewErrorResponse(err)
+	}
+
+	if method.IsAsync() {
+		if tx != nil {
+			return kit.NewErrorResponse("async_method_in_transaction", fmt.Sprintf("The async method %v can not run in an atomic batch", call.Method), true)
+		}
+
+		response, err := app.queueAsyncMethod(method, request, rawData)
+		if err != nil {
+			return kit.NewErrorResponse(err)
+		}
+		return response
+	}
+
+	return app.sessionManager.RunNestedMethod(method, request)
+}
+
+var batchMethod kit.Method = &Method{
+	Name:            "batch",
+	Blocking:        false,
+	ArgumentsStruct: batchArguments{},
+	Handler: func(registry kit.Registry, r kit.Request, unblock func()) (response kit.Response) {
+		args, ok := r.GetData().(*batchArguments)
+		if !ok {
+			return kit.NewErrorResponse(UnpreparedArgumentsError())
+		}
+
+		maxCalls := registry.Config().UInt("methods.batchMaxCalls", 50)
+		if len(args.Calls) > maxCalls {
+			return kit.NewErrorResponse(&apperror.Err{
+				Code:    "too_many_calls",
+				Message: fmt.Sprintf("A batch may contain at most %v calls", maxCalls),
+				Public:  true,
+				Status:  400,
+			})
+		}
+
+		serializer := registry.DefaultSerializer()
+		if name := r.GetContext().String("response-serializer"); name != "" {
+			if s := registry.Serializer(name); s != nil {
+				serializer = s
+			}
+		}
+
+		var tx db.Transaction
+		if args.Atomic {
+			backend, ok := registry.DefaultBackend().(db.TransactionBackend)
+			if !ok {
+				return kit.NewErrorResponse("transactions_unsupported", "The default backend does not support transactions", true)
+			}
+
+			rawTx, err := backend.Begin()
+			if err != nil {
+				return kit.NewErrorResponse(err)
+			}
+			// Resources defer cache invalidation and events until the
+			// commit.
+			tx = resources.NewTransaction(rawTx)
+
+			// Roll back if the batch panics, and fail the batch instead.
+			defer func() {
+				if rawErr := recover(); rawErr != nil {
+					registry.Logger().Errorf("Atomic batch paniced: %v", rawErr)
+					if err := tx.Rollback(); err != nil {
+						registry.Logger().Errorf("Could not roll back batch: %v", err)
+					}
+					response = kit.NewErrorResponse(&apperror.Err{
+						Code:    "batch_failed",
+						Message: "The batch failed and was rolled back",
+						Public:  true,
+						Status:  500,
+					})
+				}
+			}()
+		}
+
+		// Results holds the serialized responses in a generic form, so that
+		// later calls can reference them.
+		results := make([]interface{}, 0)
+		var failed apperror.Error
+		failedIndex := -1
+
+		ctx := r.GetGoContext()
+
+		for index, call := range args.Calls {
+			// Stop once the batch timed out or the client disconnected.
+			if ctx.Err() != nil {
+				break
+			}
+
+			var callResponse kit.Response
+
+			data, err := resolveReferences(call.Data, results)
+			if err != nil {
+				callResponse = kit.NewErrorResponse(err)
+			} else {
+				call.Data, _ = data.(map[string]interface{})
+				callResponse = runBatchCall(registry, r, call, tx)
+			}
+
+			serialized, _ := serializer.MustSerializeResponse(callResponse)
+
+			var result interface{}
+			js, jsonErr := json.Marshal(serialized)
+			if jsonErr == nil {
+				jsonErr = json.Unmarshal(js, &result)
+			}
+			if jsonErr != nil {
+				result = serialized
+			}
+			results = append(results, result)
+
+			if callResponse.GetError() != nil && args.Atomic {
+				failed = callResponse.GetError()
+				failedIndex = index
+				break
+			}
+		}
+
+		// The caller already received a cancellation error, so the
+		// transaction must not be committed.
+		if ctx.Err() != nil {
+			if tx != nil {
+				if err := tx.Rollback(); err != nil {
+					registry.Logger().Errorf("Could not roll back cancelled batch: %v", err)
+				}
+			}
+			return kit.NewErrorResponse("batch_cancelled", "The batch was cancelled before all calls finished", true)
+		}
+
+		meta := map[string]interface{}{
+			"atomic": args.Atomic,
+		}
+
+		if tx != nil {
+			if failed != nil {
+				if err := tx.Rollback(); err != nil {
+					return kit.NewErrorResponse(err)
+				}
+				meta["rolled_back"] = true
+				meta["failed_call"] = failedIndex
+			} else if err := tx.Commit(); err != nil {
+				return kit.NewErrorResponse(err)
+			}
+		}
+
+		return &kit.AppResponse{
+			Data: results,
+			Meta: meta,
+		}
+	},
+}
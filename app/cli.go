@@ -1,10 +1,18 @@
 package app
 
 import (
+	"encoding/json"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/theduke/go-apperror"
+
+	"github.com/app-kit/go-appkit/clientgen"
 )
 
 func (app *App) InitCli() {
@@ -108,6 +116,75 @@ func (app *App) InitCli() {
 	cmdDbDrop.Flags().BoolVarP(&dropAll, "all", "a", false, "Drop all backends")
 	cli.AddCommand(cmdDbDrop)
 
+	var openApiOutput string
+	cmdOpenApi := &cobra.Command{
+		Use:   "openapi",
+		Short: "Generate the OpenAPI document of the app.",
+		Long:  `Generate the OpenAPI 3 document of the app and write it to stdout or a file`,
+
+		Run: func(cmd *cobra.Command, args []string) {
+			js, err := json.MarshalIndent(BuildOpenApi(app.registry), "", "  ")
+			if err != nil {
+				log.Fatalf("Could not marshal OpenAPI document: %v", err)
+			}
+
+			if openApiOutput == "" {
+				os.Stdout.Write(append(js, '\n'))
+				return
+			}
+			if err := ioutil.WriteFile(openApiOutput, js, 0644); err != nil {
+				log.Fatalf("Could not write OpenAPI document: %v", err)
+			}
+		},
+	}
+	cmdOpenApi.Flags().StringVarP(&openApiOutput, "output", "o", "", "Write the document to a file instead of stdout")
+	cli.AddCommand(cmdOpenApi)
+
+	var clientOutput, clientLanguages, clientPackage string
+	cmdGenerateClient := &cobra.Command{
+		Use:   "generate-client",
+		Short: "Generate TypeScript and Go clients.",
+		Long:  `Generate typed TypeScript and Go clients for the resources and methods of the app`,
+
+		Run: func(cmd *cobra.Command, args []string) {
+			spec := clientgen.Build(app.registry)
+
+			if err := os.MkdirAll(clientOutput, 0755); err != nil {
+				log.Fatalf("Could not create output directory: %v", err)
+			}
+
+			for _, language := range strings.Split(clientLanguages, ",") {
+				var code []byte
+				var err apperror.Error
+				var file string
+
+				switch strings.TrimSpace(language) {
+				case "ts", "typescript":
+					code, err = clientgen.TypeScript(spec)
+					file = "client.ts"
+				case "go":
+					code, err = clientgen.Go(spec, clientPackage)
+					file = "client.go"
+				default:
+					log.Fatalf("Unknown client language %v", language)
+				}
+				if err != nil {
+					log.Fatalf("Could not generate %v client: %v", language, err)
+				}
+
+				path := filepath.Join(clientOutput, file)
+				if err := ioutil.WriteFile(path, code, 0644); err != nil {
+					log.Fatalf("Could not write %v: %v", path, err)
+				}
+				log.Printf("Wrote %v", path)
+			}
+		},
+	}
+	cmdGenerateClient.Flags().StringVarP(&clientOutput, "output", "o", "client", "Output directory")
+	cmdGenerateClient.Flags().StringVarP(&clientLanguages, "lang", "l", "typescript,go", "Comma separated languages of the clients")
+	cmdGenerateClient.Flags().StringVarP(&clientPackage, "package", "p", "client", "Package name of the Go client")
+	cli.AddCommand(cmdGenerateClient)
+
 	app.Cli = cli
 }
 
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/theduke/go-apperror"
+	"github.com/theduke/go-reflector"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/app/methods"
+	"github.com/app-kit/go-appkit/resources"
+	"github.com/app-kit/go-appkit/tasks"
+)
+
+// importTask builds the task that imports uploaded csv and xlsx files.
+// The progress is published like the progress of async methods.
+func importTask() kit.TaskSpec {
+	return &tasks.TaskSpec{
+		Name: resources.ImportTaskName,
+		Handler: func(registry kit.Registry, task kit.Task, progressChan chan kit.Task) (interface{}, apperror.Error, bool) {
+			data, _ := task.GetData().(map[string]interface{})
+			collection, _ := data["collection"].(string)
+			fileId, _ := data["fileId"].(string)
+			format, _ := data["format"].(string)
+
+			res := registry.Resource(collection)
+			if res == nil || res.Csv() == nil || !res.Csv().Import {
+				return nil, apperror.New("unknown_resource", fmt.Sprintf("The collection %v does not support imports", collection)), false
+			}
+
+			r := kit.NewRequest()
+			r.SetFrontend("tasks")
+			r.SetPath("/import/" + collection)
+
+			if userId := task.GetUserId(); userId != nil && !reflector.R(userId).IsZero() {
+				user, err := registry.UserService().FindUser(userId)
+				if err != nil {
+					return nil, err, false
+				} else if user == nil {
+					return nil, apperror.New("user_not_found", "The user who started the import does not exist"), false
+				}
+				r.SetUser(user)
+			}
+
+			// Only report changes of the percentage.
+			progress := func(done, total int) {
+				if percent := done * 100 / total; percent != task.GetProgress() {
+					task.SetProgress(percent)
+					progressChan <- task
+					PublishTaskStatus(registry, task)
+				}
+			}
+
+			files := registry.FileService()
+			if files == nil {
+				return nil, apperror.New("files_disabled", "Imports require the file service"), false
+			}
+			file, err := files.FindOne(fileId)
+			if err != nil {
+				return nil, err, false
+			} else if file == nil {
+				return nil, apperror.New("file_not_found", "The imported file does not exist"), false
+			}
+
+			result, err := importFile(res, file, format, r, progress)
+
+			// The stored file is only needed by the import.
+			if err := files.Delete(file, r.GetUser()); err != nil {
+				registry.Logger().Errorf("Could not delete imported file %v: %v", fileId, err)
+			}
+
+			if err != nil {
+				return nil, err, false
+			}
+
+			task.SetResult(result)
+			task.SetIsComplete(true)
+			task.SetIsSuccess(true)
+			PublishTaskStatus(registry, task)
+
+			return result, nil, false
+		},
+	}
+}
+
+// importFile imports a stored csv or xlsx file. The file is streamed from
+// the file backend.
+func importFile(res kit.Resource, file kit.File, format string, r kit.Request, progress func(done, total int)) (*resources.ImportResult, apperror.Error) {
+	reader, err := file.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	if format != "xlsx" {
+		return resources.ImportCsv(res, reader, r, progress)
+	}
+
+	// Zip archives need random access. File backends that do not provide
+	// it are read into memory.
+	size := file.GetSize()
+	readerAt, ok := reader.(io.ReaderAt)
+	if !ok {
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, apperror.Wrap(err, "file_read_error", "")
+		}
+		readerAt = bytes.NewReader(data)
+		size = int64(len(data))
+	}
+	return resources.ImportXlsx(res, readerAt, size, r, progress)
+}
+
+// registerImportTask registers the import task with the task runner if any
+// resource supports imports.
+func (a *App) registerImportTask(runner kit.TaskRunner) {
+	for _, res := range a.registry.Resources() {
+		if res.Csv() != nil && res.Csv().Import {
+			runner.RegisterTask(importTask())
+			return
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 
 	kit "github.com/app-kit/go-appkit"
 	. "github.com/app-kit/go-appkit/app/methods"
+	"github.com/app-kit/go-appkit/utils"
 )
 
 type methodInstance struct {
@@ -25,10 +28,22 @@ type methodInstance struct {
 
 	finishedChannel chan bool
 
-	blocked bool
-	stale   bool
+	// cancel cancels the context.Context of the running method.
+	cancel context.CancelFunc
+
+	blocked  bool
+	stale    bool
+	finished bool
+
+	// waiting is true while the method waits for a nested method, like a
+	// batch for its calls. Waiting methods do not count as running.
+	waiting bool
 }
 
+// methodInstanceKey is the context.Context key of the running method
+// instance.
+type methodInstanceKey struct{}
+
 func NewMethodInstance(m kit.Method, r kit.Request, responder func(kit.Response)) *methodInstance {
 	return &methodInstance{
 		method:    m,
@@ -39,10 +54,18 @@ func NewMethodInstance(m kit.Method, r kit.Request, responder func(kit.Response)
 	}
 }
 
-func (m methodInstance) IsRunning() bool {
+func (m *methodInstance) IsRunning() bool {
 	return !m.startedAt.IsZero()
 }
 
+// Timeout returns the method specific timeout, or the supplied default.
+func (m *methodInstance) Timeout(defaultTimeout int) time.Duration {
+	if timeout := m.method.GetTimeout(); timeout > 0 {
+		return timeout
+	}
+	return time.Duration(defaultTimeout) * time.Second
+}
+
 type methodQueue struct {
 	app *App
 
@@ -50,53 +73,46 @@ type methodQueue struct {
 
 	queue map[*methodInstance]bool
 
-	maxQueued    int
-	maxRunning   int
-	maxPerMinute int
-	timeout      int
+	maxQueued  int
+	maxRunning int
+	timeout    int
 
 	lastAction time.Time
 }
 
 func newMethodQueue(m *SessionManager) *methodQueue {
 	return &methodQueue{
-		app:          m.app,
-		queue:        make(map[*methodInstance]bool),
-		maxQueued:    m.maxQueued,
-		maxRunning:   m.maxRunning,
-		maxPerMinute: m.maxPerMinute,
-		timeout:      m.timeout,
-		lastAction:   time.Now(),
+		app:        m.app,
+		queue:      make(map[*methodInstance]bool),
+		maxQueued:  m.maxQueued,
+		maxRunning: m.maxRunning,
+		timeout:    m.timeout,
+		lastAction: time.Now(),
 	}
 }
 
 func (m *methodQueue) TimeSinceActive() int {
+	m.Lock()
+	defer m.Unlock()
+
 	secs := time.Now().Sub(m.lastAction).Seconds()
 	return int(secs)
 }
 
 func (m *methodQueue) Count() int {
+	m.Lock()
+	defer m.Unlock()
+
 	return len(m.queue)
 }
 
 func (m *methodQueue) CountActive() int {
-	count := 0
-	for method := range m.queue {
-		if method.IsRunning() {
-			count++
-		} else {
-			break
-		}
-	}
-
-	return count
-}
+	m.Lock()
+	defer m.Unlock()
 
-func (m *methodQueue) CountAddedSince(seconds int) int {
-	now := time.Now()
 	count := 0
 	for method := range m.queue {
-		if now.Sub(method.createdAt).Seconds() <= float64(seconds) {
+		if method.IsRunning() {
 			count++
 		}
 	}
@@ -105,23 +121,17 @@ func (m *methodQueue) CountAddedSince(seconds int) int {
 }
 
 func (m *methodQueue) Add(method *methodInstance) apperror.Error {
+	m.Lock()
 	m.lastAction = time.Now()
 
 	if len(m.queue) >= m.maxQueued {
+		m.Unlock()
 		return &apperror.Err{
 			Code:    "max_methods_queued",
 			Message: "The maximum amount of methods is already running",
 		}
 	}
 
-	if m.CountAddedSince(60) >= m.maxPerMinute {
-		return &apperror.Err{
-			Code:    "max_methods_per_minute",
-			Message: "You have reached the maximum methods/minute limit.",
-		}
-	}
-
-	m.Lock()
 	m.queue[method] = true
 	m.Unlock()
 
@@ -131,26 +141,42 @@ func (m *methodQueue) Add(method *methodInstance) apperror.Error {
 	return nil
 }
 
-// Remove methods that have exceeded the timeout.
+// Mark methods that have exceeded the timeout as stale and cancel them.
 func (m *methodQueue) PruneStaleMethods() {
+	m.Lock()
+	defer m.Unlock()
+
+	m.pruneStaleMethods()
+}
+
+// pruneStaleMethods must be called with the queue locked.
+func (m *methodQueue) pruneStaleMethods() {
 	now := time.Now()
 
 	for method := range m.queue {
-		if !method.stale && method.IsRunning() && now.Sub(method.startedAt).Seconds() > float64(m.timeout) {
-			m.Lock()
+		if !method.stale && method.IsRunning() && now.Sub(method.startedAt) > method.Timeout(m.timeout) {
 			method.stale = true
-			m.Unlock()
+			method.cancel()
 		}
-
 	}
 }
 
 func (m *methodQueue) CanProcess() bool {
-	m.PruneStaleMethods()
+	m.Lock()
+	defer m.Unlock()
+
+	return m.canProcess()
+}
+
+// canProcess must be called with the queue locked.
+// Stale methods still count as running until their handler has returned,
+// so a blocking method that ignores the cancellation keeps blocking.
+func (m *methodQueue) canProcess() bool {
+	m.pruneStaleMethods()
 
 	running := 0
 	for method := range m.queue {
-		if method.IsRunning() && !method.stale {
+		if method.IsRunning() && !method.waiting {
 			if method.blocked {
 				return false
 			}
@@ -165,6 +191,7 @@ func (m *methodQueue) CanProcess() bool {
 	return true
 }
 
+// Next must be called with the queue locked.
 func (m *methodQueue) Next() *methodInstance {
 	for method := range m.queue {
 		if method.IsRunning() || method.stale {
@@ -177,22 +204,39 @@ func (m *methodQueue) Next() *methodInstance {
 }
 
 func (m *methodQueue) Process() {
-	if !m.CanProcess() {
+	m.Lock()
+
+	if !m.canProcess() {
+		m.Unlock()
 		return
 	}
 
-	m.Lock()
 	next := m.Next()
-
 	if next == nil {
 		m.Unlock()
 		return
 	}
 
 	next.startedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(next.request.GetGoContext(), next.Timeout(m.timeout))
+	next.cancel = cancel
+	ctx = context.WithValue(ctx, methodInstanceKey{}, next)
+	next.request.SetGoContext(ctx)
+
 	m.Unlock()
 
-	go func(method *methodInstance) {
+	go m.run(next, ctx)
+}
+
+// run executes the method handler and sends the response once the handler
+// returns, the timeout is reached or the request context is cancelled,
+// whichever happens first.
+// The method only leaves the queue once the handler has returned.
+func (m *methodQueue) run(method *methodInstance, ctx context.Context) {
+	responseChan := make(chan kit.Response, 1)
+
+	go func() {
 
 		// Recover from panic.
 		/*
@@ -217,29 +261,78 @@ func (m *methodQueue) Process() {
 
 		// Run method.
 		handler := method.method.GetHandler()
-		resp := handler(m.app.Registry(), method.request, func() {
+		responseChan <- handler(m.app.Registry(), method.request, func() {
+			m.Lock()
 			method.blocked = false
+			m.Unlock()
+
+			m.Process()
 		})
+	}()
 
+	select {
+	case resp := <-responseChan:
 		m.Finish(method, resp)
-	}(next)
+
+	case <-ctx.Done():
+		m.Finish(method, cancelledMethodResponse(method, ctx.Err(), m.timeout))
+
+		// The caller already received the error. Handlers that honour the
+		// request context return early, others keep their slot in the
+		// queue until they are done, and their late response is dropped.
+		<-responseChan
+	}
+
+	method.cancel()
+	m.remove(method)
 }
 
+// cancelledMethodResponse builds the error response for a method that was
+// cancelled before it finished.
+func cancelledMethodResponse(method *methodInstance, ctxErr error, defaultTimeout int) kit.Response {
+	name := method.method.GetName()
+
+	if ctxErr == context.DeadlineExceeded {
+		timeout := method.Timeout(defaultTimeout)
+
+		return kit.NewErrorResponse(&apperror.Err{
+			Code:    "method_timeout",
+			Message: fmt.Sprintf("The method %v did not finish within %v", name, timeout),
+			Data: map[string]interface{}{
+				"method":  name,
+				"timeout": timeout.Seconds(),
+			},
+			Public: true,
+			Status: 504,
+		})
+	}
+
+	return kit.NewErrorResponse(&apperror.Err{
+		Code:    "method_cancelled",
+		Message: fmt.Sprintf("The method %v was cancelled", name),
+		Data: map[string]interface{}{
+			"method": name,
+		},
+		Public: true,
+	})
+}
+
+// Finish sends the response of a method. It does not remove the method from
+// the queue, which happens once the handler has returned.
 func (m *methodQueue) Finish(method *methodInstance, response kit.Response) {
-	// Send the response.
+	// Ensure that a method is only finished once.
+	m.Lock()
+	if method.finished {
+		m.Unlock()
+		return
+	}
+	method.finished = true
+	m.Unlock()
 
 	// Recover a panic in the responder.
 	defer func() {
-		err := recover()
-		if err != nil {
-			// Responder paniced!
-
-			// Remove method from queue.
-			m.Lock()
-			delete(m.queue, method)
-			m.Unlock()
-
-			method.finishedAt = time.Now()
+		if err := recover(); err != nil {
+			m.app.Logger().Errorf("Responder of method %v paniced: %v", method.method.GetName(), err)
 		}
 	}()
 
@@ -248,15 +341,42 @@ func (m *methodQueue) Finish(method *methodInstance, response kit.Response) {
 	if method.finishedChannel != nil {
 		method.finishedChannel <- true
 	}
+}
 
-	// Remove method from queue.
+// removeQueued removes a method that has not started yet.
+// Returns false if the method is already running.
+func (m *methodQueue) removeQueued(method *methodInstance) bool {
 	m.Lock()
+	defer m.Unlock()
+
+	if method.IsRunning() {
+		return false
+	}
 	delete(m.queue, method)
+	return true
+}
+
+// setWaiting marks a running method as waiting for a nested method.
+func (m *methodQueue) setWaiting(method *methodInstance, waiting bool) {
+	m.Lock()
+	method.waiting = waiting
 	m.Unlock()
 
+	if !waiting {
+		return
+	}
+	m.Process()
+}
+
+// remove removes a method whose handler has returned from the queue and
+// runs the next queued methods.
+func (m *methodQueue) remove(method *methodInstance) {
+	m.Lock()
+	delete(m.queue, method)
+	method.blocked = false
 	method.finishedAt = time.Now()
+	m.Unlock()
 
-	// Try to run queued methods.
 	m.Process()
 }
 
@@ -267,10 +387,12 @@ type SessionManager struct {
 
 	queues map[kit.Session]*methodQueue
 
-	maxQueued    int
-	maxRunning   int
-	maxPerMinute int
-	timeout      int
+	maxQueued  int
+	maxRunning int
+	timeout    int
+
+	// rateLimit limits the methods a session may queue per minute.
+	rateLimit *kit.RateLimitPolicy
 
 	sessionTimeout int
 	pruneInterval  int
@@ -281,26 +403,44 @@ func NewSessionManager(app *App) *SessionManager {
 		app:    app,
 		queues: make(map[kit.Session]*methodQueue),
 
-		maxQueued:    app.Config().UInt("methods.maxQueued", 30),
-		maxRunning:   app.Config().UInt("methods.maxRunning", 5),
-		maxPerMinute: app.Config().UInt("methods.maxPerMinute", 100),
-		timeout:      app.Config().UInt("methods.timeout", 30),
+		maxQueued:  app.Config().UInt("methods.maxQueued", 30),
+		maxRunning: app.Config().UInt("methods.maxRunning", 5),
+		timeout:    app.Config().UInt("methods.timeout", 30),
+
+		rateLimit: &kit.RateLimitPolicy{
+			Name:      "methods.session",
+			Algorithm: kit.RateLimitSlidingWindow,
+			KeyBy:     kit.RateLimitBySession,
+			Limit:     app.Config().UInt("methods.maxPerMinute", 100),
+			Interval:  time.Minute,
+		},
 
 		sessionTimeout: app.Config().UInt("sessions.sessionTimeout", 60*4),
 		pruneInterval:  app.Config().UInt("sessions.pruneInterval", 60*5),
 	}
 }
 
-func (m *SessionManager) QueueMethod(session kit.Session, method *methodInstance) apperror.Error {
+// sessionQueue returns the method queue of a session.
+func (m *SessionManager) sessionQueue(session kit.Session) *methodQueue {
+	m.Lock()
+	defer m.Unlock()
+
 	queue := m.queues[session]
 	if queue == nil {
-		m.Lock()
-		m.queues[session] = newMethodQueue(m)
-		m.Unlock()
-		queue = m.queues[session]
+		queue = newMethodQueue(m)
+		m.queues[session] = queue
+	}
+	return queue
+}
+
+func (m *SessionManager) QueueMethod(session kit.Session, method *methodInstance) apperror.Error {
+	if limiter := m.app.registry.RateLimiter(); limiter != nil {
+		if err := limiter.Check(m.rateLimit, method.request); err != nil {
+			return err
+		}
 	}
 
-	err := queue.Add(method)
+	err := m.sessionQueue(session).Add(method)
 	if err != nil {
 		return err
 	}
@@ -308,6 +448,65 @@ func (m *SessionManager) QueueMethod(session kit.Session, method *methodInstance
 	return nil
 }
 
+// RunNestedMethod queues a method that is called by a running method of the
+// same session, like a call of a batch, and waits for its response.
+// The calling method, taken from the context of the request, does not count
+// as running while it waits, so the nested method can not get stuck behind
+// it. The nested method follows the limits and the blocking of the queue.
+func (m *SessionManager) RunNestedMethod(method kit.Method, r kit.Request) kit.Response {
+	ctx := r.GetGoContext()
+	queue := m.sessionQueue(r.GetSession())
+
+	responseChan := make(chan kit.Response, 1)
+	instance := NewMethodInstance(nestedMethod{method}, r, func(response kit.Response) {
+		responseChan <- response
+	})
+
+	if parent, ok := ctx.Value(methodInstanceKey{}).(*methodInstance); ok {
+		queue.setWaiting(parent, true)
+		defer queue.setWaiting(parent, false)
+	}
+
+	if err := m.QueueMethod(r.GetSession(), instance); err != nil {
+		return kit.NewErrorResponse(err)
+	}
+
+	select {
+	case response := <-responseChan:
+		return response
+	case <-ctx.Done():
+		// Methods that already started respond once they are cancelled.
+		if !queue.removeQueued(instance) {
+			return <-responseChan
+		}
+		return cancelledMethodResponse(instance, ctx.Err(), m.timeout)
+	}
+}
+
+// nestedMethod turns panics of a nested method into error responses, so
+// that the calling method can clean up, like rolling back a transaction.
+type nestedMethod struct {
+	kit.Method
+}
+
+func (m nestedMethod) GetHandler() kit.MethodHandler {
+	handler := m.Method.GetHandler()
+	return func(registry kit.Registry, r kit.Request, unblock func()) (response kit.Response) {
+		defer func() {
+			if rawErr := recover(); rawErr != nil {
+				registry.Logger().Errorf("Method %v paniced: %v", m.GetName(), rawErr)
+				response = kit.NewErrorResponse(&apperror.Err{
+					Code:    "method_panic",
+					Message: fmt.Sprintf("The method %v failed", m.GetName()),
+					Public:  true,
+					Status:  500,
+				})
+			}
+		}()
+		return handler(registry, r, unblock)
+	}
+}
+
 func (m *SessionManager) Prune() {
 	m.Lock()
 	for session, queue := range m.queues {
@@ -332,6 +531,17 @@ type ResourceMethodData struct {
 	Query    db.Query
 }
 
+// checkBulkModels ensures that all models of a bulk request belong to the
+// same collection.
+func checkBulkModels(models []kit.Model) apperror.Error {
+	for _, model := range models {
+		if model.Collection() != models[0].Collection() {
+			return apperror.New("multiple_collections", "All models of a request must belong to the same collection.", true)
+		}
+	}
+	return nil
+}
+
 var createMethod kit.Method = &Method{
 	Name:     "create",
 	Blocking: true,
@@ -339,15 +549,18 @@ var createMethod kit.Method = &Method{
 		models := r.GetTransferData().GetModels()
 		if len(models) == 0 {
 			return kit.NewErrorResponse("no_model", "No model was found in the request.")
-		} else if len(models) > 1 {
-			return kit.NewErrorResponse("multiple_models", "Request contained more than one model.")
+		} else if err := checkBulkModels(models); err != nil {
+			return kit.NewErrorResponse(err)
 		}
 
-		res := registry.Resource(models[0].Collection())
+		res := requestResource(registry, models[0].Collection(), r)
 		if res == nil || !res.IsPublic() {
 			return kit.NewErrorResponse("unknown_collection", fmt.Sprintf("The collection %v does not exist", models[0].Collection()))
 		}
 
+		if len(models) > 1 {
+			return res.ApiBulkCreate(models, r)
+		}
 		return res.ApiCreate(models[0], r)
 	},
 }
@@ -359,70 +572,120 @@ var updateMethod kit.Method = &Method{
 		models := r.GetTransferData().GetModels()
 		if len(models) == 0 {
 			return kit.NewErrorResponse("no_model", "No model was found in the request.")
-		} else if len(models) > 1 {
-			return kit.NewErrorResponse("multiple_models", "Request contained more than one model.")
+		} else if err := checkBulkModels(models); err != nil {
+			return kit.NewErrorResponse(err)
 		}
 
-		res := registry.Resource(models[0].Collection())
+		res := requestResource(registry, models[0].Collection(), r)
 		if res == nil || !res.IsPublic() {
 			return kit.NewErrorResponse("unknown_collection", fmt.Sprintf("The collection %v does not exist", models[0].Collection()))
 		}
 
+		if len(models) > 1 {
+			return res.ApiBulkUpdate(models, r)
+		}
 		return res.ApiUpdate(models[0], r)
 	},
 }
 
+// modelArguments are the arguments of methods operating on a single model.
+type modelArguments struct {
+	Collection string `json:"collection" arg:"required"`
+	Id         string `json:"id" arg:"required"`
+}
+
+// deleteArguments are the arguments of the delete method, which deletes
+// a single model by id or multiple models by ids.
+type deleteArguments struct {
+	Collection string   `json:"collection" arg:"required"`
+	Id         string   `json:"id"`
+	Ids        []string `json:"ids"`
+}
+
 var deleteMethod kit.Method = &Method{
-	Name:     "delete",
-	Blocking: true,
+	Name:            "delete",
+	Blocking:        true,
+	ArgumentsStruct: deleteArguments{},
 	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
-		data, _ := r.GetData().(map[string]interface{})
-		if data == nil {
-			return kit.NewErrorResponse("no_data", "No request data.")
-		}
-		collection, _ := data["collection"].(string)
-		id, _ := data["id"].(string)
-
-		if collection == "" {
-			return kit.NewErrorResponse("no_collection", "Expected 'collection' key in data")
-		} else if id == "" {
-			return kit.NewErrorResponse("no_id", "Expected 'id' key with string value in data")
+		args, ok := r.GetData().(*deleteArguments)
+		if !ok {
+			return kit.NewErrorResponse(UnpreparedArgumentsError())
 		}
 
-		res := registry.Resource(collection)
+		res := requestResource(registry, args.Collection, r)
 		if res == nil || !res.IsPublic() {
-			return kit.NewErrorResponse("unknown_resource", fmt.Sprintf("The collection %v does not exist", collection))
+			return kit.NewErrorResponse("unknown_resource", fmt.Sprintf("The collection %v does not exist", args.Collection))
 		}
 
-		return res.ApiDelete(id, r)
+		if len(args.Ids) > 0 {
+			return res.ApiBulkDelete(args.Ids, r)
+		} else if args.Id == "" {
+			return kit.NewErrorResponse("missing_id", "Expected an id or ids argument.", true)
+		}
+		return res.ApiDelete(args.Id, r)
 	},
 }
 
 var queryMethod kit.Method = &Method{
 	Name:     "query",
 	Blocking: false,
+	Arguments: []*kit.Argument{
+		{
+			Name:     "query",
+			Type:     kit.ArgumentTypeMap,
+			Required: true,
+			Arguments: []*kit.Argument{
+				{Name: "collection", Type: kit.ArgumentTypeString, Required: true},
+			},
+		},
+		{
+			Name:        "page",
+			Type:        kit.ArgumentTypeMap,
+			Description: "Cursor pagination with after, before and size.",
+		},
+		{
+			Name:        "count",
+			Type:        kit.ArgumentTypeBool,
+			Description: "Add the total number of results and pagination info to the meta.",
+		},
+	},
 	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
 		// Build query.
-		data, _ := r.GetData().(map[string]interface{})
-		if data == nil {
-			return kit.NewErrorResponse("no_data", "No request data.", true)
+		rawQuery, _ := utils.GetMapDictKey(r.GetData(), "query")
+		collection := utils.GetMapStringKey(rawQuery, "collection")
+
+		if count, ok := utils.GetMapKey(r.GetData(), "count"); ok {
+			r.GetContext().Set("count", fmt.Sprintf("%v", count))
 		}
 
-		rawQuery, _ := data["query"].(map[string]interface{})
-		if rawQuery == nil {
-			return kit.NewErrorResponse("no_query", "No query in request data.", true)
+		// With cursor pagination, the resource sorts the query itself.
+		if page, ok := utils.GetMapDictKey(r.GetData(), "page"); ok {
+			for _, key := range []string{"after", "before", "size"} {
+				if val, ok := page[key]; ok {
+					r.GetContext().Set("page["+key+"]", fmt.Sprintf("%v", val))
+				}
+			}
+			if order, ok := rawQuery["order"]; ok {
+				r.GetContext().Set("order", order)
+				delete(rawQuery, "order")
+			}
 		}
 
-		collection, _ := rawQuery["collection"].(string)
-		if collection == "" {
-			return kit.NewErrorResponse("empty_collection", "No collection in query", true)
+		// Admins may include soft deleted models.
+		if includeDeleted, _ := rawQuery["include_deleted"].(bool); includeDeleted {
+			r.GetContext().Set("include_deleted", "true")
 		}
+		delete(rawQuery, "include_deleted")
 
 		resource := registry.Resource(collection)
-		if resource == nil {
+		if resource == nil || !resource.IsPublic() {
 			return kit.NewErrorResponse("unknown_collection", "Unknown collection", true)
 		}
 
+		if err := resource.CheckQuery(rawQuery); err != nil {
+			return kit.NewErrorResponse(err)
+		}
+
 		query, err := db.ParseQuery(resource.Backend(), rawQuery)
 		if err != nil {
 			if err.IsPublic() {
@@ -432,7 +695,7 @@ var queryMethod kit.Method = &Method{
 			}
 		}
 
-		res := registry.Resource(query.GetCollection())
+		res := requestResource(registry, query.GetCollection(), r)
 		if res == nil {
 			return kit.NewErrorResponse("unknown_resource", fmt.Sprintf("The collection %v does not exist", query.GetCollection()))
 		}
@@ -441,28 +704,99 @@ var queryMethod kit.Method = &Method{
 	},
 }
 
-var findOneMethod kit.Method = &Method{
-	Name:     "find_one",
+var deleteByQueryMethod kit.Method = &Method{
+	Name:     "delete_by_query",
+	Blocking: true,
+	Arguments: []*kit.Argument{
+		{
+			Name:     "query",
+			Type:     kit.ArgumentTypeMap,
+			Required: true,
+			Arguments: []*kit.Argument{
+				{Name: "collection", Type: kit.ArgumentTypeString, Required: true},
+			},
+		},
+	},
+	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
+		rawQuery, _ := utils.GetMapDictKey(r.GetData(), "query")
+		collection := utils.GetMapStringKey(rawQuery, "collection")
+
+		res := requestResource(registry, collection, r)
+		if res == nil || !res.IsPublic() {
+			return kit.NewErrorResponse("unknown_resource", fmt.Sprintf("The collection %v does not exist", collection))
+		}
+
+		if err := res.CheckQuery(rawQuery); err != nil {
+			return kit.NewErrorResponse(err)
+		}
+
+		query, err := db.ParseQuery(res.Backend(), rawQuery)
+		if err != nil {
+			if err.IsPublic() {
+				return kit.NewErrorResponse(err)
+			} else {
+				return kit.NewErrorResponse("invalid_query", err)
+			}
+		}
+
+		return res.ApiDeleteByQuery(query, r)
+	},
+}
+
+var cacheStatsMethod kit.Method = &Method{
+	Name:     "resources.cache_stats",
 	Blocking: false,
+	Roles:    []string{"admin"},
 	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
-		data, _ := r.GetData().(map[string]interface{})
-		if data == nil {
-			return kit.NewErrorResponse("no_data", "No request data.")
+		stats := make(map[string]kit.CacheStats)
+		for collection, res := range registry.Resources() {
+			if res.Cache() != nil {
+				stats[collection] = res.CacheStats()
+			}
 		}
-		collection, _ := data["collection"].(string)
-		id, _ := data["id"].(string)
 
-		if collection == "" {
-			return kit.NewErrorResponse("no_collection", "Expected 'collection' key in data")
-		} else if id == "" {
-			return kit.NewErrorResponse("no_id", "Expected 'id' key with string value in data")
+		return &kit.AppResponse{
+			Data: stats,
+		}
+	},
+}
+
+var findOneMethod kit.Method = &Method{
+	Name:            "find_one",
+	Blocking:        false,
+	ArgumentsStruct: modelArguments{},
+	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
+		args, ok := r.GetData().(*modelArguments)
+		if !ok {
+			return kit.NewErrorResponse(UnpreparedArgumentsError())
 		}
 
-		res := registry.Resource(collection)
+		res := requestResource(registry, args.Collection, r)
 		if res == nil || !res.IsPublic() {
-			return kit.NewErrorResponse("unknown_resource", fmt.Sprintf("The collection %v does not exist", collection))
+			return kit.NewErrorResponse("unknown_resource", fmt.Sprintf("The collection %v does not exist", args.Collection))
 		}
 
-		return res.ApiFindOne(id, r)
+		return res.ApiFindOne(args.Id, r)
+	},
+}
+
+var listMethodsMethod kit.Method = &Method{
+	Name:     "methods.list",
+	Blocking: false,
+	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
+		names := make([]string, 0)
+		for name := range registry.Methods() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		infos := make([]*kit.MethodInfo, 0)
+		for _, name := range names {
+			infos = append(infos, Describe(registry.Method(name)))
+		}
+
+		return &kit.AppResponse{
+			Data: infos,
+		}
 	},
 }
@@ -0,0 +1,451 @@
+package methods
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/theduke/go-apperror"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// Bound is a helper for specifying the Min and Max values of an argument.
+func Bound(n float64) *float64 {
+	return &n
+}
+
+/**
+ * Building arguments from structs.
+ */
+
+// structArgumentsCache holds the argument schema of each struct type, since
+// GetArguments() is called for every method call.
+var structArgumentsCache = make(map[reflect.Type][]*kit.Argument)
+var structArgumentsLock sync.RWMutex
+
+// ArgumentsFromStruct builds the argument schema for a struct.
+// The argument names are taken from the json tag, and constraints can be
+// specified with the arg tag:
+//
+//	Token string `json:"token" arg:"required;min:10;max:100"`
+//	Type  string `json:"type" arg:"enum:a,b,c"`
+//	Level int    `json:"level" arg:"enum:1,2,3"`
+//
+// The schema is built once per struct type and cached.
+func ArgumentsFromStruct(s interface{}) []*kit.Argument {
+	typ := reflect.TypeOf(s)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("ArgumentsFromStruct() expects a struct, got %v", typ))
+	}
+
+	structArgumentsLock.RLock()
+	args, ok := structArgumentsCache[typ]
+	structArgumentsLock.RUnlock()
+	if ok {
+		return args
+	}
+
+	args = structArguments(typ, make(map[reflect.Type]bool))
+
+	structArgumentsLock.Lock()
+	structArgumentsCache[typ] = args
+	structArgumentsLock.Unlock()
+
+	return args
+}
+
+// structArguments builds the arguments of the fields of a struct.
+// visited holds the struct types that are being built, to stop at
+// self-referential types.
+func structArguments(typ reflect.Type, visited map[reflect.Type]bool) []*kit.Argument {
+	visited[typ] = true
+	defer delete(visited, typ)
+
+	args := make([]*kit.Argument, 0)
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			// Unexported field.
+			continue
+		}
+
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			} else if tagName != "" {
+				name = tagName
+			}
+		}
+
+		arg := typeArgument(field.Type, visited)
+		arg.Name = name
+
+		if tag := field.Tag.Get("arg"); tag != "" {
+			parseArgumentTag(arg, tag)
+		}
+
+		args = append(args, arg)
+	}
+
+	return args
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+func typeArgument(typ reflect.Type, visited map[reflect.Type]bool) *kit.Argument {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	arg := &kit.Argument{}
+
+	// Times and other types that decode from text are sent as strings,
+	// like RFC3339 timestamps.
+	if typ == timeType || reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		arg.Type = kit.ArgumentTypeString
+		return arg
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		arg.Type = kit.ArgumentTypeString
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		arg.Type = kit.ArgumentTypeInt
+	case reflect.Float32, reflect.Float64:
+		arg.Type = kit.ArgumentTypeFloat
+	case reflect.Bool:
+		arg.Type = kit.ArgumentTypeBool
+	case reflect.Struct:
+		arg.Type = kit.ArgumentTypeMap
+		// The fields of self-referential types are not validated below
+		// the first level.
+		if !visited[typ] {
+			arg.Arguments = structArguments(typ, visited)
+		}
+	case reflect.Map:
+		arg.Type = kit.ArgumentTypeMap
+	case reflect.Slice, reflect.Array:
+		arg.Type = kit.ArgumentTypeList
+		arg.Items = typeArgument(typ.Elem(), visited)
+	default:
+		arg.Type = kit.ArgumentTypeAny
+	}
+
+	return arg
+}
+
+func parseArgumentTag(arg *kit.Argument, tag string) {
+	for _, part := range strings.Split(tag, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var value string
+		if index := strings.Index(part, ":"); index != -1 {
+			value = part[index+1:]
+			part = part[:index]
+		}
+
+		switch part {
+		case "required":
+			arg.Required = true
+
+		case "min", "max":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				panic(fmt.Sprintf("Invalid %v value in arg tag of argument %v: %v", part, arg.Name, value))
+			}
+			if part == "min" {
+				arg.Min = Bound(n)
+			} else {
+				arg.Max = Bound(n)
+			}
+
+		case "enum":
+			for _, item := range strings.Split(value, ",") {
+				arg.Enum = append(arg.Enum, parseEnumItem(arg, item))
+			}
+
+		case "description":
+			arg.Description = value
+
+		default:
+			panic(fmt.Sprintf("Unknown arg tag option %v for argument %v", part, arg.Name))
+		}
+	}
+}
+
+// parseEnumItem converts an enum item of an arg tag to the type of the
+// argument, so it can match the request data.
+func parseEnumItem(arg *kit.Argument, item string) interface{} {
+	var value interface{}
+	var err error
+
+	switch arg.Type {
+	case kit.ArgumentTypeString:
+		return item
+	case kit.ArgumentTypeInt:
+		value, err = strconv.ParseInt(item, 10, 64)
+	case kit.ArgumentTypeFloat:
+		value, err = strconv.ParseFloat(item, 64)
+	case kit.ArgumentTypeBool:
+		value, err = strconv.ParseBool(item)
+	default:
+		panic(fmt.Sprintf("The enum option is not supported for %v argument %v", arg.Type, arg.Name))
+	}
+
+	if err != nil {
+		panic(fmt.Sprintf("Invalid enum value in arg tag of %v argument %v: %v", arg.Type, arg.Name, item))
+	}
+	return value
+}
+
+// UnpreparedArgumentsError is returned by handlers whose request data was
+// not decoded into the arguments struct of the method, which happens when
+// a handler is called without PrepareArguments().
+func UnpreparedArgumentsError() apperror.Error {
+	return &apperror.Err{
+		Code:    "arguments_not_prepared",
+		Message: "The method arguments were not prepared",
+		Status:  500,
+	}
+}
+
+/**
+ * Validation.
+ */
+
+func argumentError(field, rule, message string) *apperror.Err {
+	return &apperror.Err{
+		Code:    "invalid_argument",
+		Message: message,
+		Data: map[string]interface{}{
+			"field": field,
+			"rule":  rule,
+		},
+		Public: true,
+	}
+}
+
+// toFloat converts all numeric values to float64.
+func toFloat(value interface{}) (float64, bool) {
+	if value == nil {
+		return 0, false
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+
+	return 0, false
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	floatVal, isNumeric := toFloat(value)
+
+	for _, item := range enum {
+		if isNumeric {
+			if f, ok := toFloat(item); ok && f == floatVal {
+				return true
+			}
+		} else if reflect.DeepEqual(item, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateArgument(arg *kit.Argument, field string, value interface{}, isSet bool) []error {
+	if !isSet || value == nil || value == "" {
+		if arg.Required {
+			return []error{argumentError(field, "required", fmt.Sprintf("The argument '%v' is required", field))}
+		}
+		return nil
+	}
+
+	// Length or value to check min/max constraints against.
+	var size float64
+
+	switch arg.Type {
+	case kit.ArgumentTypeString:
+		str, ok := value.(string)
+		if !ok {
+			return []error{argumentError(field, "type", fmt.Sprintf("The argument '%v' must be a string", field))}
+		}
+		size = float64(len(str))
+
+	case kit.ArgumentTypeInt:
+		n, ok := toFloat(value)
+		if !ok || n != math.Trunc(n) {
+			return []error{argumentError(field, "type", fmt.Sprintf("The argument '%v' must be an integer", field))}
+		}
+		size = n
+
+	case kit.ArgumentTypeFloat:
+		n, ok := toFloat(value)
+		if !ok {
+			return []error{argumentError(field, "type", fmt.Sprintf("The argument '%v' must be a number", field))}
+		}
+		size = n
+
+	case kit.ArgumentTypeBool:
+		if _, ok := value.(bool); !ok {
+			return []error{argumentError(field, "type", fmt.Sprintf("The argument '%v' must be a boolean", field))}
+		}
+
+	case kit.ArgumentTypeMap:
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return []error{argumentError(field, "type", fmt.Sprintf("The argument '%v' must be a dictionary", field))}
+		}
+		size = float64(len(data))
+
+		if arg.Arguments != nil {
+			if errs := validateArguments(arg.Arguments, field+".", data); errs != nil {
+				return errs
+			}
+		}
+
+	case kit.ArgumentTypeList:
+		items, ok := value.([]interface{})
+		if !ok {
+			return []error{argumentError(field, "type", fmt.Sprintf("The argument '%v' must be a list", field))}
+		}
+		size = float64(len(items))
+
+		if arg.Items != nil {
+			var errs []error
+			for index, item := range items {
+				itemField := field + "." + strconv.Itoa(index)
+				errs = append(errs, validateArgument(arg.Items, itemField, item, true)...)
+			}
+			if errs != nil {
+				return errs
+			}
+		}
+	}
+
+	if arg.Enum != nil && !inEnum(arg.Enum, value) {
+		return []error{argumentError(field, "enum", fmt.Sprintf("The argument '%v' must be one of %v", field, arg.Enum))}
+	}
+
+	if arg.Min != nil && size < *arg.Min {
+		return []error{argumentError(field, "min", fmt.Sprintf("The argument '%v' must be at least %v", field, *arg.Min))}
+	}
+	if arg.Max != nil && size > *arg.Max {
+		return []error{argumentError(field, "max", fmt.Sprintf("The argument '%v' must be at most %v", field, *arg.Max))}
+	}
+
+	return nil
+}
+
+func validateArguments(args []*kit.Argument, prefix string, data map[string]interface{}) []error {
+	var errs []error
+	for _, arg := range args {
+		value, isSet := data[arg.Name]
+		errs = append(errs, validateArgument(arg, prefix+arg.Name, value, isSet)...)
+	}
+
+	return errs
+}
+
+// ValidateArguments validates the data against the argument schema.
+// All invalid arguments are reported in the nested errors of the
+// returned invalid_arguments error.
+func ValidateArguments(args []*kit.Argument, rawData interface{}) apperror.Error {
+	data, ok := rawData.(map[string]interface{})
+	if !ok {
+		if rawData != nil {
+			return &apperror.Err{
+				Code:    "invalid_arguments",
+				Message: "Expected a dictionary with the method arguments",
+				Public:  true,
+				Status:  400,
+			}
+		}
+		data = make(map[string]interface{})
+	}
+
+	errs := validateArguments(args, "", data)
+	if errs == nil {
+		return nil
+	}
+
+	return &apperror.Err{
+		Code:    "invalid_arguments",
+		Message: "The method arguments are invalid",
+		Errors:  errs,
+		Public:  true,
+		Status:  400,
+	}
+}
+
+// DecodeArguments decodes the data into a new instance of the struct
+// type of target.
+// Returns a pointer to the new struct.
+func DecodeArguments(target interface{}, data interface{}) (interface{}, apperror.Error) {
+	typ := reflect.TypeOf(target)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	js, err := json.Marshal(data)
+	if err != nil {
+		return nil, apperror.Wrap(err, "json_marshal_error")
+	}
+
+	decoded := reflect.New(typ).Interface()
+	if err := json.Unmarshal(js, decoded); err != nil {
+		return nil, apperror.Wrap(err, "invalid_arguments", "The method arguments could not be decoded", true)
+	}
+
+	return decoded, nil
+}
+
+// PrepareArguments validates the request data against the argument schema
+// of the method.
+// If the method has an arguments struct, the request data is replaced with
+// a pointer to the decoded struct.
+func PrepareArguments(method kit.Method, r kit.Request) apperror.Error {
+	args := method.GetArguments()
+	if args == nil {
+		return nil
+	}
+
+	if err := ValidateArguments(args, r.GetData()); err != nil {
+		return err
+	}
+
+	if target := method.GetArgumentsStruct(); target != nil {
+		decoded, err := DecodeArguments(target, r.GetData())
+		if err != nil {
+			return err
+		}
+		r.SetData(decoded)
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package methods_test
+
+import (
+	"time"
+
+	"github.com/theduke/go-apperror"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/app/methods"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type testArguments struct {
+	Name   string   `json:"name" arg:"required;min:2;max:5"`
+	Type   string   `json:"type" arg:"enum:a,b"`
+	Count  int      `json:"count" arg:"min:1"`
+	Tags   []string `json:"tags"`
+	Nested struct {
+		Id string `json:"id" arg:"required"`
+	} `json:"nested"`
+}
+
+type treeArguments struct {
+	Name     string          `json:"name"`
+	Children []treeArguments `json:"children"`
+}
+
+var _ = Describe("Arguments", func() {
+
+	Describe("ArgumentsFromStruct", func() {
+		It("Should build the argument schema", func() {
+			args := ArgumentsFromStruct(testArguments{})
+			Expect(args).To(HaveLen(5))
+
+			Expect(args[0]).To(Equal(&kit.Argument{
+				Name:     "name",
+				Type:     kit.ArgumentTypeString,
+				Required: true,
+				Min:      Bound(2),
+				Max:      Bound(5),
+			}))
+			Expect(args[1].Enum).To(Equal([]interface{}{"a", "b"}))
+			Expect(args[2].Type).To(Equal(kit.ArgumentTypeInt))
+			Expect(args[3].Type).To(Equal(kit.ArgumentTypeList))
+			Expect(args[3].Items.Type).To(Equal(kit.ArgumentTypeString))
+			Expect(args[4].Type).To(Equal(kit.ArgumentTypeMap))
+			Expect(args[4].Arguments[0].Name).To(Equal("id"))
+		})
+
+		It("Should convert enum items to the argument type", func() {
+			type levels struct {
+				Level int     `json:"level" arg:"enum:1,2"`
+				Ratio float64 `json:"ratio" arg:"enum:0.5,1"`
+				Flag  bool    `json:"flag" arg:"enum:true"`
+			}
+			args := ArgumentsFromStruct(levels{})
+			Expect(args[0].Enum).To(Equal([]interface{}{int64(1), int64(2)}))
+			Expect(args[1].Enum).To(Equal([]interface{}{0.5, float64(1)}))
+			Expect(args[2].Enum).To(Equal([]interface{}{true}))
+
+			Expect(ValidateArguments(args, map[string]interface{}{"level": float64(2)})).To(BeNil())
+			Expect(ValidateArguments(args, map[string]interface{}{"level": float64(3)})).ToNot(BeNil())
+		})
+
+		It("Should panic on enum tags of unsupported or mismatched types", func() {
+			type mapEnum struct {
+				Data map[string]interface{} `arg:"enum:a"`
+			}
+			Expect(func() { ArgumentsFromStruct(mapEnum{}) }).To(Panic())
+
+			type intEnum struct {
+				Level int `arg:"enum:a"`
+			}
+			Expect(func() { ArgumentsFromStruct(intEnum{}) }).To(Panic())
+		})
+
+		It("Should treat times as strings", func() {
+			type dates struct {
+				From  time.Time  `json:"from" arg:"required"`
+				Until *time.Time `json:"until"`
+			}
+			args := ArgumentsFromStruct(dates{})
+			Expect(args[0].Type).To(Equal(kit.ArgumentTypeString))
+			Expect(args[1].Type).To(Equal(kit.ArgumentTypeString))
+
+			data := map[string]interface{}{"from": "2016-01-02T15:04:05Z"}
+			Expect(ValidateArguments(args, data)).To(BeNil())
+		})
+
+		It("Should stop at self-referential struct types", func() {
+			args := ArgumentsFromStruct(treeArguments{})
+			Expect(args[1].Type).To(Equal(kit.ArgumentTypeList))
+			Expect(args[1].Items.Type).To(Equal(kit.ArgumentTypeMap))
+			Expect(args[1].Items.Arguments).To(BeNil())
+		})
+
+		It("Should cache the schema per struct type", func() {
+			first := ArgumentsFromStruct(testArguments{})
+			second := ArgumentsFromStruct(&testArguments{})
+			Expect(second[0]).To(BeIdenticalTo(first[0]))
+		})
+
+		It("Should panic on unknown tag options", func() {
+			type invalid struct {
+				Name string `arg:"unknown"`
+			}
+			Expect(func() { ArgumentsFromStruct(invalid{}) }).To(Panic())
+		})
+	})
+
+	Describe("ValidateArguments", func() {
+		args := ArgumentsFromStruct(testArguments{})
+
+		It("Should accept valid data", func() {
+			err := ValidateArguments(args, map[string]interface{}{
+				"name":   "abc",
+				"type":   "a",
+				"count":  float64(3),
+				"tags":   []interface{}{"x"},
+				"nested": map[string]interface{}{"id": "1"},
+			})
+			Expect(err).To(BeNil())
+		})
+
+		It("Should report all invalid arguments", func() {
+			err := ValidateArguments(args, map[string]interface{}{
+				"type":   "c",
+				"count":  float64(0),
+				"tags":   []interface{}{1},
+				"nested": map[string]interface{}{},
+			})
+			Expect(err).ToNot(BeNil())
+			Expect(err.GetCode()).To(Equal("invalid_arguments"))
+			Expect(err.GetStatus()).To(Equal(400))
+
+			fields := make([]string, 0)
+			for _, e := range err.GetErrors() {
+				data := e.(*apperror.Err).Data.(map[string]interface{})
+				fields = append(fields, data["field"].(string))
+			}
+			Expect(fields).To(Equal([]string{"name", "type", "count", "tags.0", "nested.id"}))
+		})
+
+		It("Should reject non-integer numbers for int arguments", func() {
+			err := ValidateArguments(args, map[string]interface{}{"name": "abc", "count": 1.5})
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("Should enforce string length bounds", func() {
+			err := ValidateArguments(args, map[string]interface{}{"name": "abcdef"})
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("Should reject non-dictionary data", func() {
+			err := ValidateArguments(args, "invalid")
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Describe("DecodeArguments", func() {
+		It("Should decode data into the struct", func() {
+			decoded, err := DecodeArguments(testArguments{}, map[string]interface{}{
+				"name":   "abc",
+				"count":  float64(3),
+				"nested": map[string]interface{}{"id": "1"},
+			})
+			Expect(err).To(BeNil())
+
+			args := decoded.(*testArguments)
+			Expect(args.Name).To(Equal("abc"))
+			Expect(args.Count).To(Equal(3))
+			Expect(args.Nested.Id).To(Equal("1"))
+		})
+	})
+})
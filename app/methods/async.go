@@ -0,0 +1,88 @@
+package methods
+
+import (
+	"context"
+
+	"github.com/theduke/go-reflector"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// taskContextKey is the key of the taskContext in the context.Context of a
+// method that runs as a task. The key type is unexported so that request
+// data can not set it.
+type taskContextKey struct{}
+
+type taskContext struct {
+	task         kit.Task
+	progressChan chan kit.Task
+}
+
+// WithTask returns a copy of ctx for a method that runs as the task.
+func WithTask(ctx context.Context, task kit.Task, progressChan chan kit.Task) context.Context {
+	return context.WithValue(ctx, taskContextKey{}, &taskContext{
+		task:         task,
+		progressChan: progressChan,
+	})
+}
+
+// CanAccessTask returns true if the user may see the status and result of
+// the task. Tasks started by a user are only visible to the user and admins.
+func CanAccessTask(user kit.User, task kit.Task) bool {
+	userId := task.GetUserId()
+	if userId == nil || reflector.R(userId).IsZero() {
+		return true
+	}
+
+	return user != nil && (user.HasRole("admin") || user.GetId() == userId)
+}
+
+// TaskStatus returns the serializable status of a task.
+func TaskStatus(task kit.Task) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         task.GetStrId(),
+		"name":       task.GetName(),
+		"progress":   task.GetProgress(),
+		"running":    task.IsRunning(),
+		"complete":   task.IsComplete(),
+		"success":    task.IsSuccess(),
+		"error":      task.GetError(),
+		"result":     task.GetResult(),
+		"createdAt":  task.GetCreatedAt(),
+		"startedAt":  task.GetStartedAt(),
+		"finishedAt": task.GetFinishedAt(),
+	}
+}
+
+// PublishTaskStatus publishes the status of a task to the tasks.<id> topic
+// of all frontends that support publishing.
+// Frontends must only allow users for which CanAccessTask() is true to
+// subscribe to the topic.
+func PublishTaskStatus(registry kit.Registry, task kit.Task) {
+	status := TaskStatus(task)
+	topic := "tasks." + task.GetStrId()
+
+	for _, frontend := range registry.Frontends() {
+		if publisher, ok := frontend.(kit.PublishingFrontend); ok {
+			if err := publisher.Publish(topic, status); err != nil {
+				registry.Logger().Errorf("Could not publish status of task %v: %v", task.GetStrId(), err)
+			}
+		}
+	}
+}
+
+// ReportProgress reports the progress of an async method in percent.
+// It does nothing if the method is not running as a task.
+func ReportProgress(registry kit.Registry, r kit.Request, progress int) {
+	tc, ok := r.GetGoContext().Value(taskContextKey{}).(*taskContext)
+	if !ok {
+		return
+	}
+
+	tc.task.SetProgress(progress)
+	if tc.progressChan != nil {
+		tc.progressChan <- tc.task
+	}
+
+	PublishTaskStatus(registry, tc.task)
+}
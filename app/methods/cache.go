@@ -0,0 +1,175 @@
+package methods
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/theduke/go-apperror"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// CollectionCacheTag returns the tag of cached method results that depend
+// on a collection.
+func CollectionCacheTag(collection string) string {
+	return "collections." + collection
+}
+
+// MethodCache returns the cache used for the results of the method,
+// or nil if the method is not cached or the cache does not exist.
+func MethodCache(registry kit.Registry, method kit.Method) kit.Cache {
+	policy := method.GetCache()
+	if policy == nil {
+		return nil
+	}
+
+	if policy.Cache != "" {
+		return registry.Cache(policy.Cache)
+	}
+	return registry.DefaultCache()
+}
+
+// CacheKey builds the cache key for a method call from the method name, the
+// arguments and, if the cache policy is per user, the user id.
+func CacheKey(method kit.Method, r kit.Request, rawData interface{}) (string, apperror.Error) {
+	js, err := json.Marshal(rawData)
+	if err != nil {
+		return "", apperror.Wrap(err, "json_marshal_error")
+	}
+
+	hash := sha1.New()
+	hash.Write(js)
+
+	if method.GetCache().PerUser {
+		user := "anonymous"
+		if r.GetUser() != nil {
+			user = fmt.Sprintf("user:%v", r.GetUser().GetId())
+		}
+		hash.Write([]byte(user))
+	}
+
+	return "methods." + method.GetName() + "." + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+type cachedResponse struct {
+	// Serialized is true if the data was serialized with the default
+	// serializer, since it contained models.
+	Serialized bool                   `json:"serialized"`
+	Data       interface{}            `json:"data"`
+	Meta       map[string]interface{} `json:"meta"`
+}
+
+// CachedResponse returns the cached response for the key, or nil.
+func CachedResponse(registry kit.Registry, method kit.Method, key string) (kit.Response, apperror.Error) {
+	cache := MethodCache(registry, method)
+	if cache == nil {
+		return nil, nil
+	}
+
+	value, err := cache.GetString(key)
+	if err != nil {
+		return nil, err
+	} else if value == "" {
+		return nil, nil
+	}
+
+	var cached cachedResponse
+	if err := json.Unmarshal([]byte(value), &cached); err != nil {
+		return nil, apperror.Wrap(err, "json_unmarshal_error")
+	}
+
+	if !cached.Serialized {
+		return &kit.AppResponse{
+			Data: cached.Data,
+			Meta: cached.Meta,
+		}, nil
+	}
+
+	transferData, err := registry.DefaultSerializer().UnserializeTransferData(cached.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &kit.AppResponse{
+		TransferData: transferData,
+		Meta:         cached.Meta,
+	}, nil
+}
+
+// CacheResponse stores a successful response in the cache of the method.
+func CacheResponse(registry kit.Registry, method kit.Method, key string, response kit.Response) apperror.Error {
+	cache := MethodCache(registry, method)
+	if cache == nil || response.GetError() != nil {
+		return nil
+	}
+
+	cached := cachedResponse{
+		Data: response.GetData(),
+		Meta: response.GetMeta(),
+	}
+
+	// Models can not be restored from plain json, so responses
+	// containing models are serialized.
+	data := response.GetData()
+	_, isModel := data.(kit.Model)
+	_, isModelSlice := data.([]kit.Model)
+	if response.GetTransferData() != nil || isModel || isModelSlice {
+		serialized, err := registry.DefaultSerializer().SerializeResponse(&kit.AppResponse{
+			TransferData: response.GetTransferData(),
+			Data:         data,
+		})
+		if err != nil {
+			return err
+		}
+		cached.Serialized = true
+		cached.Data = serialized
+	}
+
+	js, err := json.Marshal(cached)
+	if err != nil {
+		return apperror.Wrap(err, "json_marshal_error")
+	}
+
+	policy := method.GetCache()
+
+	tags := append([]string{}, policy.Tags...)
+	for _, collection := range policy.Collections {
+		tags = append(tags, CollectionCacheTag(collection))
+	}
+
+	var expiresAt *time.Time
+	if policy.TTL > 0 {
+		t := time.Now().Add(policy.TTL)
+		expiresAt = &t
+	}
+
+	return cache.SetString(key, string(js), expiresAt, tags)
+}
+
+// ClearCollectionCaches clears the cached results of all methods that
+// depend on the collection.
+func ClearCollectionCaches(registry kit.Registry, collection string) {
+	tag := CollectionCacheTag(collection)
+
+	for _, method := range registry.Methods() {
+		policy := method.GetCache()
+		if policy == nil {
+			continue
+		}
+
+		for _, c := range policy.Collections {
+			if c != collection {
+				continue
+			}
+
+			if cache := MethodCache(registry, method); cache != nil {
+				if err := cache.ClearTag(tag); err != nil {
+					registry.Logger().Errorf("Could not clear cache tag %v: %v", tag, err)
+				}
+			}
+			break
+		}
+	}
+}
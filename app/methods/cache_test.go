@@ -0,0 +1,42 @@
+package methods_test
+
+import (
+	db "github.com/theduke/go-dukedb"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/app/methods"
+	"github.com/app-kit/go-appkit/users"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cache", func() {
+	data := map[string]interface{}{"a": "b"}
+
+	It("Should build equal keys for equal arguments", func() {
+		m := &Method{Name: "m", Cache: &kit.MethodCache{}}
+		r := kit.NewRequest()
+
+		key1, err := CacheKey(m, r, data)
+		Expect(err).To(BeNil())
+		key2, _ := CacheKey(m, r, map[string]interface{}{"a": "b"})
+		key3, _ := CacheKey(m, r, map[string]interface{}{"a": "c"})
+
+		Expect(key1).To(Equal(key2))
+		Expect(key1).ToNot(Equal(key3))
+	})
+
+	It("Should build user specific keys", func() {
+		m := &Method{Name: "m", Cache: &kit.MethodCache{PerUser: true}}
+
+		r1 := kit.NewRequest()
+		r1.SetUser(&users.UserStrId{StrIdModel: db.StrIdModel{Id: "1"}})
+		r2 := kit.NewRequest()
+		r2.SetUser(&users.UserStrId{StrIdModel: db.StrIdModel{Id: "2"}})
+
+		key1, _ := CacheKey(m, r1, data)
+		key2, _ := CacheKey(m, r2, data)
+		Expect(key1).ToNot(Equal(key2))
+	})
+})
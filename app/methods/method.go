@@ -1,13 +1,47 @@
 package methods
 
 import (
+	"time"
+
 	kit "github.com/app-kit/go-appkit"
 )
 
 type Method struct {
 	Name     string
 	Blocking bool
-	Handler  kit.MethodHandler
+
+	// Async methods are run as a task, and the caller immediately receives
+	// the task id.
+	Async bool
+
+	// Timeout overrides the methods.timeout config setting for this method.
+	Timeout time.Duration
+
+	// Arguments is the schema the request data is validated against before
+	// the handler runs.
+	Arguments []*kit.Argument
+
+	// ArgumentsStruct is a struct the validated request data is decoded into.
+	// The handler can then access a pointer to the struct with r.GetData().
+	// If Arguments is nil, the schema is built from the struct.
+	ArgumentsStruct interface{}
+
+	// RequireUser restricts the method to logged in users.
+	RequireUser bool
+
+	// Roles restricts the method to users with at least one of the roles.
+	Roles []string
+
+	// Permissions restricts the method to users with all of the permissions.
+	Permissions []string
+
+	// Cache enables caching of the method results.
+	Cache *kit.MethodCache
+
+	// RateLimit limits how often the method may be called.
+	RateLimit *kit.RateLimitPolicy
+
+	Handler kit.MethodHandler
 }
 
 // Ensure Method implements kit.Method interface.
@@ -21,6 +55,45 @@ func (m Method) IsBlocking() bool {
 	return m.Blocking
 }
 
+func (m Method) IsAsync() bool {
+	return m.Async
+}
+
+func (m Method) GetTimeout() time.Duration {
+	return m.Timeout
+}
+
+func (m Method) GetArguments() []*kit.Argument {
+	if m.Arguments == nil && m.ArgumentsStruct != nil {
+		return ArgumentsFromStruct(m.ArgumentsStruct)
+	}
+	return m.Arguments
+}
+
+func (m Method) GetArgumentsStruct() interface{} {
+	return m.ArgumentsStruct
+}
+
+func (m Method) GetPermissions() *kit.MethodPermissions {
+	if !m.RequireUser && len(m.Roles) == 0 && len(m.Permissions) == 0 {
+		return nil
+	}
+
+	return &kit.MethodPermissions{
+		Authenticated: true,
+		Roles:         m.Roles,
+		Permissions:   m.Permissions,
+	}
+}
+
+func (m Method) GetCache() *kit.MethodCache {
+	return m.Cache
+}
+
+func (m Method) GetRateLimit() *kit.RateLimitPolicy {
+	return m.RateLimit
+}
+
 func (m Method) GetHandler() kit.MethodHandler {
 	return m.Handler
 }
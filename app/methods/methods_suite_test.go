@@ -0,0 +1,13 @@
+package methods_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestMethods(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Methods Suite")
+}
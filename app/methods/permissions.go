@@ -0,0 +1,70 @@
+package methods
+
+import (
+	"fmt"
+
+	"github.com/theduke/go-apperror"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// CheckPermissions verifies that the user fulfills the permission
+// requirements of the method.
+// Returns a not_authenticated error if the method requires a user but none
+// is logged in, and a permission_denied error if the user lacks a required
+// role or permission.
+func CheckPermissions(method kit.Method, user kit.User) apperror.Error {
+	perms := method.GetPermissions()
+	if perms == nil {
+		return nil
+	}
+
+	if user == nil {
+		if perms.Authenticated || len(perms.Roles) > 0 || len(perms.Permissions) > 0 {
+			return &apperror.Err{
+				Code:    "not_authenticated",
+				Message: fmt.Sprintf("The method %v requires an authenticated user", method.GetName()),
+				Public:  true,
+				Status:  401,
+			}
+		}
+		return nil
+	}
+
+	if len(perms.Roles) > 0 && !user.HasRole(perms.Roles...) {
+		return &apperror.Err{
+			Code:    "permission_denied",
+			Message: fmt.Sprintf("The method %v requires one of the roles %v", method.GetName(), perms.Roles),
+			Data:    map[string]interface{}{"roles": perms.Roles},
+			Public:  true,
+			Status:  403,
+		}
+	}
+
+	for _, perm := range perms.Permissions {
+		if !user.HasPermission(perm) {
+			return &apperror.Err{
+				Code:    "permission_denied",
+				Message: fmt.Sprintf("The method %v requires the permission %v", method.GetName(), perm),
+				Data:    map[string]interface{}{"permission": perm},
+				Public:  true,
+				Status:  403,
+			}
+		}
+	}
+
+	return nil
+}
+
+// Describe returns the serializable description of a method,
+// including its arguments and permission requirements.
+func Describe(method kit.Method) *kit.MethodInfo {
+	return &kit.MethodInfo{
+		Name:        method.GetName(),
+		Blocking:    method.IsBlocking(),
+		Async:       method.IsAsync(),
+		Timeout:     method.GetTimeout().Seconds(),
+		Arguments:   method.GetArguments(),
+		Permissions: method.GetPermissions(),
+	}
+}
@@ -0,0 +1,63 @@
+package methods_test
+
+import (
+	"github.com/theduke/go-apperror"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/app/methods"
+	"github.com/app-kit/go-appkit/users"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Permissions", func() {
+	user := &users.User{
+		Roles: []*users.Role{
+			&users.Role{
+				Name: "editor",
+				Permissions: []*users.Permission{
+					&users.Permission{Name: "pages.publish"},
+				},
+			},
+		},
+	}
+
+	code := func(err apperror.Error) string {
+		if err == nil {
+			return ""
+		}
+		return err.GetCode()
+	}
+
+	It("Should allow public methods", func() {
+		Expect(CheckPermissions(&Method{Name: "m"}, nil)).To(BeNil())
+		Expect((&Method{Name: "m"}).GetPermissions()).To(BeNil())
+	})
+
+	It("Should require a user", func() {
+		m := &Method{Name: "m", RequireUser: true}
+		Expect(code(CheckPermissions(m, nil))).To(Equal("not_authenticated"))
+		Expect(CheckPermissions(m, user)).To(BeNil())
+	})
+
+	It("Should require roles", func() {
+		Expect(code(CheckPermissions(&Method{Name: "m", Roles: []string{"admin"}}, nil))).To(Equal("not_authenticated"))
+		Expect(code(CheckPermissions(&Method{Name: "m", Roles: []string{"admin"}}, user))).To(Equal("permission_denied"))
+		Expect(CheckPermissions(&Method{Name: "m", Roles: []string{"admin", "editor"}}, user)).To(BeNil())
+	})
+
+	It("Should require all permissions", func() {
+		Expect(CheckPermissions(&Method{Name: "m", Permissions: []string{"pages.publish"}}, user)).To(BeNil())
+		m := &Method{Name: "m", Permissions: []string{"pages.publish", "pages.delete"}}
+		Expect(code(CheckPermissions(m, user))).To(Equal("permission_denied"))
+	})
+
+	It("Should describe the requirements", func() {
+		m := &Method{Name: "m", Roles: []string{"admin"}}
+		Expect(Describe(m).Permissions).To(Equal(&kit.MethodPermissions{
+			Authenticated: true,
+			Roles:         []string{"admin"},
+		}))
+	})
+})
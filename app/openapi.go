@@ -0,0 +1,668 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/app/methods"
+	"github.com/app-kit/go-appkit/resources"
+)
+
+// OpenApiVersion is the version of the OpenAPI specification the generated
+// documents conform to.
+const OpenApiVersion = "3.0.3"
+
+// openApiObject is an object of an OpenAPI document.
+type openApiObject map[string]interface{}
+
+func schemaRef(name string) openApiObject {
+	return openApiObject{"$ref": "#/components/schemas/" + name}
+}
+
+// jsonContent wraps a schema in the content object of a json body.
+func jsonContent(schema openApiObject) openApiObject {
+	return openApiObject{
+		"application/json": openApiObject{"schema": schema},
+	}
+}
+
+func jsonResponse(description string, schema openApiObject) openApiObject {
+	return openApiObject{
+		"description": description,
+		"content":     jsonContent(schema),
+	}
+}
+
+// errorResponses are the responses of failed requests.
+func errorResponses(responses openApiObject) openApiObject {
+	responses["default"] = jsonResponse("Error", schemaRef("ErrorDocument"))
+	return responses
+}
+
+// openApiType converts the schema type of an attribute or argument.
+func openApiType(typ string) openApiObject {
+	switch typ {
+	case string(kit.ArgumentTypeString):
+		return openApiObject{"type": "string"}
+	case string(kit.ArgumentTypeInt):
+		return openApiObject{"type": "integer"}
+	case string(kit.ArgumentTypeFloat):
+		return openApiObject{"type": "number"}
+	case string(kit.ArgumentTypeBool):
+		return openApiObject{"type": "boolean"}
+	case "datetime":
+		return openApiObject{"type": "string", "format": "date-time"}
+	case string(kit.ArgumentTypeMap):
+		return openApiObject{"type": "object"}
+	case string(kit.ArgumentTypeList):
+		return openApiObject{"type": "array", "items": openApiObject{}}
+	}
+	return openApiObject{}
+}
+
+// applyBounds sets the bounds of numbers, or the length bounds of strings and
+// lists.
+func applyBounds(schema openApiObject, typ string, min, max *float64) {
+	minKey, maxKey := "minimum", "maximum"
+	switch typ {
+	case string(kit.ArgumentTypeString):
+		minKey, maxKey = "minLength", "maxLength"
+	case string(kit.ArgumentTypeList):
+		minKey, maxKey = "minItems", "maxItems"
+	case string(kit.ArgumentTypeMap), string(kit.ArgumentTypeBool), string(kit.ArgumentTypeAny):
+		return
+	}
+
+	if min != nil {
+		schema[minKey] = *min
+	}
+	if max != nil {
+		schema[maxKey] = *max
+	}
+}
+
+func attributeOpenApi(attr *kit.AttributeSchema) openApiObject {
+	schema := openApiType(attr.Type)
+	applyBounds(schema, attr.Type, attr.Min, attr.Max)
+	if attr.Default != nil {
+		schema["default"] = attr.Default
+	}
+	if attr.ReadOnly {
+		schema["readOnly"] = true
+	}
+	return schema
+}
+
+func argumentOpenApi(arg *kit.Argument) openApiObject {
+	schema := openApiType(string(arg.Type))
+	applyBounds(schema, string(arg.Type), arg.Min, arg.Max)
+	if arg.Description != "" {
+		schema["description"] = arg.Description
+	}
+	if len(arg.Enum) > 0 {
+		schema["enum"] = arg.Enum
+	}
+	if arg.Type == kit.ArgumentTypeMap && len(arg.Arguments) > 0 {
+		schema = argumentsOpenApi(arg.Arguments, schema)
+	}
+	if arg.Type == kit.ArgumentTypeList && arg.Items != nil {
+		schema["items"] = argumentOpenApi(arg.Items)
+	}
+	return schema
+}
+
+// argumentsOpenApi adds the arguments as properties to an object schema.
+func argumentsOpenApi(args []*kit.Argument, schema openApiObject) openApiObject {
+	schema["type"] = "object"
+
+	properties := openApiObject{}
+	required := make([]string, 0)
+	for _, arg := range args {
+		properties[arg.Name] = argumentOpenApi(arg)
+		if arg.Required {
+			required = append(required, arg.Name)
+		}
+	}
+
+	schema["properties"] = properties
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// resourceSchemas builds the JSONAPI schemas of a resource: the attributes,
+// the relationships, the resource object, and the documents of single
+// models and lists.
+func resourceSchemas(schema *kit.ResourceSchema, schemas openApiObject) {
+	collection := schema.Collection
+
+	properties := openApiObject{}
+	required := make([]string, 0)
+	for _, attr := range schema.Attributes {
+		if attr.Name == "id" {
+			continue
+		}
+		properties[attr.Name] = attributeOpenApi(attr)
+		if attr.Required && !attr.ReadOnly {
+			required = append(required, attr.Name)
+		}
+	}
+	attributes := openApiObject{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		attributes["required"] = required
+	}
+	schemas[collection+".attributes"] = attributes
+
+	relationships := openApiObject{}
+	for _, rel := range schema.Relations {
+		identifier := openApiObject{
+			"type": "object",
+			"properties": openApiObject{
+				"type": openApiObject{"type": "string", "enum": []string{rel.Collection}},
+				"id":   openApiObject{"type": "string"},
+			},
+		}
+		data := identifier
+		if rel.Cardinality == "many" {
+			data = openApiObject{"type": "array", "items": identifier}
+		}
+		relationships[rel.Name] = openApiObject{
+			"type":       "object",
+			"properties": openApiObject{"data": data},
+		}
+	}
+	schemas[collection+".relationships"] = openApiObject{
+		"type":       "object",
+		"properties": relationships,
+	}
+
+	schemas[collection] = openApiObject{
+		"type":     "object",
+		"required": []string{"type"},
+		"properties": openApiObject{
+			"type":          openApiObject{"type": "string", "enum": []string{collection}},
+			"id":            openApiObject{"type": "string"},
+			"attributes":    schemaRef(collection + ".attributes"),
+			"relationships": schemaRef(collection + ".relationships"),
+		},
+	}
+
+	schemas[collection+".document"] = openApiObject{
+		"type":     "object",
+		"required": []string{"data"},
+		"properties": openApiObject{
+			"data":     schemaRef(collection),
+			"included": openApiObject{"type": "array", "items": schemaRef("Resource")},
+			"meta":     openApiObject{"type": "object"},
+		},
+	}
+
+	schemas[collection+".list"] = openApiObject{
+		"type":     "object",
+		"required": []string{"data"},
+		"properties": openApiObject{
+			"data":     openApiObject{"type": "array", "items": schemaRef(collection)},
+			"included": openApiObject{"type": "array", "items": schemaRef("Resource")},
+			"meta":     openApiObject{"type": "object"},
+		},
+	}
+}
+
+// baseSchemas are the schemas shared by all operations.
+func baseSchemas() openApiObject {
+	return openApiObject{
+		"Resource": openApiObject{
+			"type":     "object",
+			"required": []string{"type", "id"},
+			"properties": openApiObject{
+				"type":          openApiObject{"type": "string"},
+				"id":            openApiObject{"type": "string"},
+				"attributes":    openApiObject{"type": "object"},
+				"relationships": openApiObject{"type": "object"},
+			},
+		},
+		"Error": openApiObject{
+			"type": "object",
+			"properties": openApiObject{
+				"code":    openApiObject{"type": "string"},
+				"message": openApiObject{"type": "string"},
+				"source": openApiObject{
+					"type": "object",
+					"properties": openApiObject{
+						"pointer": openApiObject{"type": "string"},
+					},
+				},
+			},
+		},
+		"ErrorDocument": openApiObject{
+			"type": "object",
+			"properties": openApiObject{
+				"errors": openApiObject{"type": "array", "items": schemaRef("Error")},
+			},
+		},
+		"Document": openApiObject{
+			"type": "object",
+			"properties": openApiObject{
+				"data": openApiObject{},
+				"meta": openApiObject{"type": "object"},
+			},
+		},
+		"Task": openApiObject{
+			"type": "object",
+			"properties": openApiObject{
+				"data": openApiObject{
+					"type": "object",
+					"properties": openApiObject{
+						"taskId": openApiObject{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// securitySchemes describes the schemes of the authentication middleware.
+// Both are sent in the Authentication header.
+func securitySchemes() openApiObject {
+	return openApiObject{
+		"session": openApiObject{
+			"type":        "apiKey",
+			"in":          "header",
+			"name":        "Authentication",
+			"description": "The token of a session, as returned by the users.authenticate method.",
+		},
+		"basic": openApiObject{
+			"type":        "apiKey",
+			"in":          "header",
+			"name":        "Authentication",
+			"description": "Basic followed by the base64 encoded username:password, like basic authentication with the Authentication header.",
+		},
+	}
+}
+
+// authenticated is the security requirement of operations that require a
+// user.
+func authenticated() []openApiObject {
+	return []openApiObject{
+		{"session": []string{}},
+		{"basic": []string{}},
+	}
+}
+
+func queryParameter(name, typ, description string) openApiObject {
+	return openApiObject{
+		"name":        name,
+		"in":          "query",
+		"description": description,
+		"schema":      openApiObject{"type": typ},
+	}
+}
+
+func pathParameter(name string) openApiObject {
+	return openApiObject{
+		"name":     name,
+		"in":       "path",
+		"required": true,
+		"schema":   openApiObject{"type": "string"},
+	}
+}
+
+// findParameters are the query parameters of find requests.
+func findParameters(collection string) []openApiObject {
+	return []openApiObject{
+		queryParameter("query", "string", "A json encoded query, as parsed by dukedb."),
+		queryParameter("limit", "integer", ""),
+		queryParameter("offset", "integer", ""),
+		queryParameter("page", "integer", ""),
+		queryParameter("per_page", "integer", ""),
+		queryParameter("page[size]", "integer", "The page size of cursor pagination."),
+		queryParameter("page[after]", "string", "The cursor after which the page starts."),
+		queryParameter("page[before]", "string", "The cursor before which the page ends."),
+		queryParameter("sort", "string", "The sort order of cursor pagination."),
+		queryParameter("count", "boolean", "Count the total number of results."),
+		queryParameter("joins", "string", "Comma separated relationships to include."),
+		queryParameter("filters", "string", "Comma separated field:value filters."),
+		queryParameter("fields["+collection+"]", "string", "Comma separated fields to return."),
+	}
+}
+
+// resourcePaths adds the JSONAPI routes of a resource.
+func resourcePaths(prefix string, res kit.Resource, paths openApiObject) {
+	collection := res.Collection()
+	path := prefix + "/" + strings.Replace(collection, "_", "-", -1)
+	tags := []string{collection}
+	document := schemaRef(collection + ".document")
+
+	paths[path] = openApiObject{
+		"get": openApiObject{
+			"tags":        tags,
+			"operationId": collection + ".find",
+			"parameters":  findParameters(collection),
+			"responses": errorResponses(openApiObject{
+				"200": jsonResponse("The models", schemaRef(collection+".list")),
+			}),
+		},
+		"post": openApiObject{
+			"tags":        tags,
+			"operationId": collection + ".create",
+			"requestBody": openApiObject{"required": true, "content": jsonContent(document)},
+			"responses": errorResponses(openApiObject{
+				"200": jsonResponse("The created model", document),
+			}),
+		},
+		"patch": openApiObject{
+			"tags":        tags,
+			"operationId": collection + ".bulkUpdate",
+			"description": "Updates the models in the request, or the models matching the query parameter with the attributes in the request.",
+			"parameters":  []openApiObject{queryParameter("query", "string", "A json encoded query, as parsed by dukedb.")},
+			"requestBody": openApiObject{"required": true, "content": jsonContent(openApiObject{})},
+			"responses": errorResponses(openApiObject{
+				"200": jsonResponse("The result of the update", schemaRef("Document")),
+			}),
+		},
+		"delete": openApiObject{
+			"tags":        tags,
+			"operationId": collection + ".bulkDelete",
+			"description": "Deletes the models in the request, or the models matching the query parameter.",
+			"parameters":  []openApiObject{queryParameter("query", "string", "A json encoded query, as parsed by dukedb.")},
+			"responses": errorResponses(openApiObject{
+				"200": jsonResponse("The result of the delete", schemaRef("Document")),
+			}),
+		},
+	}
+
+	paths[path+"/{id}"] = openApiObject{
+		"parameters": []openApiObject{pathParameter("id")},
+		"get": openApiObject{
+			"tags":        tags,
+			"operationId": collection + ".findOne",
+			"parameters":  []openApiObject{queryParameter("joins", "string", "Comma separated relationships to include.")},
+			"responses": errorResponses(openApiObject{
+				"200": jsonResponse("The model", document),
+			}),
+		},
+		"patch": openApiObject{
+			"tags":        tags,
+			"operationId": collection + ".update",
+			"requestBody": openApiObject{"required": true, "content": jsonContent(document)},
+			"responses": errorResponses(openApiObject{
+				"200": jsonResponse("The updated model", document),
+			}),
+		},
+		"delete": openApiObject{
+			"tags":        tags,
+			"operationId": collection + ".delete",
+			"responses": errorResponses(openApiObject{
+				"200": jsonResponse("The model was deleted", schemaRef("Document")),
+			}),
+		},
+	}
+
+	csv := res.Csv()
+	if csv == nil {
+		return
+	}
+	if csv.Export {
+		for format, contentType := range map[string]string{
+			"csv":  "text/csv",
+			"xlsx": resources.XlsxContentType,
+		} {
+			paths[path+"/export."+format] = openApiObject{
+				"get": openApiObject{
+					"tags":        tags,
+					"operationId": collection + ".export." + format,
+					"parameters":  findParameters(collection),
+					"responses": errorResponses(openApiObject{
+						"200": openApiObject{
+							"description": "The models as " + format,
+							"content": openApiObject{
+								contentType: openApiObject{"schema": openApiObject{"type": "string", "format": "binary"}},
+							},
+						},
+					}),
+				},
+			}
+		}
+	}
+	if csv.Import {
+		paths[path+"/import"] = openApiObject{
+			"post": openApiObject{
+				"tags":        tags,
+				"operationId": collection + ".import",
+				"security":    authenticated(),
+				"requestBody": uploadBody(),
+				"responses": errorResponses(openApiObject{
+					"202": jsonResponse("The import task was queued", schemaRef("Task")),
+				}),
+			},
+		}
+	}
+}
+
+// uploadBody is the body of file uploads.
+func uploadBody() openApiObject {
+	return openApiObject{
+		"required": true,
+		"content": openApiObject{
+			"multipart/form-data": openApiObject{
+				"schema": openApiObject{
+					"type": "object",
+					"properties": openApiObject{
+						"file": openApiObject{"type": "string", "format": "binary"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// methodOperation describes the REST route of a method.
+func methodOperation(info *kit.MethodInfo) openApiObject {
+	args := argumentsOpenApi(info.Arguments, openApiObject{})
+
+	operation := openApiObject{
+		"tags":        []string{"methods"},
+		"operationId": info.Name,
+		"requestBody": openApiObject{
+			"content": jsonContent(openApiObject{
+				"type":       "object",
+				"properties": openApiObject{"data": args},
+			}),
+		},
+		"responses": errorResponses(openApiObject{
+			"200": jsonResponse("The result of the method", schemaRef("Document")),
+		}),
+	}
+	if info.Async {
+		operation["description"] = "The method runs as a task. The response contains the task id."
+		operation["responses"] = errorResponses(openApiObject{
+			"200": jsonResponse("The method was queued", schemaRef("Task")),
+		})
+	}
+
+	if perms := info.Permissions; perms != nil {
+		if perms.Authenticated || len(perms.Roles) > 0 || len(perms.Permissions) > 0 {
+			operation["security"] = authenticated()
+		}
+		if len(perms.Roles) > 0 {
+			operation["x-roles"] = perms.Roles
+		}
+		if len(perms.Permissions) > 0 {
+			operation["x-permissions"] = perms.Permissions
+		}
+	}
+
+	return operation
+}
+
+// openApiPath converts the parameters of a router path to OpenAPI
+// parameters and returns the names of the parameters.
+func openApiPath(route string) (string, []string) {
+	parts := strings.Split(route, "/")
+	params := make([]string, 0)
+	for index, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			params = append(params, part[1:])
+			parts[index] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+// routePaths adds the custom http routes of a resource.
+// Options routes only answer preflight requests and are skipped.
+func routePaths(res kit.Resource, paths openApiObject) {
+	hook, ok := res.Hooks().(resources.ApiHttpRoutes)
+	if !ok {
+		return
+	}
+
+	for _, route := range hook.HttpRoutes(res) {
+		method := strings.ToLower(route.Method())
+		if method == "options" {
+			continue
+		}
+
+		path, params := openApiPath(route.Route())
+		item, _ := paths[path].(openApiObject)
+		if item == nil {
+			item = openApiObject{}
+			paths[path] = item
+		}
+
+		parameters := make([]openApiObject, 0)
+		for _, name := range params {
+			parameters = append(parameters, pathParameter(name))
+		}
+
+		operation := openApiObject{
+			"tags":       []string{res.Collection()},
+			"parameters": parameters,
+			"responses": errorResponses(openApiObject{
+				"200": openApiObject{"description": "Success"},
+			}),
+		}
+
+		// The upload route stores multipart files and responds with their
+		// temporary paths.
+		if route.Route() == "/api/file-upload" && method == "post" {
+			operation["operationId"] = "files.upload"
+			operation["requestBody"] = uploadBody()
+			operation["responses"] = errorResponses(openApiObject{
+				"200": jsonResponse("The temporary paths of the uploaded files", openApiObject{
+					"type": "object",
+					"properties": openApiObject{
+						"data": openApiObject{"type": "array", "items": openApiObject{"type": "string"}},
+					},
+				}),
+			})
+		}
+
+		item[method] = operation
+	}
+}
+
+// BuildOpenApi builds an OpenAPI 3 document of the JSONAPI routes of the
+// public resources, the REST routes of the methods and the custom http
+// routes of the resources.
+func BuildOpenApi(registry kit.Registry) map[string]interface{} {
+	config := registry.Config()
+	prefix := "/" + config.UString("api.prefix", "api")
+
+	schemas := baseSchemas()
+	paths := openApiObject{}
+
+	collections := make([]string, 0)
+	for collection := range registry.Resources() {
+		collections = append(collections, collection)
+	}
+	sort.Strings(collections)
+
+	for _, collection := range collections {
+		res := registry.Resource(collection)
+		if res.IsPublic() {
+			resourceSchemas(resources.ModelSchema(res), schemas)
+			resourcePaths(prefix, res, paths)
+		}
+		if res.Hooks() != nil {
+			routePaths(res, paths)
+		}
+	}
+
+	for name, method := range registry.Methods() {
+		paths["/api/method/"+name] = openApiObject{
+			"post": methodOperation(Describe(method)),
+		}
+	}
+
+	paths["/api/schema"] = openApiObject{
+		"get": openApiObject{
+			"tags":        []string{"methods"},
+			"operationId": "schema.get",
+			"responses": errorResponses(openApiObject{
+				"200": jsonResponse("The schema of the app", schemaRef("Document")),
+			}),
+		},
+	}
+	paths[prefix+"/openapi.json"] = openApiObject{
+		"get": openApiObject{
+			"operationId": "openapi",
+			"responses": errorResponses(openApiObject{
+				"200": jsonResponse("This document", openApiObject{"type": "object"}),
+			}),
+		},
+	}
+
+	doc := openApiObject{
+		"openapi": OpenApiVersion,
+		"info": openApiObject{
+			"title":   config.UString("openapi.title", "API"),
+			"version": config.UString("openapi.version", "1.0.0"),
+		},
+		"paths": paths,
+		"components": openApiObject{
+			"schemas":         schemas,
+			"securitySchemes": securitySchemes(),
+		},
+		// Authentication is optional, unless an operation overrides the
+		// requirement.
+		"security": []openApiObject{
+			{"session": []string{}},
+			{"basic": []string{}},
+			{},
+		},
+	}
+	if url := config.UString("url"); url != "" {
+		doc["servers"] = []openApiObject{{"url": url}}
+	}
+
+	return doc
+}
+
+// openApiHandler serves the OpenAPI document. Like the schema, it is only
+// served to admins if the schema.public setting is disabled.
+func openApiHandler(registry kit.Registry, r kit.Request) (kit.Response, bool) {
+	user := r.GetUser()
+	if !registry.Config().UBool("schema.public", true) && (user == nil || !user.HasRole("admin")) {
+		return kit.NewErrorResponse("permission_denied", ""), false
+	}
+
+	js, err := json.Marshal(BuildOpenApi(registry))
+	if err != nil {
+		return kit.NewErrorResponse(err, "openapi_marshal_error"), false
+	}
+
+	w := r.GetHttpResponseWriter()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(js)
+
+	return nil, true
+}
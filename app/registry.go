@@ -2,9 +2,11 @@ package app
 
 import (
 	"github.com/Sirupsen/logrus"
+	"github.com/theduke/go-apperror"
 	db "github.com/theduke/go-dukedb"
 
 	kit "github.com/app-kit/go-appkit"
+	"github.com/app-kit/go-appkit/resources"
 )
 
 type Registry struct {
@@ -34,6 +36,7 @@ type Registry struct {
 	userService     kit.UserService
 	templateEngine  kit.TemplateEngine
 	taskService     kit.TaskService
+	rateLimiter     kit.RateLimiter
 
 	values map[string]interface{}
 }
@@ -50,6 +53,7 @@ func NewRegistry() kit.Registry {
 		methods:     make(map[string]kit.Method),
 		serializers: make(map[string]kit.Serializer),
 		values:      make(map[string]interface{}),
+		eventBus:    kit.NewEventBus(),
 	}
 }
 
@@ -175,6 +179,49 @@ func (d *Registry) AllModelInfo() map[string]*db.ModelInfo {
 	return info
 }
 
+// WithTransaction runs fn inside a transaction of the default backend.
+// Resources have to be scoped to the transaction with res.WithBackend(tx).
+// The transaction is rolled back if fn returns an error or panics, and
+// committed otherwise. Cache invalidation and events of the resources only
+// happen after the commit.
+// If the default backend does not support transactions, fn receives the
+// default backend itself.
+func (d *Registry) WithTransaction(fn func(tx db.Backend) error) apperror.Error {
+	backend, ok := d.defaultBackend.(db.TransactionBackend)
+	if !ok {
+		return wrapTransactionError(fn(d.defaultBackend))
+	}
+
+	rawTx, err := backend.Begin()
+	if err != nil {
+		return err
+	}
+	tx := resources.NewTransaction(rawTx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return wrapTransactionError(err)
+	}
+
+	return tx.Commit()
+}
+
+func wrapTransactionError(err error) apperror.Error {
+	if err == nil {
+		return nil
+	} else if appErr, ok := err.(apperror.Error); ok {
+		return appErr
+	}
+	return apperror.Wrap(err, "transaction_error")
+}
+
 /**
  * Resources.
  */
@@ -327,6 +374,14 @@ func (d *Registry) SetTaskService(s kit.TaskService) {
 	d.taskService = s
 }
 
+func (d *Registry) RateLimiter() kit.RateLimiter {
+	return d.rateLimiter
+}
+
+func (d *Registry) SetRateLimiter(l kit.RateLimiter) {
+	d.rateLimiter = l
+}
+
 /**
  * Custom registrations.
  */
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"sort"
+
+	"github.com/theduke/go-apperror"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/app/methods"
+	"github.com/app-kit/go-appkit/resources"
+)
+
+// BuildSchema describes the public resources and the methods of the app
+// for a user.
+func BuildSchema(registry kit.Registry, user kit.User) *kit.Schema {
+	schema := &kit.Schema{
+		Resources: make([]*kit.ResourceSchema, 0),
+		Methods:   make([]*kit.MethodInfo, 0),
+	}
+
+	collections := make([]string, 0)
+	for collection, res := range registry.Resources() {
+		if res.IsPublic() {
+			collections = append(collections, collection)
+		}
+	}
+	sort.Strings(collections)
+	for _, collection := range collections {
+		schema.Resources = append(schema.Resources, resources.Schema(registry.Resource(collection), user))
+	}
+
+	names := make([]string, 0)
+	for name := range registry.Methods() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		schema.Methods = append(schema.Methods, Describe(registry.Method(name)))
+	}
+
+	return schema
+}
+
+// schemaMethod describes the app. With the schema.public setting disabled,
+// only admins may see the schema.
+var schemaMethod kit.Method = &Method{
+	Name:     "schema",
+	Blocking: false,
+	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
+		user := r.GetUser()
+		if !registry.Config().UBool("schema.public", true) && (user == nil || !user.HasRole("admin")) {
+			return kit.NewErrorResponse(&apperror.Err{
+				Code:    "permission_denied",
+				Message: "Only admins may see the schema",
+				Public:  true,
+				Status:  403,
+			})
+		}
+
+		return &kit.AppResponse{
+			Data: BuildSchema(registry, user),
+		}
+	},
+}
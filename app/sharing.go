@@ -0,0 +1,144 @@
+package app
+
+import (
+	"fmt"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/app/methods"
+	"github.com/app-kit/go-appkit/resources"
+)
+
+type shareArguments struct {
+	Collection string `json:"collection" arg:"required"`
+	Id         string `json:"id" arg:"required"`
+	UserId     string `json:"userId"`
+	Role       string `json:"role"`
+	Level      string `json:"level" arg:"required"`
+}
+
+type unshareArguments struct {
+	Collection string `json:"collection" arg:"required"`
+	Id         string `json:"id" arg:"required"`
+	UserId     string `json:"userId"`
+	Role       string `json:"role"`
+}
+
+// sharedResource returns the resource for a sharing call, or an error
+// response if the collection does not exist, is not shared or the user
+// does not have the required access level to the model.
+func sharedResource(registry kit.Registry, collection, id, level string, r kit.Request) (kit.Resource, kit.Response) {
+	res := requestResource(registry, collection, r)
+	if res == nil || !res.IsPublic() {
+		return nil, kit.NewErrorResponse("unknown_resource", fmt.Sprintf("The collection %v does not exist", collection))
+	} else if !res.IsShared() {
+		return nil, kit.NewErrorResponse("sharing_disabled", fmt.Sprintf("The collection %v is not shared", collection), true)
+	}
+
+	// Models the row policy hides from the user can not be shared.
+	model, err := res.FindOneForRequest(id, r)
+	if err != nil {
+		return nil, kit.NewErrorResponse(err)
+	} else if model == nil {
+		return nil, kit.NewErrorResponse("not_found", "")
+	}
+
+	if !resources.AccessAllows(res.AccessLevel(model, r.GetUser()), level) {
+		return nil, kit.NewErrorResponse("permission_denied", "")
+	}
+
+	return res, nil
+}
+
+func grantInfo(grant kit.AccessGrant) map[string]interface{} {
+	return map[string]interface{}{
+		"userId":    grant.GetUserId(),
+		"role":      grant.GetRole(),
+		"level":     grant.GetLevel(),
+		"grantedBy": grant.GetGrantedBy(),
+		"createdAt": grant.GetCreatedAt(),
+	}
+}
+
+var listGrantsMethod kit.Method = &Method{
+	Name:            "sharing.list",
+	Blocking:        false,
+	RequireUser:     true,
+	ArgumentsStruct: modelArguments{},
+	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
+		args, ok := r.GetData().(*modelArguments)
+		if !ok {
+			return kit.NewErrorResponse(UnpreparedArgumentsError())
+		}
+
+		res, errResponse := sharedResource(registry, args.Collection, args.Id, resources.AccessRead, r)
+		if errResponse != nil {
+			return errResponse
+		}
+
+		grants, err := res.Grants(args.Id)
+		if err != nil {
+			return kit.NewErrorResponse(err)
+		}
+
+		infos := make([]map[string]interface{}, 0)
+		for _, grant := range grants {
+			infos = append(infos, grantInfo(grant))
+		}
+
+		return &kit.AppResponse{
+			Data: infos,
+		}
+	},
+}
+
+var shareMethod kit.Method = &Method{
+	Name:            "sharing.share",
+	Blocking:        true,
+	RequireUser:     true,
+	ArgumentsStruct: shareArguments{},
+	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
+		args, ok := r.GetData().(*shareArguments)
+		if !ok {
+			return kit.NewErrorResponse(UnpreparedArgumentsError())
+		}
+
+		// Only users with admin access may share a model.
+		res, errResponse := sharedResource(registry, args.Collection, args.Id, resources.AccessAdmin, r)
+		if errResponse != nil {
+			return errResponse
+		}
+
+		grant, err := res.Share(args.Id, args.UserId, args.Role, args.Level, r.GetUser())
+		if err != nil {
+			return kit.NewErrorResponse(err)
+		}
+
+		return &kit.AppResponse{
+			Data: grantInfo(grant),
+		}
+	},
+}
+
+var unshareMethod kit.Method = &Method{
+	Name:            "sharing.unshare",
+	Blocking:        true,
+	RequireUser:     true,
+	ArgumentsStruct: unshareArguments{},
+	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
+		args, ok := r.GetData().(*unshareArguments)
+		if !ok {
+			return kit.NewErrorResponse(UnpreparedArgumentsError())
+		}
+
+		res, errResponse := sharedResource(registry, args.Collection, args.Id, resources.AccessAdmin, r)
+		if errResponse != nil {
+			return errResponse
+		}
+
+		if err := res.Unshare(args.Id, args.UserId, args.Role); err != nil {
+			return kit.NewErrorResponse(err)
+		}
+
+		return &kit.AppResponse{}
+	},
+}
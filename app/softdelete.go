@@ -0,0 +1,168 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/theduke/go-apperror"
+	expr "github.com/theduke/go-dukedb/expressions"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/app/methods"
+	"github.com/app-kit/go-appkit/tasks"
+)
+
+const purgeDeletedTaskName = "resources.purge_deleted"
+
+// softDeleteResource returns the resource for a restore or purge call, or an
+// error response if the collection does not exist or does not support soft
+// delete.
+func softDeleteResource(registry kit.Registry, collection string, r kit.Request) (kit.Resource, kit.Response) {
+	res := requestResource(registry, collection, r)
+	if res == nil || !res.IsPublic() {
+		return nil, kit.NewErrorResponse("unknown_resource", fmt.Sprintf("The collection %v does not exist", collection))
+	} else if !res.IsSoftDelete() {
+		return nil, kit.NewErrorResponse("soft_delete_disabled", fmt.Sprintf("The collection %v does not support soft delete", collection), true)
+	}
+	return res, nil
+}
+
+var restoreMethod kit.Method = &Method{
+	Name:            "restore",
+	Blocking:        true,
+	RequireUser:     true,
+	ArgumentsStruct: modelArguments{},
+	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
+		args, ok := r.GetData().(*modelArguments)
+		if !ok {
+			return kit.NewErrorResponse(UnpreparedArgumentsError())
+		}
+
+		res, errResponse := softDeleteResource(registry, args.Collection, r)
+		if errResponse != nil {
+			return errResponse
+		}
+
+		return res.ApiRestore(args.Id, r)
+	},
+}
+
+var purgeMethod kit.Method = &Method{
+	Name:            "purge",
+	Blocking:        true,
+	Roles:           []string{"admin"},
+	ArgumentsStruct: modelArguments{},
+	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
+		args, ok := r.GetData().(*modelArguments)
+		if !ok {
+			return kit.NewErrorResponse(UnpreparedArgumentsError())
+		}
+
+		res, errResponse := softDeleteResource(registry, args.Collection, r)
+		if errResponse != nil {
+			return errResponse
+		}
+
+		return res.ApiPurge(args.Id, r)
+	},
+}
+
+// purgeDeleted permanently deletes all soft deleted models that were
+// deleted before the cutoff and returns the number of purged models.
+func purgeDeleted(registry kit.Registry, cutoff time.Time) (int, apperror.Error) {
+	count := 0
+
+	for _, res := range registry.Resources() {
+		if !res.IsSoftDelete() {
+			continue
+		}
+
+		query := res.QIncludeDeleted().FilterExpr(expr.Lte("", "deleted_at", cutoff))
+		models, err := res.Query(query)
+		if err != nil {
+			return count, err
+		}
+
+		for _, model := range models {
+			if err := res.Purge(model, nil); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// purgeDeletedTask builds the task that purges soft deleted models after
+// the retention period.
+// Once complete, the task queues its next run.
+func (a *App) purgeDeletedTask() kit.TaskSpec {
+	retention := time.Duration(a.Config().UInt("resources.softDelete.retentionDays", 30)) * 24 * time.Hour
+
+	return &tasks.TaskSpec{
+		Name: purgeDeletedTaskName,
+		Handler: func(registry kit.Registry, task kit.Task, progressChan chan kit.Task) (interface{}, apperror.Error, bool) {
+			count, err := purgeDeleted(registry, time.Now().Add(-retention))
+			if err != nil {
+				return nil, err, false
+			}
+
+			registry.Logger().Debugf("Purged %v soft deleted models", count)
+			return map[string]interface{}{"purged": count}, nil, false
+		},
+		OnCompleteHandker: func(registry kit.Registry, task kit.Task) {
+			if task.IsComplete() {
+				a.queuePurgeDeletedTask()
+			}
+		},
+	}
+}
+
+// queuePurgeDeletedTask queues the next run of the purge task.
+func (a *App) queuePurgeDeletedTask() {
+	service := a.registry.TaskService()
+	interval := time.Duration(a.Config().UInt("resources.softDelete.purgeIntervalHours", 24)) * time.Hour
+
+	runAt := time.Now().Add(interval)
+
+	task := service.NewTask()
+	task.SetName(purgeDeletedTaskName)
+	task.SetRunAt(&runAt)
+
+	if err := service.Queue(task); err != nil {
+		a.Logger().Errorf("Could not queue the purge task: %v", err)
+	}
+}
+
+// registerPurgeDeletedTask registers the purge task with the task runner
+// if any resource uses soft delete, and queues it unless a run is already
+// pending.
+func (a *App) registerPurgeDeletedTask(runner kit.TaskRunner) {
+	softDelete := false
+	for _, res := range a.registry.Resources() {
+		if res.IsSoftDelete() {
+			softDelete = true
+			break
+		}
+	}
+	if !softDelete {
+		return
+	}
+
+	runner.RegisterTask(a.purgeDeletedTask())
+
+	backend := a.registry.DefaultBackend()
+	query := backend.Q(a.registry.TaskService().NewTask().Collection()).
+		Filter("Name", purgeDeletedTaskName).
+		Filter("Complete", false)
+	pending, err := backend.Count(query)
+	if err != nil {
+		a.Logger().Errorf("Could not check for a pending purge task: %v", err)
+		return
+	}
+
+	if pending == 0 {
+		a.queuePurgeDeletedTask()
+	}
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"fmt"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/app/methods"
+	"github.com/app-kit/go-appkit/resources"
+)
+
+type versionsArguments struct {
+	Collection string `json:"collection" arg:"required"`
+	Id         string `json:"id" arg:"required"`
+}
+
+type versionsDiffArguments struct {
+	Collection string `json:"collection" arg:"required"`
+	Id         string `json:"id" arg:"required"`
+	From       int    `json:"from" arg:"required"`
+	To         int    `json:"to" arg:"required"`
+}
+
+type versionsRevertArguments struct {
+	Collection string `json:"collection" arg:"required"`
+	Id         string `json:"id" arg:"required"`
+	Version    int    `json:"version" arg:"required"`
+}
+
+// versionedResource returns the resource and the current model for a
+// versions call, or an error response if the collection does not exist, is
+// not versioned or the user may not see the model.
+func versionedResource(registry kit.Registry, collection, id string, r kit.Request) (kit.Resource, kit.Model, kit.Response) {
+	res := requestResource(registry, collection, r)
+	if res == nil || !res.IsPublic() {
+		return nil, nil, kit.NewErrorResponse("unknown_resource", fmt.Sprintf("The collection %v does not exist", collection))
+	} else if !res.IsVersioned() {
+		return nil, nil, kit.NewErrorResponse("versioning_disabled", fmt.Sprintf("The collection %v is not versioned", collection), true)
+	}
+
+	// Users may only see the versions of models they may see.
+	response := res.ApiFindOne(id, r)
+	if response.GetError() != nil {
+		return nil, nil, response
+	}
+	model, ok := response.GetData().(kit.Model)
+	if !ok {
+		return nil, nil, kit.NewErrorResponse("not_found", "")
+	}
+
+	return res, model, nil
+}
+
+// versionInfo describes a version. The data only contains the fields the
+// user may read in the current model.
+func versionInfo(res kit.Resource, model kit.Model, version kit.ModelVersion, user kit.User) map[string]interface{} {
+	return map[string]interface{}{
+		"version":   version.GetVersion(),
+		"userId":    version.GetUserId(),
+		"createdAt": version.GetCreatedAt(),
+		"data":      res.ReadableVersionData(model, version, user),
+	}
+}
+
+var listVersionsMethod kit.Method = &Method{
+	Name:            "versions.list",
+	Blocking:        false,
+	ArgumentsStruct: versionsArguments{},
+	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
+		args, ok := r.GetData().(*versionsArguments)
+		if !ok {
+			return kit.NewErrorResponse(UnpreparedArgumentsError())
+		}
+
+		res, model, errResponse := versionedResource(registry, args.Collection, args.Id, r)
+		if errResponse != nil {
+			return errResponse
+		}
+
+		versions, err := res.Versions(args.Id)
+		if err != nil {
+			return kit.NewErrorResponse(err)
+		}
+
+		infos := make([]map[string]interface{}, 0)
+		for _, version := range versions {
+			infos = append(infos, versionInfo(res, model, version, r.GetUser()))
+		}
+
+		return &kit.AppResponse{
+			Data: infos,
+		}
+	},
+}
+
+var diffVersionsMethod kit.Method = &Method{
+	Name:            "versions.diff",
+	Blocking:        false,
+	ArgumentsStruct: versionsDiffArguments{},
+	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
+		args, ok := r.GetData().(*versionsDiffArguments)
+		if !ok {
+			return kit.NewErrorResponse(UnpreparedArgumentsError())
+		}
+
+		res, model, errResponse := versionedResource(registry, args.Collection, args.Id, r)
+		if errResponse != nil {
+			return errResponse
+		}
+
+		from, err := res.Version(args.Id, args.From)
+		if err != nil {
+			return kit.NewErrorResponse(err)
+		}
+		to, err := res.Version(args.Id, args.To)
+		if err != nil {
+			return kit.NewErrorResponse(err)
+		}
+		if from == nil || to == nil {
+			return kit.NewErrorResponse("unknown_version", "The version does not exist", true)
+		}
+
+		changes := resources.DiffVersionData(
+			res.ReadableVersionData(model, from, r.GetUser()),
+			res.ReadableVersionData(model, to, r.GetUser()),
+		)
+
+		return &kit.AppResponse{
+			Data: map[string]interface{}{
+				"from":    args.From,
+				"to":      args.To,
+				"changes": changes,
+			},
+		}
+	},
+}
+
+var revertVersionMethod kit.Method = &Method{
+	Name:            "versions.revert",
+	Blocking:        true,
+	RequireUser:     true,
+	ArgumentsStruct: versionsRevertArguments{},
+	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
+		args, ok := r.GetData().(*versionsRevertArguments)
+		if !ok {
+			return kit.NewErrorResponse(UnpreparedArgumentsError())
+		}
+
+		res, _, errResponse := versionedResource(registry, args.Collection, args.Id, r)
+		if errResponse != nil {
+			return errResponse
+		}
+
+		// The revert runs the regular update, including the hooks that check
+		// permissions.
+		reverted, err := res.Revert(args.Id, args.Version, r.GetUser())
+		if err != nil {
+			return kit.NewErrorResponse(err)
+		}
+
+		return res.ClearUnreadableFields(&kit.AppResponse{
+			Data: reverted,
+		}, r.GetUser())
+	},
+}
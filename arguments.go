@@ -0,0 +1,37 @@
+package appkit
+
+type ArgumentType string
+
+const (
+	ArgumentTypeString ArgumentType = "string"
+	ArgumentTypeInt    ArgumentType = "int"
+	ArgumentTypeFloat  ArgumentType = "float"
+	ArgumentTypeBool   ArgumentType = "bool"
+	ArgumentTypeMap    ArgumentType = "map"
+	ArgumentTypeList   ArgumentType = "list"
+	ArgumentTypeAny    ArgumentType = "any"
+)
+
+// Argument describes a single argument a method accepts.
+type Argument struct {
+	Name        string       `json:"name"`
+	Type        ArgumentType `json:"type"`
+	Description string       `json:"description,omitempty"`
+
+	// Required arguments must be present and may not be null or an empty string.
+	Required bool `json:"required,omitempty"`
+
+	// Enum restricts the argument to a set of allowed values.
+	Enum []interface{} `json:"enum,omitempty"`
+
+	// Min and Max are the bounds of numeric arguments, or the length bounds
+	// of string and list arguments.
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+
+	// Arguments describes the keys of a nested map argument.
+	Arguments []*Argument `json:"arguments,omitempty"`
+
+	// Items describes the items of a list argument.
+	Items *Argument `json:"items,omitempty"`
+}
@@ -0,0 +1,11 @@
+package appkit
+
+import (
+	"github.com/theduke/go-apperror"
+)
+
+// BulkResult is the result of a single model of a bulk operation.
+type BulkResult struct {
+	Model Model
+	Error apperror.Error
+}
@@ -46,6 +46,9 @@ type Redis struct {
 // Ensure redis implements the Cache interface.
 var _ kit.Cache = (*Redis)(nil)
 
+// Ensure redis implements the CounterCache interface.
+var _ kit.CounterCache = (*Redis)(nil)
+
 func redisErr(err error) Error {
 	return kit.AppError{
 		Code:    "redis_error",
@@ -318,6 +321,35 @@ func (r *Redis) Delete(keys ...string) Error {
 	return nil
 }
 
+// Increment atomically adds delta to a counter.
+func (r *Redis) Increment(key string, delta int, expiresAt *time.Time) (int, Error) {
+	if key == "" {
+		return 0, kit.AppError{Code: "empty_key"}
+	}
+	key = r.key(key)
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	conn.Send("MULTI")
+	conn.Send("INCRBY", key, delta)
+	if expiresAt != nil {
+		conn.Send("PEXPIREAT", key, expiresAt.UnixNano()/int64(time.Millisecond))
+	}
+
+	result, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return 0, redisErr(err)
+	}
+
+	count, err := redis.Int(result[0], nil)
+	if err != nil {
+		return 0, redisErr(err)
+	}
+
+	return count, nil
+}
+
 func (r *Redis) getRawKeys(conn redis.Conn) ([]string, Error) {
 	rawKeys, err := redis.Strings(conn.Do("KEYS", r.key("")+"*"))
 	if err != nil {
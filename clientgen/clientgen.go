@@ -0,0 +1,180 @@
+// Package clientgen generates typed TypeScript and Go clients for the
+// resources and methods of an app.
+//
+// The generated clients run all operations with the methods of the app, so
+// they work with the REST frontend and the WAMP frontend alike.
+package clientgen
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/app/methods"
+	"github.com/app-kit/go-appkit/resources"
+)
+
+// Model describes the model of a public resource.
+type Model struct {
+	// Name is the type name of the model in the generated code.
+	Name string
+
+	// Accessor is the name of the resource on the generated client.
+	Accessor string
+
+	Collection string
+	Attributes []*kit.AttributeSchema
+	Relations  []*Relation
+}
+
+// Relation describes a relationship of a model.
+type Relation struct {
+	*kit.RelationSchema
+
+	// Model is the type name of the related model, or empty if the related
+	// resource is not public.
+	Model string
+}
+
+// Method describes a method of the app.
+type Method struct {
+	*kit.MethodInfo
+
+	// FuncName is the name of the method in the generated code.
+	FuncName string
+}
+
+// Spec describes everything a client is generated from.
+type Spec struct {
+	Models  []*Model
+	Methods []*Method
+}
+
+// reservedNames are used by the generated code and can not be used for
+// models and resources.
+var reservedNames = map[string]bool{
+	"Client":         true,
+	"Transport":      true,
+	"HttpTransport":  true,
+	"WampTransport":  true,
+	"WampCall":       true,
+	"Query":          true,
+	"Filter":         true,
+	"Response":       true,
+	"Error":          true,
+	"ResourceObject": true,
+	"Identifier":     true,
+	"Relationship":   true,
+	"Methods":        true,
+	"Call":           true,
+	"Login":          true,
+	"Resume":         true,
+	"Logout":         true,
+	"Token":          true,
+	"SetToken":       true,
+	"SetBasicAuth":   true,
+}
+
+var nonAlphaNumeric = regexp.MustCompile("[^A-Za-z0-9]+")
+
+// Pascal converts names like users.send-confirmation_email to
+// UsersSendConfirmationEmail.
+func Pascal(name string) string {
+	parts := nonAlphaNumeric.Split(name, -1)
+	for index, part := range parts {
+		if part != "" {
+			parts[index] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// Camel converts names like users.send-confirmation_email to
+// usersSendConfirmationEmail.
+func Camel(name string) string {
+	pascal := Pascal(name)
+	if pascal == "" {
+		return pascal
+	}
+	return strings.ToLower(pascal[:1]) + pascal[1:]
+}
+
+// modelName returns the name of the model struct, or the collection name if
+// it is taken.
+func modelName(res kit.Resource, taken map[string]bool) string {
+	name := reflect.TypeOf(res.Model()).Elem().Name()
+	if name == "" || taken[name] {
+		name = Pascal(res.Collection())
+	}
+	if reservedNames[name] || taken[name] {
+		name += "Model"
+	}
+	return name
+}
+
+// Build describes the public resources and all methods of the registry.
+func Build(registry kit.Registry) *Spec {
+	spec := &Spec{
+		Models:  make([]*Model, 0),
+		Methods: make([]*Method, 0),
+	}
+
+	collections := make([]string, 0)
+	for collection, res := range registry.Resources() {
+		if res.IsPublic() {
+			collections = append(collections, collection)
+		}
+	}
+	sort.Strings(collections)
+
+	taken := make(map[string]bool)
+	names := make(map[string]string)
+	schemas := make(map[string]*kit.ResourceSchema)
+	for _, collection := range collections {
+		res := registry.Resource(collection)
+		name := modelName(res, taken)
+		taken[name] = true
+		names[collection] = name
+
+		accessor := Pascal(collection)
+		if reservedNames[accessor] {
+			accessor += "Resource"
+		}
+
+		schema := resources.ModelSchema(res)
+		schemas[collection] = schema
+		spec.Models = append(spec.Models, &Model{
+			Name:       name,
+			Accessor:   accessor,
+			Collection: collection,
+			Attributes: schema.Attributes,
+		})
+	}
+
+	// Relations are resolved once all models are named.
+	for _, model := range spec.Models {
+		for _, rel := range schemas[model.Collection].Relations {
+			model.Relations = append(model.Relations, &Relation{
+				RelationSchema: rel,
+				Model:          names[rel.Collection],
+			})
+		}
+	}
+
+	methodNames := make([]string, 0)
+	for name := range registry.Methods() {
+		methodNames = append(methodNames, name)
+	}
+	sort.Strings(methodNames)
+
+	for _, name := range methodNames {
+		spec.Methods = append(spec.Methods, &Method{
+			MethodInfo: Describe(registry.Method(name)),
+			FuncName:   Pascal(name),
+		})
+	}
+
+	return spec
+}
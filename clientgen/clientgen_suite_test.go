@@ -0,0 +1,13 @@
+package clientgen_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestClientgen(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Clientgen Suite")
+}
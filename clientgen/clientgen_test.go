@@ -0,0 +1,22 @@
+package clientgen_test
+
+import (
+	. "github.com/app-kit/go-appkit/clientgen"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Clientgen", func() {
+	It("Should convert names to pascal case", func() {
+		Expect(Pascal("users.send-confirmation_email")).To(Equal("UsersSendConfirmationEmail"))
+		Expect(Pascal("query")).To(Equal("Query"))
+		Expect(Pascal("")).To(Equal(""))
+	})
+
+	It("Should convert names to camel case", func() {
+		Expect(Camel("users.send-confirmation_email")).To(Equal("usersSendConfirmationEmail"))
+		Expect(Camel("file_upload")).To(Equal("fileUpload"))
+		Expect(Camel("")).To(Equal(""))
+	})
+})
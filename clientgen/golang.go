@@ -0,0 +1,884 @@
+package clientgen
+
+import (
+	"bytes"
+	"go/format"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/theduke/go-apperror"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// goType returns the Go type of an attribute type.
+func goType(typ string) string {
+	switch typ {
+	case string(kit.ArgumentTypeString):
+		return "string"
+	case string(kit.ArgumentTypeInt):
+		return "int"
+	case string(kit.ArgumentTypeFloat):
+		return "float64"
+	case string(kit.ArgumentTypeBool):
+		return "bool"
+	case "datetime":
+		return "time.Time"
+	case string(kit.ArgumentTypeMap):
+		return "map[string]interface{}"
+	case string(kit.ArgumentTypeList):
+		return "[]interface{}"
+	}
+	return "interface{}"
+}
+
+// goArgumentType returns the Go type of a method argument.
+func goArgumentType(arg *kit.Argument) string {
+	if arg.Type == kit.ArgumentTypeList && arg.Items != nil {
+		return "[]" + goArgumentType(arg.Items)
+	}
+	return goType(string(arg.Type))
+}
+
+// oneLine joins the lines of a description for comments.
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func usesTime(spec *Spec) bool {
+	for _, model := range spec.Models {
+		for _, attr := range model.Attributes {
+			if attr.Type == "datetime" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var goFuncs = template.FuncMap{
+	"pascal":       Pascal,
+	"quote":        strconv.Quote,
+	"oneLine":      oneLine,
+	"goType":       goType,
+	"goArgType":    goArgumentType,
+	"relatedModel": func(rel *Relation) bool { return rel.Model != "" },
+}
+
+// Go generates the Go client with the package name.
+func Go(spec *Spec, pkg string) ([]byte, apperror.Error) {
+	tpl, err := template.New("go").Funcs(goFuncs).Parse(goTemplate)
+	if err != nil {
+		return nil, apperror.Wrap(err, "template_parse_error", "")
+	}
+
+	var buffer bytes.Buffer
+	data := map[string]interface{}{
+		"Package":  pkg,
+		"Spec":     spec,
+		"UsesTime": usesTime(spec),
+	}
+	if err := tpl.Execute(&buffer, data); err != nil {
+		return nil, apperror.Wrap(err, "template_render_error", "")
+	}
+
+	code, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return nil, apperror.Wrap(err, "go_format_error", "The generated Go client is invalid")
+	}
+	return code, nil
+}
+
+const goTemplate = `// Code generated by appkit generate-client. DO NOT EDIT.
+
+// Package {{.Package}} is a client for the resources and methods of the app.
+package {{.Package}}
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+{{- if .UsesTime}}
+	"time"
+{{- end}}
+)
+
+// Error is an error returned by the app.
+type Error struct {
+	Code    string ` + "`json:\"code,omitempty\"`" + `
+	Message string ` + "`json:\"message,omitempty\"`" + `
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Identifier identifies a model in a relationship.
+type Identifier struct {
+	Type string ` + "`json:\"type\"`" + `
+	Id   string ` + "`json:\"id\"`" + `
+}
+
+// Relationship contains the identifiers of related models.
+type Relationship struct {
+	Data json.RawMessage ` + "`json:\"data\"`" + `
+}
+
+// Identifiers returns the identifiers of a to-one or to-many relationship.
+func (r *Relationship) Identifiers() []Identifier {
+	if r == nil || len(r.Data) == 0 {
+		return nil
+	}
+
+	var many []Identifier
+	if err := json.Unmarshal(r.Data, &many); err == nil {
+		return many
+	}
+
+	var one *Identifier
+	if err := json.Unmarshal(r.Data, &one); err == nil && one != nil {
+		return []Identifier{*one}
+	}
+	return nil
+}
+
+// ResourceObject is a JSONAPI resource object.
+type ResourceObject struct {
+	Type          string                   ` + "`json:\"type\"`" + `
+	Id            string                   ` + "`json:\"id,omitempty\"`" + `
+	Attributes    json.RawMessage          ` + "`json:\"attributes,omitempty\"`" + `
+	Relationships map[string]*Relationship ` + "`json:\"relationships,omitempty\"`" + `
+}
+
+// Response is the JSONAPI document returned by a method.
+type Response struct {
+	Data     json.RawMessage        ` + "`json:\"data,omitempty\"`" + `
+	Included []*ResourceObject      ` + "`json:\"included,omitempty\"`" + `
+	Meta     map[string]interface{} ` + "`json:\"meta,omitempty\"`" + `
+	Errors   []*Error               ` + "`json:\"errors,omitempty\"`" + `
+}
+
+// Err returns the first error of the response, or nil.
+func (r *Response) Err() error {
+	for _, err := range r.Errors {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Transport sends method calls to the app.
+type Transport interface {
+	// Call runs a method. auth is the value of the Authentication header,
+	// or empty.
+	Call(method string, data interface{}, auth string) (*Response, error)
+}
+
+// HttpTransport calls methods with the REST frontend.
+type HttpTransport struct {
+	// Url is the url of the app, like https://example.com.
+	Url    string
+	Client *http.Client
+}
+
+func NewHttpTransport(url string) *HttpTransport {
+	return &HttpTransport{
+		Url:    strings.TrimRight(url, "/"),
+		Client: http.DefaultClient,
+	}
+}
+
+func (t *HttpTransport) Call(method string, data interface{}, auth string) (*Response, error) {
+	body, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", t.Url+"/api/method/"+method, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if auth != "" {
+		req.Header.Set("Authentication", auth)
+	}
+
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	response := &Response{}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return nil, fmt.Errorf("invalid response with status %v: %v", resp.StatusCode, err)
+	}
+	return response, nil
+}
+
+// WampCall calls a WAMP procedure with keyword arguments and returns the
+// keyword arguments of the result. With a turnpike client:
+//
+//	func(procedure string, kwargs map[string]interface{}) (map[string]interface{}, error) {
+//		result, err := client.Call(procedure, nil, kwargs)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return result.ArgumentsKw, nil
+//	}
+type WampCall func(procedure string, kwargs map[string]interface{}) (map[string]interface{}, error)
+
+// WampTransport calls methods with the WAMP frontend.
+// WAMP sessions are authenticated with the Login and Resume methods of the
+// client, so the auth value is not used.
+type WampTransport struct {
+	call WampCall
+}
+
+func NewWampTransport(call WampCall) *WampTransport {
+	return &WampTransport{call: call}
+}
+
+func (t *WampTransport) Call(method string, data interface{}, auth string) (*Response, error) {
+	var plain interface{}
+	if err := convert(data, &plain); err != nil {
+		return nil, err
+	}
+
+	result, err := t.call(method, map[string]interface{}{"data": plain})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &Response{}
+	if err := convert(result, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// convert converts a value by encoding and decoding it as json.
+func convert(from, to interface{}) error {
+	js, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(js, to)
+}
+
+// Client runs the methods of the app and keeps the session token.
+type Client struct {
+	transport Transport
+	token     string
+	basicAuth string
+}
+
+func New(transport Transport) *Client {
+	return &Client{transport: transport}
+}
+
+// Token returns the token of the authenticated session.
+func (c *Client) Token() string {
+	return c.token
+}
+
+// SetToken authenticates http requests with the token of a session.
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// SetBasicAuth authenticates http requests with a username and password.
+func (c *Client) SetBasicAuth(user, password string) {
+	c.basicAuth = "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+}
+
+func (c *Client) auth() string {
+	if c.token != "" {
+		return c.token
+	}
+	return c.basicAuth
+}
+
+// Call runs a method. Errors in the response are returned as *Error.
+func (c *Client) Call(method string, data interface{}) (*Response, error) {
+	response, err := c.transport.Call(method, data, c.auth())
+	if err != nil {
+		return nil, err
+	}
+	if err := response.Err(); err != nil {
+		return response, err
+	}
+	return response, nil
+}
+
+// Login authenticates the session with a username and password.
+func (c *Client) Login(user, password string) error {
+	response, err := c.Call("users.authenticate", map[string]interface{}{
+		"user":     user,
+		"adaptor":  "password",
+		"authData": map[string]interface{}{"password": password},
+	})
+	if err != nil {
+		return err
+	}
+	return c.setSession(response)
+}
+
+// Resume authenticates the session with the token of an earlier session.
+func (c *Client) Resume(token string) error {
+	response, err := c.Call("users.resume_session", map[string]interface{}{"token": token})
+	if err != nil {
+		return err
+	}
+	return c.setSession(response)
+}
+
+// Logout removes the user from the session.
+func (c *Client) Logout() error {
+	_, err := c.Call("users.unauthenticate", map[string]interface{}{})
+	c.token = ""
+	return err
+}
+
+// setSession keeps the token of the session in a response.
+func (c *Client) setSession(response *Response) error {
+	session := &ResourceObject{}
+	if err := json.Unmarshal(response.Data, session); err != nil {
+		return err
+	}
+	c.token = session.Id
+	return nil
+}
+
+// Filter is a filter of a query, like {"age": {"$gt": 18}}.
+type Filter map[string]interface{}
+
+// Eq returns a filter that matches models whose field equals the value.
+func Eq(field string, value interface{}) Filter {
+	return Filter{field: value}
+}
+
+// Op returns a filter with an operator like $gt, $in or $like.
+func Op(field, operator string, value interface{}) Filter {
+	return Filter{field: map[string]interface{}{operator: value}}
+}
+
+// Query builds a query in the format of the query method.
+type Query struct {
+	client     *Client
+	collection string
+
+	filters Filter
+	joins   []string
+	fields  []string
+	order   []string
+	limit   int
+	offset  int
+
+	page  map[string]interface{}
+	count bool
+}
+
+func newQuery(client *Client, collection string) *Query {
+	return &Query{
+		client:     client,
+		collection: collection,
+		filters:    Filter{},
+	}
+}
+
+// Where filters by a field value.
+func (q *Query) Where(field string, value interface{}) *Query {
+	q.filters[field] = value
+	return q
+}
+
+// WhereOp filters with an operator like $gt, $in or $like.
+func (q *Query) WhereOp(field, operator string, value interface{}) *Query {
+	conditions, ok := q.filters[field].(map[string]interface{})
+	if !ok {
+		conditions = make(map[string]interface{})
+		q.filters[field] = conditions
+	}
+	conditions[operator] = value
+	return q
+}
+
+// Or matches models that match any of the filters.
+func (q *Query) Or(filters ...Filter) *Query {
+	or, _ := q.filters["$or"].([]Filter)
+	q.filters["$or"] = append(or, filters...)
+	return q
+}
+
+// Join includes related models.
+func (q *Query) Join(relations ...string) *Query {
+	q.joins = append(q.joins, relations...)
+	return q
+}
+
+// Fields restricts the returned fields.
+func (q *Query) Fields(fields ...string) *Query {
+	q.fields = append(q.fields, fields...)
+	return q
+}
+
+// OrderBy sorts by the fields. Prefix a field with - to sort descending.
+func (q *Query) OrderBy(fields ...string) *Query {
+	q.order = append(q.order, fields...)
+	return q
+}
+
+func (q *Query) Limit(limit int) *Query {
+	q.limit = limit
+	return q
+}
+
+func (q *Query) Offset(offset int) *Query {
+	q.offset = offset
+	return q
+}
+
+func (q *Query) setPage(key string, value interface{}) *Query {
+	if q.page == nil {
+		q.page = make(map[string]interface{})
+	}
+	q.page[key] = value
+	return q
+}
+
+// After returns the page after a cursor.
+func (q *Query) After(cursor string) *Query {
+	return q.setPage("after", cursor)
+}
+
+// Before returns the page before a cursor.
+func (q *Query) Before(cursor string) *Query {
+	return q.setPage("before", cursor)
+}
+
+// PageSize sets the size of cursor paginated pages.
+func (q *Query) PageSize(size int) *Query {
+	return q.setPage("size", size)
+}
+
+// Count adds the total number of results to the meta.
+func (q *Query) Count() *Query {
+	q.count = true
+	return q
+}
+
+// Raw returns the query in the format of the query method.
+func (q *Query) Raw() map[string]interface{} {
+	raw := map[string]interface{}{
+		"collection": q.collection,
+	}
+	if len(q.filters) > 0 {
+		raw["filters"] = q.filters
+	}
+	if len(q.joins) > 0 {
+		raw["joins"] = q.joins
+	}
+	if len(q.fields) > 0 {
+		raw["fields"] = q.fields
+	}
+	if len(q.order) > 0 {
+		raw["order"] = q.order
+	}
+	if q.limit > 0 {
+		raw["limit"] = q.limit
+	}
+	if q.offset > 0 {
+		raw["offset"] = q.offset
+	}
+	return raw
+}
+
+func (q *Query) run() (*Response, error) {
+	data := map[string]interface{}{
+		"query": q.Raw(),
+	}
+	if q.page != nil {
+		data["page"] = q.page
+	}
+	if q.count {
+		data["count"] = true
+	}
+	return q.client.Call("query", data)
+}
+
+// decoder is implemented by the models.
+type decoder interface {
+	decode(obj *ResourceObject, inc *included) error
+}
+
+// included resolves related models from the models of a response.
+type included struct {
+	objects map[string]*ResourceObject
+	models  map[string]decoder
+}
+
+func newIncluded(response *Response) *included {
+	inc := &included{
+		objects: make(map[string]*ResourceObject),
+		models:  make(map[string]decoder),
+	}
+	for _, obj := range response.Included {
+		inc.add(obj)
+	}
+	return inc
+}
+
+func (inc *included) add(obj *ResourceObject) {
+	inc.objects[obj.Type+":"+obj.Id] = obj
+}
+
+// resolve decodes the model of an identifier. Models that are not in the
+// response only have an id.
+func (inc *included) resolve(ident Identifier, model decoder) (decoder, error) {
+	key := ident.Type + ":" + ident.Id
+	if existing, ok := inc.models[key]; ok {
+		return existing, nil
+	}
+	inc.models[key] = model
+
+	obj := inc.objects[key]
+	if obj == nil {
+		obj = &ResourceObject{Type: ident.Type, Id: ident.Id}
+	}
+	if err := model.decode(obj, inc); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// decodeOne decodes the model in the data of a response.
+func decodeOne(response *Response, model decoder) error {
+	obj := &ResourceObject{}
+	if err := json.Unmarshal(response.Data, obj); err != nil {
+		return err
+	}
+
+	inc := newIncluded(response)
+	inc.add(obj)
+	_, err := inc.resolve(Identifier{Type: obj.Type, Id: obj.Id}, model)
+	return err
+}
+
+// decodeList decodes the models in the data of a response.
+func decodeList(response *Response, newModel func() decoder) ([]decoder, error) {
+	objects := make([]*ResourceObject, 0)
+	if err := json.Unmarshal(response.Data, &objects); err != nil {
+		return nil, err
+	}
+
+	inc := newIncluded(response)
+	for _, obj := range objects {
+		inc.add(obj)
+	}
+
+	models := make([]decoder, 0, len(objects))
+	for _, obj := range objects {
+		model, err := inc.resolve(Identifier{Type: obj.Type, Id: obj.Id}, newModel())
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, model)
+	}
+	return models, nil
+}
+
+func identifierData(collection, id string) *Relationship {
+	data, _ := json.Marshal(Identifier{Type: collection, Id: id})
+	return &Relationship{Data: data}
+}
+{{range $model := .Spec.Models}}
+// {{.Name}} is a model of the {{.Collection}} collection.
+type {{.Name}} struct {
+	Id string ` + "`json:\"-\"`" + `
+{{- range .Attributes}}{{if ne .Name "id"}}
+	{{pascal .Name}} {{goType .Type}} ` + "`json:\"{{.Name}}{{if .ReadOnly}},omitempty{{end}}\"`" + `
+{{- end}}{{end}}
+{{- range .Relations}}{{if relatedModel .}}
+	{{pascal .Name}} {{if eq .Cardinality "many"}}[]{{end}}*{{.Model}} ` + "`json:\"-\"`" + `
+{{- end}}{{end}}
+}
+
+// Fields of {{.Name}} for queries.
+const (
+{{- range .Attributes}}
+	{{$model.Name}}Field{{pascal .Name}} = {{quote .Name}}
+{{- end}}
+)
+
+func (m *{{.Name}}) decode(obj *ResourceObject, inc *included) error {
+	m.Id = obj.Id
+	if len(obj.Attributes) > 0 {
+		if err := json.Unmarshal(obj.Attributes, m); err != nil {
+			return err
+		}
+	}
+{{- range .Relations}}{{if relatedModel .}}
+	if rel := obj.Relationships[{{quote .Name}}]; rel != nil {
+{{- if eq .Cardinality "many"}}
+		m.{{pascal .Name}} = nil
+{{- end}}
+		for _, ident := range rel.Identifiers() {
+			model, err := inc.resolve(ident, &{{.Model}}{})
+			if err != nil {
+				return err
+			}
+{{- if eq .Cardinality "many"}}
+			m.{{pascal .Name}} = append(m.{{pascal .Name}}, model.(*{{.Model}}))
+{{- else}}
+			m.{{pascal .Name}} = model.(*{{.Model}})
+{{- end}}
+		}
+	}
+{{- end}}{{end}}
+	return nil
+}
+
+func (m *{{.Name}}) encode() (*ResourceObject, error) {
+	attributes, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &ResourceObject{
+		Type:          {{quote .Collection}},
+		Id:            m.Id,
+		Attributes:    attributes,
+		Relationships: make(map[string]*Relationship),
+	}
+{{- range .Relations}}{{if and (relatedModel .) (ne .Cardinality "many")}}
+	if m.{{pascal .Name}} != nil && m.{{pascal .Name}}.Id != "" {
+		obj.Relationships[{{quote .Name}}] = identifierData({{quote .Collection}}, m.{{pascal .Name}}.Id)
+	}
+{{- end}}{{end}}
+	return obj, nil
+}
+
+// {{.Name}}Query builds queries of the {{.Collection}} collection.
+type {{.Name}}Query struct {
+	query *Query
+}
+
+// Where filters by a field value.
+func (q *{{.Name}}Query) Where(field string, value interface{}) *{{.Name}}Query {
+	q.query.Where(field, value)
+	return q
+}
+
+// WhereOp filters with an operator like $gt, $in or $like.
+func (q *{{.Name}}Query) WhereOp(field, operator string, value interface{}) *{{.Name}}Query {
+	q.query.WhereOp(field, operator, value)
+	return q
+}
+
+// Or matches models that match any of the filters.
+func (q *{{.Name}}Query) Or(filters ...Filter) *{{.Name}}Query {
+	q.query.Or(filters...)
+	return q
+}
+
+// Join includes related models.
+func (q *{{.Name}}Query) Join(relations ...string) *{{.Name}}Query {
+	q.query.Join(relations...)
+	return q
+}
+
+// Fields restricts the returned fields.
+func (q *{{.Name}}Query) Fields(fields ...string) *{{.Name}}Query {
+	q.query.Fields(fields...)
+	return q
+}
+
+// OrderBy sorts by the fields. Prefix a field with - to sort descending.
+func (q *{{.Name}}Query) OrderBy(fields ...string) *{{.Name}}Query {
+	q.query.OrderBy(fields...)
+	return q
+}
+
+func (q *{{.Name}}Query) Limit(limit int) *{{.Name}}Query {
+	q.query.Limit(limit)
+	return q
+}
+
+func (q *{{.Name}}Query) Offset(offset int) *{{.Name}}Query {
+	q.query.Offset(offset)
+	return q
+}
+
+// After returns the page after a cursor.
+func (q *{{.Name}}Query) After(cursor string) *{{.Name}}Query {
+	q.query.After(cursor)
+	return q
+}
+
+// Before returns the page before a cursor.
+func (q *{{.Name}}Query) Before(cursor string) *{{.Name}}Query {
+	q.query.Before(cursor)
+	return q
+}
+
+// PageSize sets the size of cursor paginated pages.
+func (q *{{.Name}}Query) PageSize(size int) *{{.Name}}Query {
+	q.query.PageSize(size)
+	return q
+}
+
+// Count adds the total number of results to the meta.
+func (q *{{.Name}}Query) Count() *{{.Name}}Query {
+	q.query.Count()
+	return q
+}
+
+// Raw returns the query in the format of the query method.
+func (q *{{.Name}}Query) Raw() map[string]interface{} {
+	return q.query.Raw()
+}
+
+// Find returns the matching models and the meta of the response.
+func (q *{{.Name}}Query) Find() ([]*{{.Name}}, map[string]interface{}, error) {
+	response, err := q.query.run()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decoded, err := decodeList(response, func() decoder { return &{{.Name}}{} })
+	if err != nil {
+		return nil, nil, err
+	}
+
+	models := make([]*{{.Name}}, 0, len(decoded))
+	for _, model := range decoded {
+		models = append(models, model.(*{{.Name}}))
+	}
+	return models, response.Meta, nil
+}
+
+// First returns the first matching model, or nil.
+func (q *{{.Name}}Query) First() (*{{.Name}}, error) {
+	models, _, err := q.Limit(1).Find()
+	if err != nil || len(models) == 0 {
+		return nil, err
+	}
+	return models[0], nil
+}
+
+// {{.Name}}Resource runs the operations of the {{.Collection}} collection.
+type {{.Name}}Resource struct {
+	client *Client
+}
+
+func (c *Client) {{.Accessor}}() *{{.Name}}Resource {
+	return &{{.Name}}Resource{client: c}
+}
+
+// Query starts a query of the collection.
+func (r *{{.Name}}Resource) Query() *{{.Name}}Query {
+	return &{{.Name}}Query{query: newQuery(r.client, {{quote .Collection}})}
+}
+
+// FindOne returns the model with the id.
+func (r *{{.Name}}Resource) FindOne(id string) (*{{.Name}}, error) {
+	response, err := r.client.Call("find_one", map[string]interface{}{
+		"collection": {{quote .Collection}},
+		"id":         id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	model := &{{.Name}}{}
+	if err := decodeOne(response, model); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// Create creates the model and returns the created model.
+func (r *{{.Name}}Resource) Create(model *{{.Name}}) (*{{.Name}}, error) {
+	return r.save("create", model)
+}
+
+// Update updates all attributes of the model and returns the updated model.
+func (r *{{.Name}}Resource) Update(model *{{.Name}}) (*{{.Name}}, error) {
+	return r.save("update", model)
+}
+
+func (r *{{.Name}}Resource) save(method string, model *{{.Name}}) (*{{.Name}}, error) {
+	obj, err := model.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := r.client.Call(method, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	saved := &{{.Name}}{}
+	if err := decodeOne(response, saved); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// Delete deletes the model with the id.
+func (r *{{.Name}}Resource) Delete(id string) error {
+	_, err := r.client.Call("delete", map[string]interface{}{
+		"collection": {{quote .Collection}},
+		"id":         id,
+	})
+	return err
+}
+{{end}}
+// Methods runs the methods of the app.
+type Methods struct {
+	client *Client
+}
+
+func (c *Client) Methods() *Methods {
+	return &Methods{client: c}
+}
+{{range .Spec.Methods}}{{if .Arguments}}
+// {{.FuncName}}Args are the arguments of the {{.Name}} method.
+type {{.FuncName}}Args struct {
+{{- range .Arguments}}
+{{- if .Description}}
+	// {{oneLine .Description}}
+{{- end}}
+	{{pascal .Name}} {{goArgType .}} ` + "`json:\"{{.Name}}{{if not .Required}},omitempty{{end}}\"`" + `
+{{- end}}
+}
+
+// {{.FuncName}} runs the {{.Name}} method.
+{{- if .Async}}
+// The method runs as a task, the response contains the task id.
+{{- end}}
+func (m *Methods) {{.FuncName}}(args *{{.FuncName}}Args) (*Response, error) {
+	return m.client.Call({{quote .Name}}, args)
+}
+{{else}}
+// {{.FuncName}} runs the {{.Name}} method.
+{{- if .Async}}
+// The method runs as a task, the response contains the task id.
+{{- end}}
+func (m *Methods) {{.FuncName}}(data interface{}) (*Response, error) {
+	return m.client.Call({{quote .Name}}, data)
+}
+{{end}}{{end}}`
@@ -0,0 +1,598 @@
+package clientgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/theduke/go-apperror"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+var tsIdentifier = regexp.MustCompile("^[A-Za-z_$][A-Za-z0-9_$]*$")
+
+// tsKey quotes property names that are no identifiers.
+func tsKey(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	js, _ := json.Marshal(name)
+	return string(js)
+}
+
+// tsType returns the TypeScript type of an attribute type.
+// Datetimes are sent as RFC 3339 strings.
+func tsType(typ string) string {
+	switch typ {
+	case string(kit.ArgumentTypeString), "datetime":
+		return "string"
+	case string(kit.ArgumentTypeInt), string(kit.ArgumentTypeFloat):
+		return "number"
+	case string(kit.ArgumentTypeBool):
+		return "boolean"
+	case string(kit.ArgumentTypeMap):
+		return "{ [key: string]: any }"
+	case string(kit.ArgumentTypeList):
+		return "any[]"
+	}
+	return "any"
+}
+
+// tsArgumentType returns the TypeScript type of a method argument.
+func tsArgumentType(arg *kit.Argument) string {
+	if len(arg.Enum) > 0 {
+		values := make([]string, 0, len(arg.Enum))
+		for _, value := range arg.Enum {
+			js, err := json.Marshal(value)
+			if err != nil {
+				return tsType(string(arg.Type))
+			}
+			values = append(values, string(js))
+		}
+		return strings.Join(values, " | ")
+	}
+
+	switch arg.Type {
+	case kit.ArgumentTypeMap:
+		if len(arg.Arguments) > 0 {
+			return tsArgumentsType(arg.Arguments, true)
+		}
+	case kit.ArgumentTypeList:
+		if arg.Items != nil {
+			return "Array<" + tsArgumentType(arg.Items) + ">"
+		}
+	}
+	return tsType(string(arg.Type))
+}
+
+// tsArgumentsType returns an object type with the arguments as properties.
+func tsArgumentsType(args []*kit.Argument, open bool) string {
+	properties := make([]string, 0, len(args))
+	for _, arg := range args {
+		optional := "?"
+		if arg.Required {
+			optional = ""
+		}
+		properties = append(properties, tsKey(arg.Name)+optional+": "+tsArgumentType(arg))
+	}
+	if open {
+		properties = append(properties, "[key: string]: any")
+	}
+	return "{ " + strings.Join(properties, "; ") + " }"
+}
+
+var tsFuncs = template.FuncMap{
+	"camel":        Camel,
+	"key":          tsKey,
+	"tsType":       tsType,
+	"tsArgsType":   func(args []*kit.Argument) string { return tsArgumentsType(args, false) },
+	"quote":        func(s string) string { js, _ := json.Marshal(s); return string(js) },
+	"relatedModel": func(rel *Relation) bool { return rel.Model != "" },
+}
+
+// TypeScript generates the TypeScript client.
+func TypeScript(spec *Spec) ([]byte, apperror.Error) {
+	tpl, err := template.New("typescript").Funcs(tsFuncs).Parse(tsTemplate)
+	if err != nil {
+		return nil, apperror.Wrap(err, "template_parse_error", "")
+	}
+
+	var buffer bytes.Buffer
+	if err := tpl.Execute(&buffer, spec); err != nil {
+		return nil, apperror.Wrap(err, "template_render_error", "")
+	}
+	return buffer.Bytes(), nil
+}
+
+const tsTemplate = `// Code generated by appkit generate-client. DO NOT EDIT.
+
+export interface Identifier {
+  type: string;
+  id: string;
+}
+
+export interface ResourceObject {
+  type: string;
+  id?: string;
+  attributes?: { [key: string]: any };
+  relationships?: { [name: string]: { data: Identifier | Identifier[] | null } };
+}
+
+export interface ApiError {
+  code?: string;
+  message?: string;
+  source?: { pointer?: string };
+}
+
+// Document is the JSONAPI document returned by a method.
+export interface Document {
+  data?: any;
+  included?: ResourceObject[];
+  meta?: { [key: string]: any };
+  errors?: ApiError[];
+}
+
+// ClientError is thrown for the errors returned by the app.
+export class ClientError extends Error {
+  code: string;
+  errors: ApiError[];
+
+  constructor(errors: ApiError[]) {
+    super(errors[0].message || errors[0].code || 'Unknown error');
+    this.code = errors[0].code || '';
+    this.errors = errors;
+  }
+}
+
+// Transport sends method calls to the app.
+export interface Transport {
+  // auth is the value of the Authentication header, or null.
+  call(method: string, data: any, auth: string | null): Promise<Document>;
+}
+
+// HttpTransport calls methods with the REST frontend.
+export class HttpTransport implements Transport {
+  private url: string;
+
+  // url is the url of the app, like https://example.com.
+  constructor(url: string) {
+    this.url = url.replace(/\/+$/, '');
+  }
+
+  async call(method: string, data: any, auth: string | null): Promise<Document> {
+    const headers: { [key: string]: string } = { 'Content-Type': 'application/json' };
+    if (auth) {
+      headers['Authentication'] = auth;
+    }
+
+    const response = await fetch(this.url + '/api/method/' + method, {
+      method: 'POST',
+      headers,
+      body: JSON.stringify({ data }),
+    });
+    return response.json();
+  }
+}
+
+// WampSession is an autobahn session, or a session with a compatible call
+// method.
+export interface WampSession {
+  call(procedure: string, args?: any[], kwargs?: { [key: string]: any }): PromiseLike<any>;
+}
+
+// WampTransport calls methods with the WAMP frontend.
+// WAMP sessions are authenticated with the login and resume methods of the
+// client, so the auth value is not used.
+export class WampTransport implements Transport {
+  constructor(private session: WampSession) {}
+
+  async call(method: string, data: any, auth: string | null): Promise<Document> {
+    const result = await this.session.call(method, [], { data });
+    // Autobahn returns results with keyword arguments as Result objects.
+    return (result && result.kwargs) || result || {};
+  }
+}
+
+export type Operator = '$eq' | '$ne' | '$gt' | '$gte' | '$lt' | '$lte' | '$in' | '$nin' | '$like';
+
+// Filter is a filter of a query, like {"age": {"$gt": 18}}.
+export type Filter = { [field: string]: any };
+
+// eq returns a filter that matches models whose field equals the value.
+export function eq(field: string, value: any): Filter {
+  return { [field]: value };
+}
+
+// op returns a filter with an operator.
+export function op(field: string, operator: Operator, value: any): Filter {
+  return { [field]: { [operator]: value } };
+}
+
+// RawQuery is the format of the queries of the query method.
+export interface RawQuery {
+  collection: string;
+  filters?: Filter;
+  joins?: string[];
+  fields?: string[];
+  order?: string[];
+  limit?: number;
+  offset?: number;
+}
+
+export interface Page {
+  after?: string;
+  before?: string;
+  size?: number;
+}
+
+export interface FindResult<T> {
+  items: T[];
+  meta: { [key: string]: any };
+}
+
+// Query builds queries of a collection. A contains the attributes that can be
+// filtered and sorted by.
+export class Query<T, A> {
+  private filters: Filter = {};
+  private joins: string[] = [];
+  private fields: string[] = [];
+  private order: string[] = [];
+  private limitValue = 0;
+  private offsetValue = 0;
+  private page: Page | null = null;
+  private countTotal = false;
+
+  constructor(private client: Client, readonly collection: string) {}
+
+  // where filters by a field value, or with an operator like $gt.
+  where<K extends keyof A & string>(field: K, value: A[K] | null): this;
+  where<K extends keyof A & string>(field: K, operator: Operator, value: any): this;
+  where(field: string, ...args: any[]): this {
+    if (args.length < 2) {
+      this.filters[field] = args[0];
+      return this;
+    }
+
+    let conditions = this.filters[field];
+    if (!conditions || typeof conditions !== 'object' || Array.isArray(conditions)) {
+      conditions = {};
+      this.filters[field] = conditions;
+    }
+    conditions[args[0]] = args[1];
+    return this;
+  }
+
+  // or matches models that match any of the filters.
+  or(...filters: Filter[]): this {
+    this.filters['$or'] = (this.filters['$or'] || []).concat(filters);
+    return this;
+  }
+
+  // join includes related models.
+  join(...relations: string[]): this {
+    this.joins = this.joins.concat(relations);
+    return this;
+  }
+
+  // select restricts the returned fields.
+  select(...fields: Array<keyof A & string>): this {
+    this.fields = this.fields.concat(fields);
+    return this;
+  }
+
+  orderBy(field: keyof A & string, direction: 'asc' | 'desc' = 'asc'): this {
+    this.order.push(direction === 'desc' ? '-' + field : field);
+    return this;
+  }
+
+  limit(limit: number): this {
+    this.limitValue = limit;
+    return this;
+  }
+
+  offset(offset: number): this {
+    this.offsetValue = offset;
+    return this;
+  }
+
+  // after returns the page after a cursor.
+  after(cursor: string): this {
+    this.page = { ...this.page, after: cursor };
+    return this;
+  }
+
+  // before returns the page before a cursor.
+  before(cursor: string): this {
+    this.page = { ...this.page, before: cursor };
+    return this;
+  }
+
+  // pageSize sets the size of cursor paginated pages.
+  pageSize(size: number): this {
+    this.page = { ...this.page, size };
+    return this;
+  }
+
+  // count adds the total number of results to the meta.
+  count(): this {
+    this.countTotal = true;
+    return this;
+  }
+
+  // toJSON returns the query in the format of the query method.
+  toJSON(): RawQuery {
+    const raw: RawQuery = { collection: this.collection };
+    if (Object.keys(this.filters).length > 0) {
+      raw.filters = this.filters;
+    }
+    if (this.joins.length > 0) {
+      raw.joins = this.joins;
+    }
+    if (this.fields.length > 0) {
+      raw.fields = this.fields;
+    }
+    if (this.order.length > 0) {
+      raw.order = this.order;
+    }
+    if (this.limitValue > 0) {
+      raw.limit = this.limitValue;
+    }
+    if (this.offsetValue > 0) {
+      raw.offset = this.offsetValue;
+    }
+    return raw;
+  }
+
+  // find returns the matching models and the meta of the response.
+  async find(): Promise<FindResult<T>> {
+    const data: { [key: string]: any } = { query: this.toJSON() };
+    if (this.page) {
+      data.page = this.page;
+    }
+    if (this.countTotal) {
+      data.count = true;
+    }
+
+    const doc = await this.client.call('query', data);
+    return { items: decodeList<T>(doc), meta: doc.meta || {} };
+  }
+
+  // first returns the first matching model, or null.
+  async first(): Promise<T | null> {
+    const result = await this.limit(1).find();
+    return result.items.length > 0 ? result.items[0] : null;
+  }
+}
+
+// Decoder resolves the relationships of the models of a document with the
+// included models. Models that are not included only have an id.
+class Decoder {
+  private objects: { [key: string]: ResourceObject } = {};
+  private models: { [key: string]: any } = {};
+
+  constructor(doc: Document) {
+    for (const obj of doc.included || []) {
+      this.add(obj);
+    }
+  }
+
+  add(obj: ResourceObject) {
+    this.objects[obj.type + ':' + obj.id] = obj;
+  }
+
+  resolve(ident: Identifier): any {
+    const key = ident.type + ':' + ident.id;
+    if (key in this.models) {
+      return this.models[key];
+    }
+
+    const obj: ResourceObject = this.objects[key] || { type: ident.type, id: ident.id };
+    const model: any = { id: obj.id };
+    this.models[key] = model;
+
+    const attributes = obj.attributes || {};
+    for (const name of Object.keys(attributes)) {
+      model[name] = attributes[name];
+    }
+
+    const relationships = obj.relationships || {};
+    for (const name of Object.keys(relationships)) {
+      const data = relationships[name].data;
+      if (Array.isArray(data)) {
+        model[name] = data.map(item => this.resolve(item));
+      } else {
+        model[name] = data ? this.resolve(data) : null;
+      }
+    }
+    return model;
+  }
+}
+
+function decodeOne<T>(doc: Document): T {
+  if (!doc.data) {
+    throw new ClientError([{ code: 'not_found' }]);
+  }
+
+  const decoder = new Decoder(doc);
+  decoder.add(doc.data);
+  return decoder.resolve(doc.data);
+}
+
+function decodeList<T>(doc: Document): T[] {
+  const objects: ResourceObject[] = doc.data || [];
+  const decoder = new Decoder(doc);
+  objects.forEach(obj => decoder.add(obj));
+  return objects.map(obj => decoder.resolve(obj as Identifier));
+}
+
+// Relations maps the relationships of a model to the related collection.
+export interface Relations {
+  [name: string]: { collection: string; many: boolean };
+}
+
+// encode converts a model to a resource object. To-one relationships are
+// sent as identifiers.
+function encode(collection: string, relations: Relations, model: any): ResourceObject {
+  const obj: ResourceObject = { type: collection, attributes: {}, relationships: {} };
+  if (model.id !== undefined && model.id !== null) {
+    obj.id = String(model.id);
+  }
+
+  for (const name of Object.keys(model)) {
+    const relation = relations[name];
+    if (name === 'id') {
+      continue;
+    } else if (!relation) {
+      obj.attributes![name] = model[name];
+    } else if (!relation.many) {
+      const related = model[name];
+      obj.relationships![name] = {
+        data: related && related.id ? { type: relation.collection, id: String(related.id) } : null,
+      };
+    }
+  }
+  return obj;
+}
+
+// Resource runs the operations of a collection. I contains the attributes
+// needed to create a model.
+export class Resource<T, A, I> {
+  constructor(private client: Client, readonly collection: string, private relations: Relations) {}
+
+  // query starts a query of the collection.
+  query(): Query<T, A> {
+    return new Query<T, A>(this.client, this.collection);
+  }
+
+  async findOne(id: string): Promise<T> {
+    const doc = await this.client.call('find_one', { collection: this.collection, id });
+    return decodeOne<T>(doc);
+  }
+
+  async create(model: I): Promise<T> {
+    const doc = await this.client.call('create', encode(this.collection, this.relations, model));
+    return decodeOne<T>(doc);
+  }
+
+  // update updates all attributes of the model.
+  async update(model: T): Promise<T> {
+    const doc = await this.client.call('update', encode(this.collection, this.relations, model));
+    return decodeOne<T>(doc);
+  }
+
+  async delete(id: string): Promise<void> {
+    await this.client.call('delete', { collection: this.collection, id });
+  }
+}
+{{range .Models}}
+// {{.Name}}Attributes are the attributes of the {{.Collection}} collection.
+export interface {{.Name}}Attributes {
+{{- range .Attributes}}
+  {{if .ReadOnly}}readonly {{end}}{{key .Name}}: {{if eq .Name "id"}}string{{else}}{{tsType .Type}}{{end}};
+{{- end}}
+}
+
+// {{.Name}} is a model of the {{.Collection}} collection.
+export interface {{.Name}} extends {{.Name}}Attributes {
+{{- range .Relations}}{{if relatedModel .}}
+  {{key .Name}}?: {{if eq .Cardinality "many"}}{{.Model}}[]{{else}}{{.Model}} | null{{end}};
+{{- end}}{{end}}
+}
+
+// {{.Name}}Input contains the attributes to create a model of the
+// {{.Collection}} collection.
+export interface {{.Name}}Input {
+{{- range .Attributes}}{{if not .ReadOnly}}
+  {{key .Name}}{{if not .Required}}?{{end}}: {{tsType .Type}};
+{{- end}}{{end}}
+{{- range .Relations}}{{if and (relatedModel .) (ne .Cardinality "many")}}
+  {{key .Name}}?: { id: string } | null;
+{{- end}}{{end}}
+}
+
+const {{camel .Name}}Relations: Relations = {
+{{- range .Relations}}
+  {{key .Name}}: { collection: {{quote .Collection}}, many: {{if eq .Cardinality "many"}}true{{else}}false{{end}} },
+{{- end}}
+};
+{{end}}
+// Methods runs the methods of the app.
+export class Methods {
+  constructor(private client: Client) {}
+{{range .Methods}}
+  // {{camel .FuncName}} runs the {{.Name}} method.
+{{- if .Async}}
+  // The method runs as a task, the response contains the task id.
+{{- end}}
+{{- if .Arguments}}
+  {{camel .FuncName}}(args: {{tsArgsType .Arguments}}): Promise<Document> {
+    return this.client.call({{quote .Name}}, args);
+  }
+{{- else}}
+  {{camel .FuncName}}(data?: any): Promise<Document> {
+    return this.client.call({{quote .Name}}, data);
+  }
+{{- end}}
+{{end}}}
+
+// Client runs the methods of the app and keeps the session token.
+export class Client {
+  token: string | null = null;
+  private basicAuth: string | null = null;
+
+  readonly methods: Methods;
+{{- range .Models}}
+  readonly {{camel .Accessor}}: Resource<{{.Name}}, {{.Name}}Attributes, {{.Name}}Input>;
+{{- end}}
+
+  constructor(readonly transport: Transport) {
+    this.methods = new Methods(this);
+{{- range .Models}}
+    this.{{camel .Accessor}} = new Resource<{{.Name}}, {{.Name}}Attributes, {{.Name}}Input>(this, {{quote .Collection}}, {{camel .Name}}Relations);
+{{- end}}
+  }
+
+  // setBasicAuth authenticates http requests with a username and password.
+  setBasicAuth(user: string, password: string) {
+    this.basicAuth = 'Basic ' + btoa(unescape(encodeURIComponent(user + ':' + password)));
+  }
+
+  // call runs a method. Errors in the response are thrown as ClientError.
+  async call(method: string, data: any = {}): Promise<Document> {
+    const doc = await this.transport.call(method, data, this.token || this.basicAuth);
+    const errors = (doc.errors || []).filter(err => !!err);
+    if (errors.length > 0) {
+      throw new ClientError(errors);
+    }
+    return doc;
+  }
+
+  // login authenticates the session with a username and password.
+  async login(user: string, password: string): Promise<void> {
+    const doc = await this.call('users.authenticate', {
+      user,
+      adaptor: 'password',
+      authData: { password },
+    });
+    this.token = doc.data.id;
+  }
+
+  // resume authenticates the session with the token of an earlier session.
+  async resume(token: string): Promise<void> {
+    const doc = await this.call('users.resume_session', { token });
+    this.token = doc.data.id;
+  }
+
+  // logout removes the user from the session.
+  async logout(): Promise<void> {
+    try {
+      await this.call('users.unauthenticate');
+    } finally {
+      this.token = null;
+    }
+  }
+}
+`
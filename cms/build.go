@@ -24,7 +24,10 @@ func Build(backend db.Backend, app kit.App, integerIds bool) {
 		app.RegisterResource(resources.NewResource(&MenuIntId{}, MenuResource{}, true))
 		app.RegisterResource(resources.NewResource(&MenuItemIntId{}, MenuItemResource{}, true))
 		app.RegisterResource(resources.NewResource(&CommentIntId{}, CommentResource{}, true))
-		app.RegisterResource(resources.NewResource(&PageIntId{}, PageResource{}, true))
+
+		pages := resources.NewResource(&PageIntId{}, PageResource{}, true)
+		pages.SetVersioned(true)
+		app.RegisterResource(pages)
 	} else {
 		backend.RegisterModel(&TagStrId{})
 		backend.RegisterModel(&LocationStrId{})
@@ -40,6 +43,9 @@ func Build(backend db.Backend, app kit.App, integerIds bool) {
 		app.RegisterResource(resources.NewResource(&MenuStrId{}, MenuResource{}, true))
 		app.RegisterResource(resources.NewResource(&MenuItemStrId{}, MenuItemResource{}, true))
 		app.RegisterResource(resources.NewResource(&CommentStrId{}, CommentResource{}, true))
-		app.RegisterResource(resources.NewResource(&PageStrId{}, PageResource{}, true))
+
+		pages := resources.NewResource(&PageStrId{}, PageResource{}, true)
+		pages.SetVersioned(true)
+		app.RegisterResource(pages)
 	}
 }
@@ -38,18 +38,13 @@ func (PageResource) Methods(res kit.Resource) []kit.Method {
 	publish := &methods.Method{
 		Name:     "cms.page.publish",
 		Blocking: true,
+		Roles:    []string{"admin"},
+		Arguments: []*kit.Argument{
+			{Name: "id", Type: kit.ArgumentTypeString, Required: true},
+		},
 		Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
-			user := r.GetUser()
-			if user == nil || !user.HasRole("admin") {
-				return kit.NewErrorResponse("permission_denied")
-			}
-
 			id := utils.GetMapStringKey(r.GetData(), "id")
 
-			if id == "" {
-				return kit.NewErrorResponse("no_id_in_data", "Expected 'id' key in data.")
-			}
-
 			rawPage, err := res.Backend().FindOne("pages", id)
 			if err != nil {
 				return kit.NewErrorResponse("db_error", err)
@@ -22,6 +22,31 @@ func NewContext(data ...map[string]interface{}) *Context {
 	return c
 }
 
+// systemKey marks system contexts. The value has an unexported type, so it
+// can not be set from request parameters.
+const systemKey = "appkit.system"
+
+type systemFlag struct{}
+
+// NewSystemContext returns a context for requests made by the system itself,
+// which bypass row level security policies.
+func NewSystemContext() *Context {
+	c := NewContext()
+	c.SetSystem()
+	return c
+}
+
+// SetSystem marks the context as a system context.
+func (c *Context) SetSystem() {
+	c.Data[systemKey] = systemFlag{}
+}
+
+// IsSystem returns true for system contexts.
+func (c Context) IsSystem() bool {
+	_, ok := c.Data[systemKey].(systemFlag)
+	return ok
+}
+
 func (c Context) Has(key string) bool {
 	_, ok := c.Data[key]
 	return ok
@@ -1,5 +1,13 @@
 package appkit
 
+// Events triggered by resources after a model was written.
+// The event data is the model.
+const (
+	EventResourceCreated = "resource.created"
+	EventResourceUpdated = "resource.updated"
+	EventResourceDeleted = "resource.deleted"
+)
+
 type AppEventBus struct {
 	events map[string][]EventHandler
 }
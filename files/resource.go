@@ -23,101 +23,61 @@ import (
 	"github.com/app-kit/go-appkit/utils"
 )
 
-type rateLimiter struct {
+// thumbnailQueue limits the number of thumbnails that are generated at the
+// same time. Requests beyond that wait in the queue.
+// This limits concurrency, not the request rate, so it is not handled by
+// the rate limiter service. The rate of thumbnail requests per ip is
+// limited with the files.thumbnails rate limit policy.
+type thumbnailQueue struct {
 	sync.Mutex
 
-	running           int
-	maxPerIPPerMinute int
-	maxQueueSize      int
+	running      int
+	maxRunning   int
+	maxQueueSize int
 
-	maxRunning    int
-	ipLog         map[string][]*time.Time
 	queueChannels []chan bool
 }
 
-func newRateLimiter(maxRunning, maxPerIPPerMinute int, maxQueueSize int) *rateLimiter {
-	limiter := &rateLimiter{
-		maxRunning:        maxRunning,
-		maxPerIPPerMinute: maxPerIPPerMinute,
-		maxQueueSize:      maxQueueSize,
-
-		ipLog:         make(map[string][]*time.Time),
+func newThumbnailQueue(maxRunning int, maxQueueSize int) *thumbnailQueue {
+	return &thumbnailQueue{
+		maxRunning:    maxRunning,
+		maxQueueSize:  maxQueueSize,
 		queueChannels: make([]chan bool, 0),
 	}
-
-	return limiter
 }
 
-func (r *rateLimiter) PruneIpLog() {
-	now := time.Now()
-
-	for ip := range r.ipLog {
-		for index, t := range r.ipLog[ip] {
-			if now.Sub(*t).Seconds() > 60 {
-				if index == len(r.ipLog[ip])-1 {
-					r.ipLog[ip] = r.ipLog[ip][:]
-				} else {
-					r.ipLog[ip] = r.ipLog[ip][index+1:]
-				}
-				break
-			}
-		}
+func (q *thumbnailQueue) Start() (chan bool, apperror.Error) {
+	q.Lock()
+	defer q.Unlock()
 
-		if len(r.ipLog[ip]) == 0 {
-			delete(r.ipLog, ip)
-		}
-	}
-}
-
-func (r *rateLimiter) Start(ip string) (chan bool, apperror.Error) {
-	if r.running >= r.maxRunning {
-		if len(r.queueChannels) >= r.maxQueueSize {
+	if q.running >= q.maxRunning {
+		if len(q.queueChannels) >= q.maxQueueSize {
 			return nil, &apperror.Err{
 				Code:    "rate_limit_queue_threshold_exceeded",
 				Message: "The queue for the rate limiter has reached it's maximum size",
 			}
-		} else {
-			channel := make(chan bool)
-			r.Lock()
-			r.queueChannels = append(r.queueChannels, channel)
-			r.Unlock()
-			return channel, nil
-		}
-	}
-
-	// Check for ip limits.
-	r.PruneIpLog()
-	if log, ok := r.ipLog[ip]; ok {
-		if len(log) > r.maxPerIPPerMinute {
-			return nil, &apperror.Err{
-				Code:    "rate_limit_max_per_ip_per_minute_exceeced",
-				Message: "The maximum limit for requests per ip per minute was exceeded",
-			}
 		}
-	}
 
-	// maxRunning is not reached, so it is allowed to start.
-	r.Lock()
-	if _, ok := r.ipLog[ip]; !ok {
-		r.ipLog[ip] = make([]*time.Time, 0)
+		// The slot is handed over by Finish().
+		channel := make(chan bool)
+		q.queueChannels = append(q.queueChannels, channel)
+		return channel, nil
 	}
-	r.running += 1
-	now := time.Now()
-	r.ipLog[ip] = append(r.ipLog[ip], &now)
-	r.Unlock()
 
+	q.running += 1
 	return nil, nil
 }
 
-func (r *rateLimiter) Finish() {
+func (q *thumbnailQueue) Finish() {
 	var channel chan bool
-	r.Lock()
-	r.running -= 1
-	if len(r.queueChannels) > 0 {
-		channel = r.queueChannels[0]
-		r.queueChannels = r.queueChannels[1:]
+	q.Lock()
+	if len(q.queueChannels) > 0 {
+		channel = q.queueChannels[0]
+		q.queueChannels = q.queueChannels[1:]
+	} else {
+		q.running -= 1
 	}
-	r.Unlock()
+	q.Unlock()
 
 	if channel != nil {
 		channel <- true
@@ -125,7 +85,8 @@ func (r *rateLimiter) Finish() {
 }
 
 type FilesResource struct {
-	thumbnailRateLimiter *rateLimiter
+	thumbnailQueue     *thumbnailQueue
+	thumbnailRateLimit *kit.RateLimitPolicy
 }
 
 func getTmpPath(res kit.Resource) string {
@@ -342,7 +303,7 @@ func serveFile(w http.ResponseWriter, request *http.Request, mime string, size i
 	return nil
 }
 
-func (r *FilesResource) getImageReader(registry kit.Registry, tmpDir string, file kit.File, width, height int64, filters []string, ip string) (reader kit.ReadSeekerCloser, size int64, err apperror.Error) {
+func (r *FilesResource) getImageReader(registry kit.Registry, tmpDir string, file kit.File, width, height int64, filters []string, request kit.Request) (reader kit.ReadSeekerCloser, size int64, err apperror.Error) {
 	if width == 0 && height == 0 && len(filters) == 0 {
 		reader, err = file.Reader()
 		return
@@ -395,8 +356,14 @@ func (r *FilesResource) getImageReader(registry kit.Registry, tmpDir string, fil
 		"jpeg")
 
 	if ok, _ := file.GetBackend().HasFileById("thumbs", thumbId); !ok {
+		if limiter := registry.RateLimiter(); limiter != nil {
+			if err = limiter.Check(r.thumbnailRateLimit, request); err != nil {
+				return
+			}
+		}
+
 		var channel chan bool
-		channel, err = r.thumbnailRateLimiter.Start(ip)
+		channel, err = r.thumbnailQueue.Start()
 		if err != nil {
 			return
 		}
@@ -488,7 +455,7 @@ func (r *FilesResource) getImageReader(registry kit.Registry, tmpDir string, fil
 
 		jpeg.Encode(writer, thumb, &jpeg.Options{Quality: 90})
 
-		r.thumbnailRateLimiter.Finish()
+		r.thumbnailQueue.Finish()
 	}
 
 	backend := file.GetBackend()
@@ -505,7 +472,14 @@ func (hooks FilesResource) HttpRoutes(res kit.Resource) []kit.HttpRoute {
 	maxRunning := res.Registry().Config().UInt("files.thumbGenerator.maxRunning", 10)
 	maxPerIPPerMinute := res.Registry().Config().UInt("files.thumbGenerator.maxPerIPPerMinute", 100)
 	maxQueueSize := res.Registry().Config().UInt("files.thumbGenerator.maxQueueSize", 100)
-	hooks.thumbnailRateLimiter = newRateLimiter(maxRunning, maxPerIPPerMinute, maxQueueSize)
+	hooks.thumbnailQueue = newThumbnailQueue(maxRunning, maxQueueSize)
+	hooks.thumbnailRateLimit = &kit.RateLimitPolicy{
+		Name:      "files.thumbnails",
+		Algorithm: kit.RateLimitSlidingWindow,
+		KeyBy:     kit.RateLimitByIP,
+		Limit:     maxPerIPPerMinute,
+		Interval:  time.Minute,
+	}
 
 	routes := make([]kit.HttpRoute, 0)
 
@@ -645,12 +619,7 @@ func (hooks FilesResource) HttpRoutes(res kit.Resource) []kit.HttpRoute {
 			thumbDir = tmpPath + string(os.PathSeparator) + "thumbnails"
 		}
 
-		ip := strings.Split(httpRequest.RemoteAddr, ":")[0]
-		if ip == "" {
-			ip = httpRequest.Header.Get("X-Forwarded-For")
-		}
-
-		reader, size, err := hooks.getImageReader(registry, thumbDir, file, width, height, filters, ip)
+		reader, size, err := hooks.getImageReader(registry, thumbDir, file, width, height, filters, r)
 		if err != nil {
 			return kit.NewErrorResponse(err), false
 		}
@@ -24,6 +24,7 @@ import (
 
 	kit "github.com/app-kit/go-appkit"
 	"github.com/app-kit/go-appkit/caches"
+	"github.com/app-kit/go-appkit/resources"
 	"github.com/app-kit/go-appkit/utils"
 )
 
@@ -379,9 +380,12 @@ func HttpHandler(w http.ResponseWriter, r *http.Request, params httprouter.Param
 	methods := config.UString("accessControl.allowedMethods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
 	header.Set("Access-Control-Allow-Methods", methods)
 
-	allowedHeaders := config.UString("accessControl.allowedHeaders", "Authentication, Content-Type, X-Requested-With, Accept, Accept-Language, Content-Language")
+	allowedHeaders := config.UString("accessControl.allowedHeaders", "Authentication, Content-Type, X-Requested-With, Accept, Accept-Language, Content-Language, If-Match")
 	header.Set("Access-Control-Allow-Headers", allowedHeaders)
 
+	// Allow clients to read the ETag for conditional updates.
+	header.Set("Access-Control-Expose-Headers", "ETag")
+
 	// If it is an options request, just respond with 200.
 	if r.Method == "OPTIONS" {
 		w.WriteHeader(200)
@@ -395,6 +399,10 @@ func HttpHandler(w http.ResponseWriter, r *http.Request, params httprouter.Param
 	request.SetHttpRequest(r)
 	request.SetHttpResponseWriter(w)
 
+	// The context of the http request is cancelled when the client closes
+	// the connection.
+	request.SetGoContext(r.Context())
+
 	for _, param := range params {
 		request.Context.Set(param.Key, param.Value)
 	}
@@ -534,10 +542,42 @@ func AuthenticationMiddleware(registry kit.Registry, r kit.Request) (kit.Respons
 	return nil, false
 }
 
+// RateLimitMiddleware applies the route policies of the rate limiter.
+func RateLimitMiddleware(registry kit.Registry, r kit.Request) (kit.Response, bool) {
+	limiter := registry.RateLimiter()
+	if limiter == nil {
+		return nil, false
+	}
+
+	for _, policy := range limiter.RoutePolicies(r) {
+		if err := limiter.Check(policy, r); err != nil {
+			return kit.NewErrorResponse(err), false
+		}
+	}
+
+	return nil, false
+}
+
 /**
  * After middlewares.
  */
 
+// ETagMiddleware sets the ETag header for responses with a single model that
+// is protected against concurrent updates.
+func ETagMiddleware(registry kit.Registry, r kit.Request, response kit.Response) (kit.Response, bool) {
+	if response.GetError() != nil || r.GetHttpResponseWriter() == nil {
+		return nil, false
+	}
+
+	if model, ok := response.GetData().(kit.Model); ok {
+		if etag := resources.ETag(model); etag != "" {
+			r.GetHttpResponseWriter().Header().Set("ETag", etag)
+		}
+	}
+
+	return nil, false
+}
+
 func ServerErrorMiddleware(registry kit.Registry, r kit.Request, response kit.Response) (kit.Response, bool) {
 	err := response.GetError()
 	if err == nil {
@@ -556,6 +596,13 @@ func ServerErrorMiddleware(registry kit.Registry, r kit.Request, response kit.Re
 
 	response.SetHttpStatus(status)
 
+	// Tell clients when to retry requests that exceeded a rate limit.
+	if apperr, ok := err.(*apperror.Err); ok && apperr.Code == "rate_limit_exceeded" {
+		if data, ok := apperr.Data.(map[string]interface{}); ok {
+			r.GetHttpResponseWriter().Header().Set("Retry-After", fmt.Sprintf("%v", data["retryAfter"]))
+		}
+	}
+
 	if response.GetRawData() != nil || response.GetRawDataReader() != nil {
 		return nil, false
 	}
@@ -41,8 +41,10 @@ func New(registry kit.Registry) *Frontend {
 	f.RegisterBeforeMiddleware(frontends.RequestTraceMiddleware)
 	f.RegisterBeforeMiddleware(UnserializeRequestMiddleware)
 	f.RegisterBeforeMiddleware(AuthenticationMiddleware)
+	f.RegisterBeforeMiddleware(RateLimitMiddleware)
 
 	f.RegisterAfterMiddleware(ServerErrorMiddleware)
+	f.RegisterAfterMiddleware(ETagMiddleware)
 	f.RegisterAfterMiddleware(frontends.SerializeResponseMiddleware)
 	f.RegisterAfterMiddleware(MarshalResponseMiddleware)
 	f.RegisterAfterMiddleware(frontends.RequestTraceAfterMiddleware)
@@ -0,0 +1,320 @@
+package jsonapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/theduke/go-apperror"
+
+	kit "github.com/app-kit/go-appkit"
+	"github.com/app-kit/go-appkit/resources"
+	"github.com/app-kit/go-appkit/utils"
+)
+
+// exportColumns returns the columns requested with fields[collection], or
+// the default columns of the resource.
+func exportColumns(res kit.Resource, request kit.Request) []string {
+	fields := request.GetContext().String("fields[" + res.Collection() + "]")
+	if fields == "" {
+		return resources.CsvColumns(res)
+	}
+
+	columns := make([]string, 0)
+	for _, field := range strings.Split(fields, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			columns = append(columns, field)
+		}
+	}
+	return columns
+}
+
+// exportWriter writes the rows of an export, like csv.Writer.
+type exportWriter interface {
+	Write(record []string) error
+	Flush()
+	Error() error
+}
+
+// HandleExportCsv streams the models matching the find parameters as csv.
+func HandleExportCsv(registry kit.Registry, request kit.Request) (kit.Response, bool) {
+	return handleExport(registry, request, "csv")
+}
+
+// HandleExportXlsx streams the models matching the find parameters as xlsx.
+func HandleExportXlsx(registry kit.Registry, request kit.Request) (kit.Response, bool) {
+	return handleExport(registry, request, "xlsx")
+}
+
+// handleExport streams the models matching the find parameters as csv or
+// xlsx.
+// The models are queried in batches with ApiFind, so the permissions, row
+// policies and field permissions of the resource apply.
+// Without a limit parameter, all models are exported.
+func handleExport(registry kit.Registry, request kit.Request, format string) (kit.Response, bool) {
+	collection := request.GetContext().MustString("collection")
+
+	res := registry.Resource(collection)
+	if res == nil || !res.IsPublic() || res.Csv() == nil || !res.Csv().Export {
+		return kit.NewErrorResponse("unknown_resource", fmt.Sprintf("The resource '%v' does not exist", collection)), false
+	}
+
+	context := request.GetContext()
+
+	// Exports page with offsets.
+	for _, key := range []string{"page[after]", "page[before]", "page[size]", "count"} {
+		delete(context.Data, key)
+	}
+
+	query, err := FindQuery(res, request)
+	if err != nil {
+		return kit.NewErrorResponse(err), false
+	}
+
+	// An explicit limit restricts the number of exported rows.
+	maxRows := 0
+	if context.Has("limit") || context.Has("per_page") {
+		maxRows = query.GetLimit()
+	}
+
+	batchSize := registry.Config().UInt("resources.csv.batchSize", 500)
+	offset := query.GetOffset()
+	columns := exportColumns(res, request)
+
+	var writer exportWriter
+	w := request.GetHttpResponseWriter()
+	rows := 0
+
+	for {
+		limit := batchSize
+		if maxRows > 0 && maxRows-rows < limit {
+			limit = maxRows - rows
+		}
+		query.Limit(limit).Offset(offset)
+
+		response := res.ApiFind(query, request)
+		if err := response.GetError(); err != nil {
+			if writer == nil {
+				return response, false
+			}
+			registry.Logger().Errorf("Error while exporting %v: %v", collection, err)
+			break
+		}
+
+		if writer == nil {
+			if format == "xlsx" {
+				w.Header().Set("Content-Type", resources.XlsxContentType)
+			} else {
+				w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			}
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%v.%v\"", collection, format))
+			w.WriteHeader(http.StatusOK)
+
+			if format == "xlsx" {
+				writer = resources.NewXlsxWriter(w)
+			} else {
+				// The byte order mark makes spreadsheet applications read
+				// the file as utf-8.
+				w.Write([]byte("\xef\xbb\xbf"))
+				writer = csv.NewWriter(w)
+			}
+			writer.Write(columns)
+		}
+
+		var computed map[string]map[string]interface{}
+		if meta := response.GetMeta(); meta != nil {
+			if values, ok := meta["computed"].(map[string]map[string]map[string]interface{}); ok {
+				computed = values[res.Collection()]
+			}
+		}
+
+		models, _ := response.GetData().([]kit.Model)
+		for _, model := range models {
+			record, err := resources.CsvRecord(res, model, columns, computed[model.GetStrId()])
+			if err != nil {
+				registry.Logger().Errorf("Error while exporting %v: %v", collection, err)
+				continue
+			}
+			writer.Write(record)
+		}
+
+		writer.Flush()
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+
+		// Models removed by AllowFind still count for the offset.
+		rows += limit
+		offset += limit
+		if maxRows > 0 && rows >= maxRows || !hasMore(response, offset, len(models), limit) {
+			break
+		}
+	}
+
+	if closer, ok := writer.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			registry.Logger().Errorf("Error while exporting %v: %v", collection, err)
+		}
+	} else if err := writer.Error(); err != nil {
+		registry.Logger().Errorf("Error while exporting %v: %v", collection, err)
+	}
+
+	return nil, true
+}
+
+// hasMore returns true if the count in the meta of a find response is
+// larger than the offset, or, without a count, if the batch was full.
+func hasMore(response kit.Response, offset, n, limit int) bool {
+	if count, ok := response.GetMeta()["count"].(int); ok {
+		return count > offset
+	}
+	return n == limit
+}
+
+// HandleImport stores the uploaded csv or xlsx file with the file service
+// and queues a task that imports it. Responds with the task id.
+// The file is read from the file field of multipart uploads, or from the
+// request body.
+func HandleImport(registry kit.Registry, request kit.Request) (kit.Response, bool) {
+	collection := request.GetContext().MustString("collection")
+
+	res := registry.Resource(collection)
+	if res == nil || !res.IsPublic() || res.Csv() == nil || !res.Csv().Import {
+		return kit.NewErrorResponse("unknown_resource", fmt.Sprintf("The resource '%v' does not exist", collection)), false
+	}
+
+	user := request.GetUser()
+	if user == nil {
+		return kit.NewErrorResponse("permission_denied", "Imports require a user", true), false
+	}
+
+	service := registry.TaskService()
+	if service == nil {
+		return kit.NewErrorResponse("tasks_disabled", "Imports require the task service, which is not enabled", true), false
+	}
+	files := registry.FileService()
+	if files == nil {
+		return kit.NewErrorResponse("files_disabled", "Imports require the file service, which is not enabled", true), false
+	}
+
+	filePath, format, err := saveUpload(registry, request)
+	if err != nil {
+		return kit.NewErrorResponse(err), false
+	}
+	dir := path.Dir(filePath)
+
+	// Check the file before queueing the task.
+	if err := checkImportFile(filePath, format); err != nil {
+		os.RemoveAll(dir)
+		return kit.NewErrorResponse(err), false
+	}
+
+	file := files.New()
+	file.SetBucket(registry.Config().UString("resources.csv.importBucket", "imports"))
+	file.SetTmpPath(filePath)
+	if err := files.BuildFile(file, user, true, true); err != nil {
+		os.RemoveAll(dir)
+		return kit.NewErrorResponse(err), false
+	}
+
+	task := service.NewTask()
+	task.SetName(resources.ImportTaskName)
+	task.SetUserId(user.GetId())
+	task.SetData(map[string]interface{}{
+		"collection": res.Collection(),
+		"fileId":     file.GetStrId(),
+		"format":     format,
+	})
+	if err := service.Queue(task); err != nil {
+		files.Delete(file, user)
+		return kit.NewErrorResponse(err), false
+	}
+
+	return &kit.AppResponse{
+		HttpStatus: http.StatusAccepted,
+		Data: map[string]interface{}{
+			"taskId": task.GetStrId(),
+		},
+	}, false
+}
+
+// saveUpload streams the uploaded file of an import request to a new
+// directory in the tmp dir. The format is detected from the content, since
+// xlsx files are zip archives.
+// Returns the path of the file and the format, csv or xlsx.
+func saveUpload(registry kit.Registry, request kit.Request) (string, string, apperror.Error) {
+	maxSize := int64(registry.Config().UInt("resources.csv.maxImportSize", 10*1024*1024))
+
+	r := request.GetHttpRequest()
+	r.Body = http.MaxBytesReader(request.GetHttpResponseWriter(), r.Body, maxSize)
+
+	var upload io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return "", "", apperror.Wrap(err, "invalid_upload", "The upload does not contain a file field", true)
+		}
+		defer file.Close()
+		upload = file
+	}
+
+	buffered := bufio.NewReader(upload)
+	format := "csv"
+	if magic, _ := buffered.Peek(4); bytes.Equal(magic, []byte("PK\x03\x04")) {
+		format = "xlsx"
+	}
+
+	id := utils.UUIdv4()
+	dir := path.Join(registry.Config().TmpDir(), "imports", id)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return "", "", apperror.Wrap(err, "create_dir_failed", "")
+	}
+
+	// The file service uses the file name as the id of the file in the
+	// bucket, so it has to be unique.
+	filePath := path.Join(dir, id+"."+format)
+	file, err := os.Create(filePath)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", "", apperror.Wrap(err, "file_create_failed", "")
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, buffered); err != nil {
+		os.RemoveAll(dir)
+		return "", "", apperror.Wrap(err, "upload_read_error", "The upload could not be read", true)
+	}
+
+	return filePath, format, nil
+}
+
+// checkImportFile checks that an uploaded file can be read.
+func checkImportFile(filePath, format string) apperror.Error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return apperror.Wrap(err, "file_read_error", "")
+	}
+	defer file.Close()
+
+	if format == "xlsx" {
+		stat, err := file.Stat()
+		if err != nil {
+			return apperror.Wrap(err, "file_read_error", "")
+		}
+		_, err2 := resources.ReadXlsx(file, stat.Size())
+		return err2
+	}
+
+	if _, err := resources.NewCsvReader(file).Read(); err == io.EOF {
+		return apperror.New("invalid_csv", "The csv data has no header", true)
+	} else if err != nil {
+		return apperror.Wrap(err, "invalid_csv", "The csv data is invalid", true)
+	}
+	return nil
+}
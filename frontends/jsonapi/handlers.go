@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/theduke/go-apperror"
@@ -25,40 +26,112 @@ func HandleWrap(collection string, handler kit.RequestHandler) kit.RequestHandle
 	}
 }
 
+// checkQuery enforces the query policy of the resource on the json query
+// combined with the limit, joins, filters and order parameters.
+// Returns the default limit of the policy if neither the query nor the
+// parameters contain a limit.
+func checkQuery(res kit.Resource, rawQuery map[string]interface{}, limit int, context *kit.Context) (int, apperror.Error) {
+	checked := map[string]interface{}{}
+	for _, key := range []string{"limit", "filters", "joins", "order"} {
+		if val, ok := rawQuery[key]; ok {
+			checked[key] = val
+		}
+	}
+
+	if limit > 0 {
+		checked["limit"] = limit
+	}
+
+	if order, ok := context.Get("order"); ok {
+		checked["order"] = order
+	}
+
+	if context.Has("joins") {
+		joins := make([]interface{}, 0)
+		if rawJoins, ok := checked["joins"].([]interface{}); ok {
+			joins = append(joins, rawJoins...)
+		}
+		for _, join := range strings.Split(context.String("joins"), ",") {
+			joins = append(joins, join)
+		}
+		checked["joins"] = joins
+	}
+
+	if context.Has("filters") {
+		filters := map[string]interface{}{}
+		if rawFilters, ok := checked["filters"].(map[string]interface{}); ok {
+			for key, val := range rawFilters {
+				filters[key] = val
+			}
+		}
+		for _, filter := range strings.Split(context.String("filters"), ",") {
+			if parts := strings.Split(filter, ":"); len(parts) == 2 {
+				filters[parts[0]] = parts[1]
+			}
+		}
+		checked["filters"] = filters
+	}
+
+	_, hasLimit := checked["limit"]
+	if err := res.CheckQuery(checked); err != nil {
+		return 0, err
+	}
+
+	if !hasLimit {
+		if defaultLimit, ok := checked["limit"].(int); ok {
+			return defaultLimit, nil
+		}
+	}
+	return 0, nil
+}
+
 func Find(res kit.Resource, request kit.Request) (kit.Response, apperror.Error) {
+	query, err := FindQuery(res, request)
+	if err != nil {
+		return nil, err
+	}
+	return res.ApiFind(query, request), nil
+}
+
+// FindQuery builds the query of a find request from the query, paging,
+// join and filter parameters.
+func FindQuery(res kit.Resource, request kit.Request) (*db.Query, apperror.Error) {
 	collection := res.Collection()
 
 	info := res.Backend().ModelInfo(collection)
 
 	var query *db.Query
 
-	jsonQuery := request.GetContext().String("query")
+	context := request.GetContext()
+
+	// With cursor pagination, the resource sorts the query by the
+	// sort parameter or the order of the query.
+	cursorPagination := context.Has("page[after]") || context.Has("page[before]") || context.Has("page[size]")
+	if cursorPagination && context.Has("sort") {
+		context.Set("order", context.String("sort"))
+	}
+
+	var rawQuery map[string]interface{}
+
+	jsonQuery := context.String("query")
 	if jsonQuery != "" {
-		var rawQuery map[string]interface{}
 		if err := json.Unmarshal([]byte(jsonQuery), &rawQuery); err != nil {
 			return nil, apperror.Wrap(err, "invalid_query_json")
 		}
 
 		rawQuery["collection"] = collection
 
-		// A custom query was supplied.
-		// Try to parse the query.
-		var err apperror.Error
-		query, err = db.ParseQuery(res.Backend(), rawQuery)
-		if err != nil {
-			return nil, apperror.Wrap(err, "invalid_query", "", false)
+		if order, ok := rawQuery["order"]; ok && cursorPagination {
+			if !context.Has("order") {
+				context.Set("order", order)
+			}
+			delete(rawQuery, "order")
 		}
 	}
 
-	if query == nil {
-		query = res.Backend().Q(collection)
-	}
-
 	// Check paging parameters.
 	var limit, offset int
 
-	context := request.GetContext()
-
 	if context.Has("limit") {
 		val, err := context.Int("limit")
 		if err != nil {
@@ -113,6 +186,29 @@ func Find(res kit.Resource, request kit.Request) (kit.Response, apperror.Error)
 		limit = perPage
 	}
 
+	defaultLimit, err := checkQuery(res, rawQuery, limit, context)
+	if err != nil {
+		return nil, err
+	}
+
+	if rawQuery != nil {
+		if defaultLimit > 0 {
+			rawQuery["limit"] = defaultLimit
+		}
+
+		// A custom query was supplied.
+		// Try to parse the query.
+		query, err = db.ParseQuery(res.Backend(), rawQuery)
+		if err != nil {
+			return nil, apperror.Wrap(err, "invalid_query", "", false)
+		}
+	} else {
+		query = res.Backend().Q(collection)
+		if defaultLimit > 0 {
+			limit = defaultLimit
+		}
+	}
+
 	if page > 1 {
 		offset = (page - 1) * limit
 	}
@@ -196,7 +292,7 @@ func Find(res kit.Resource, request kit.Request) (kit.Response, apperror.Error)
 		}
 	}
 
-	return res.ApiFind(query, request), nil
+	return query, nil
 }
 
 func HandleFind(registry kit.Registry, request kit.Request) (kit.Response, bool) {
@@ -222,13 +318,132 @@ func HandleFind(registry kit.Registry, request kit.Request) (kit.Response, bool)
 
 	meta := response.GetMeta()
 	if meta != nil && err2 == nil {
-		count, ok := meta["count"]
-		if ok {
-			meta["total_pages"] = math.Ceil(float64(count.(int)) / float64(perPage))
+		if count, ok := meta["count"].(int); ok && perPage > 0 {
+			meta["total_pages"] = math.Ceil(float64(count) / float64(perPage))
 		}
 	}
 
-	return response, false
+	if meta != nil {
+		if page, ok := meta["page"].(map[string]interface{}); ok {
+			meta["links"] = cursorLinks(request, page)
+		} else if pages, ok := meta["pages"].(int); ok {
+			// Hooks may return their own meta, so the links are skipped
+			// if the page values are missing.
+			page, pageOk := meta["page"].(int)
+			perPage, perPageOk := meta["per_page"].(int)
+			if pageOk && perPageOk {
+				meta["links"] = pageLinks(request, page, perPage, pages)
+			}
+		}
+	}
+
+	return addFieldsets(request, response), false
+}
+
+// addFieldsets adds the sparse fieldsets of the fields[type] parameters to
+// the response meta, so that the serializer removes all other fields.
+func addFieldsets(request kit.Request, response kit.Response) kit.Response {
+	httpRequest := request.GetHttpRequest()
+	if httpRequest == nil || response.GetError() != nil {
+		return response
+	}
+
+	fieldsets := make(map[string][]string)
+	for key, values := range httpRequest.URL.Query() {
+		if !strings.HasPrefix(key, "fields[") || !strings.HasSuffix(key, "]") || len(values) == 0 {
+			continue
+		}
+
+		collection := strings.Replace(key[len("fields["):len(key)-1], "-", "_", -1)
+		fieldsets[collection] = strings.Split(values[0], ",")
+	}
+	if len(fieldsets) == 0 {
+		return response
+	}
+
+	meta := response.GetMeta()
+	if meta == nil {
+		meta = make(map[string]interface{})
+	}
+	meta["fields"] = fieldsets
+	response.SetMeta(meta)
+
+	return response
+}
+
+// pageLinks builds the first, last, prev and next links of a page with
+// offset pagination.
+func pageLinks(request kit.Request, page, perPage, pages int) map[string]interface{} {
+	links := map[string]interface{}{
+		"first": nil,
+		"last":  nil,
+		"prev":  nil,
+		"next":  nil,
+	}
+
+	httpRequest := request.GetHttpRequest()
+	if httpRequest == nil {
+		return links
+	}
+
+	link := func(page int) string {
+		u := *httpRequest.URL
+		values := u.Query()
+		values.Del("limit")
+		values.Del("offset")
+		values.Set("page", strconv.Itoa(page))
+		values.Set("per_page", strconv.Itoa(perPage))
+		u.RawQuery = values.Encode()
+
+		return u.String()
+	}
+
+	links["first"] = link(1)
+	if pages > 0 {
+		links["last"] = link(pages)
+	}
+	if page > 1 {
+		links["prev"] = link(page - 1)
+	}
+	if page < pages {
+		links["next"] = link(page + 1)
+	}
+
+	return links
+}
+
+// cursorLinks builds the next and prev links of a page with cursor
+// pagination.
+func cursorLinks(request kit.Request, page map[string]interface{}) map[string]interface{} {
+	links := map[string]interface{}{
+		"next": nil,
+		"prev": nil,
+	}
+
+	httpRequest := request.GetHttpRequest()
+	if httpRequest == nil {
+		return links
+	}
+
+	link := func(param string, cursor interface{}) interface{} {
+		if cursor == nil {
+			return nil
+		}
+
+		u := *httpRequest.URL
+		values := u.Query()
+		values.Del("page[after]")
+		values.Del("page[before]")
+		values.Set(param, fmt.Sprintf("%v", cursor))
+		u.RawQuery = values.Encode()
+
+		return u.String()
+	}
+
+	links["next"] = link("page[after]", page["next"])
+	links["prev"] = link("page[before]", page["prev"])
+
+	return links
 }
 
 func HandleFindOne(registry kit.Registry, request kit.Request) (kit.Response, bool) {
@@ -241,7 +456,16 @@ func HandleFindOne(registry kit.Registry, request kit.Request) (kit.Response, bo
 		return resp, false
 	}
 
-	return res.ApiFindOne(id, request), false
+	if res.Csv() != nil && res.Csv().Export {
+		switch id {
+		case "export.csv":
+			return HandleExportCsv(registry, request)
+		case "export.xlsx":
+			return HandleExportXlsx(registry, request)
+		}
+	}
+
+	return addFieldsets(request, res.ApiFindOne(id, request)), false
 }
 
 func Create(registry kit.Registry, request kit.Request) (kit.Response, apperror.Error) {
@@ -256,12 +480,20 @@ func Create(registry kit.Registry, request kit.Request) (kit.Response, apperror.
 	}
 
 	fmt.Printf("data: %v |  %+v\n\n", nil, request.GetData())
-	model, ok := request.GetData().(kit.Model)
-	if !ok {
-		return nil, apperror.New("invalid_data_no_model", "No model data in request.")
-	}
 
-	response := res.ApiCreate(model, request)
+	var response kit.Response
+
+	// Create all models if the data contains an array.
+	if models := request.GetTransferData().GetModels(); len(models) > 1 {
+		response = res.ApiBulkCreate(models, request)
+	} else {
+		model, ok := request.GetData().(kit.Model)
+		if !ok {
+			return nil, apperror.New("invalid_data_no_model", "No model data in request.")
+		}
+
+		response = res.ApiCreate(model, request)
+	}
 	if response.GetError() == nil {
 		response.SetHttpStatus(201)
 	}
@@ -308,6 +540,57 @@ func HandleUpdate(registry kit.Registry, request kit.Request) (kit.Response, boo
 	return response, false
 }
 
+// BulkUpdate updates all models in the data array.
+func BulkUpdate(registry kit.Registry, request kit.Request) (kit.Response, apperror.Error) {
+	collection := request.GetContext().MustString("collection")
+
+	res := registry.Resource(collection)
+	if res == nil || !res.IsPublic() {
+		return nil, &apperror.Err{
+			Code:    "unknown_resource",
+			Message: fmt.Sprintf("The resource '%v' does not exist", collection),
+		}
+	}
+
+	models := request.GetTransferData().GetModels()
+	if len(models) == 0 {
+		return nil, apperror.New("invalid_data_no_model", "No model data in request.")
+	}
+
+	return res.ApiBulkUpdate(models, request), nil
+}
+
+func HandleBulkUpdate(registry kit.Registry, request kit.Request) (kit.Response, bool) {
+	response, err := BulkUpdate(registry, request)
+	if err != nil {
+		return kit.NewErrorResponse(err), false
+	}
+
+	return response, false
+}
+
+// HandleBulkDelete deletes all models in the data array of resource
+// identifiers.
+func HandleBulkDelete(registry kit.Registry, request kit.Request) (kit.Response, bool) {
+	collection := request.GetContext().MustString("collection")
+
+	res := registry.Resource(collection)
+	if res == nil || !res.IsPublic() {
+		resp := kit.NewErrorResponse("unknown_resource", fmt.Sprintf("The resource '%v' does not exist", collection))
+		return resp, false
+	}
+
+	ids := make([]string, 0)
+	for _, model := range request.GetTransferData().GetModels() {
+		ids = append(ids, model.GetStrId())
+	}
+	if len(ids) == 0 {
+		return kit.NewErrorResponse("invalid_data_no_model", "No model data in request."), false
+	}
+
+	return res.ApiBulkDelete(ids, request), false
+}
+
 func HandleDelete(registry kit.Registry, request kit.Request) (kit.Response, bool) {
 	collection := request.GetContext().MustString("collection")
 	id := request.GetContext().MustString("id")
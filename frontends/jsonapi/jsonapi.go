@@ -84,8 +84,8 @@ func (f *Frontend) Init() apperror.Error {
 	}
 
 	resources := f.registry.Resources()
-	for name := range resources {
-		name = strings.Replace(name, "_", "-", -1)
+	for collection, res := range resources {
+		name := strings.Replace(collection, "_", "-", -1)
 
 		httpFrontend.RegisterHttpHandler("OPTIONS", "/"+apiPrefix+"/"+name, HandleOptions)
 		httpFrontend.RegisterHttpHandler("OPTIONS", "/"+apiPrefix+"/"+name+"/:id", HandleOptions)
@@ -104,6 +104,19 @@ func (f *Frontend) Init() apperror.Error {
 
 		// Delete.
 		httpFrontend.RegisterHttpHandler("DELETE", "/"+apiPrefix+"/"+name+"/:id", HandleWrap(name, HandleDelete))
+
+		// Bulk update and delete.
+		httpFrontend.RegisterHttpHandler("PATCH", "/"+apiPrefix+"/"+name, HandleWrap(name, HandleBulkUpdate))
+		httpFrontend.RegisterHttpHandler("DELETE", "/"+apiPrefix+"/"+name, HandleWrap(name, HandleBulkDelete))
+
+		// The csv and xlsx exports at /<name>/export.csv and
+		// /<name>/export.xlsx are served by the FindOne route, since the
+		// router does not allow static routes next to the :id parameter.
+
+		// Csv and xlsx import.
+		if res.Csv() != nil && res.Csv().Import {
+			httpFrontend.RegisterHttpHandler("POST", "/"+apiPrefix+"/"+name+"/import", HandleWrap(name, HandleImport))
+		}
 	}
 
 	return nil
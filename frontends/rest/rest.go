@@ -116,6 +116,27 @@ func (f *Frontend) Init() apperror.Error {
 		apphttp.HttpHandler(w, r, params, f.registry, methodHandler)
 	})
 
+	// Serve the schema method with GET.
+	httpFrontend.Router().GET("/api/schema", func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		apphttp.HttpHandler(w, r, params, f.registry, func(registry kit.Registry, r kit.Request) (kit.Response, bool) {
+			r.GetContext().Set("name", "schema")
+			return methodHandler(registry, r)
+		})
+	})
+
+	// Handle batch requests.
+	httpFrontend.Router().OPTIONS("/api/batch", func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		apphttp.HttpHandler(w, r, params, f.registry, func(registry kit.Registry, r kit.Request) (kit.Response, bool) {
+			return &kit.AppResponse{}, false
+		})
+	})
+	httpFrontend.Router().POST("/api/batch", func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		apphttp.HttpHandler(w, r, params, f.registry, func(registry kit.Registry, r kit.Request) (kit.Response, bool) {
+			r.GetContext().Set("name", "batch")
+			return methodHandler(registry, r)
+		})
+	})
+
 	return nil
 }
 
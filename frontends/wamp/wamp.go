@@ -1,8 +1,11 @@
 package wamp
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/julienschmidt/httprouter"
@@ -10,6 +13,7 @@ import (
 	"gopkg.in/jcelliott/turnpike.v2"
 
 	kit "github.com/app-kit/go-appkit"
+	"github.com/app-kit/go-appkit/app/methods"
 	"github.com/app-kit/go-appkit/frontends"
 )
 
@@ -48,11 +52,20 @@ type Frontend struct {
 	server *turnpike.WebsocketServer
 	client *turnpike.Client
 
+	// sessionsLock guards sessions, sessionContexts and sessionCancels, which
+	// are accessed by the turnpike callbacks and all running calls.
+	sessionsLock sync.Mutex
+
 	sessions map[uint]kit.Session
+
+	// sessionContexts holds a context for each WAMP session which is
+	// cancelled when the session is closed.
+	sessionContexts map[uint]context.Context
+	sessionCancels  map[uint]context.CancelFunc
 }
 
-// Ensure that Frontend implements appkit.Frontend.
-var _ kit.Frontend = (*Frontend)(nil)
+// Ensure that Frontend implements appkit.PublishingFrontend.
+var _ kit.PublishingFrontend = (*Frontend)(nil)
 
 func New(registry kit.Registry) *Frontend {
 	conf := registry.Config()
@@ -63,6 +76,8 @@ func New(registry kit.Registry) *Frontend {
 		beforeMiddlewares: make([]kit.RequestHandler, 0),
 		afterMiddlewares:  make([]kit.AfterRequestMiddleware, 0),
 		sessions:          make(map[uint]kit.Session),
+		sessionContexts:   make(map[uint]context.Context),
+		sessionCancels:    make(map[uint]context.CancelFunc),
 	}
 
 	f.RegisterBeforeMiddleware(frontends.RequestTraceMiddleware)
@@ -143,7 +158,12 @@ func (f *Frontend) Init() apperror.Error {
 	if f.debug {
 		turnpike.Debug()
 	}
-	server := turnpike.NewBasicWebsocketServer(realm)
+	server, err := turnpike.NewWebsocketServer(map[string]turnpike.Realm{
+		realm: {Authorizer: &taskAuthorizer{frontend: f}},
+	})
+	if err != nil {
+		return apperror.Wrap(err, "turnpike_server_error")
+	}
 
 	// Install session open/close callbacks.
 
@@ -155,11 +175,27 @@ func (f *Frontend) Init() apperror.Error {
 			// Todo: figure out how to handle an error.
 		}
 
+		ctx, cancel := context.WithCancel(context.Background())
+
+		f.sessionsLock.Lock()
 		f.sessions[id] = session
+		f.sessionContexts[id] = ctx
+		f.sessionCancels[id] = cancel
+		f.sessionsLock.Unlock()
 	})
 
 	server.AddSessionCloseCallback(func(id uint, realm string) {
+		f.sessionsLock.Lock()
+		cancel := f.sessionCancels[id]
 		delete(f.sessions, id)
+		delete(f.sessionContexts, id)
+		delete(f.sessionCancels, id)
+		f.sessionsLock.Unlock()
+
+		// Cancel all methods still running for the session.
+		if cancel != nil {
+			cancel()
+		}
 	})
 
 	// Register websocket handler.
@@ -180,6 +216,61 @@ func (f *Frontend) Init() apperror.Error {
 	return nil
 }
 
+// session returns the app session and the context of a WAMP session.
+func (f *Frontend) session(id uint) (kit.Session, context.Context) {
+	f.sessionsLock.Lock()
+	defer f.sessionsLock.Unlock()
+
+	return f.sessions[id], f.sessionContexts[id]
+}
+
+// taskAuthorizer restricts subscriptions to the tasks.<id> topics, which
+// publish the status and result of tasks, to the users that may access the
+// task.
+type taskAuthorizer struct {
+	frontend *Frontend
+}
+
+func (a *taskAuthorizer) Authorize(session *turnpike.Session, msg turnpike.Message) (bool, error) {
+	subscribe, ok := msg.(*turnpike.Subscribe)
+	if !ok {
+		return true, nil
+	}
+
+	topic := string(subscribe.Topic)
+
+	// Pattern subscriptions could match the topics of all tasks.
+	if match, _ := subscribe.Options["match"].(string); match != "" && match != "exact" {
+		if strings.HasPrefix(topic, "tasks") || strings.HasPrefix("tasks.", topic) || strings.HasPrefix(topic, ".") {
+			return false, nil
+		}
+		return true, nil
+	}
+
+	if !strings.HasPrefix(topic, "tasks.") {
+		return true, nil
+	}
+
+	service := a.frontend.registry.TaskService()
+	if service == nil {
+		return false, nil
+	}
+
+	task, err := service.GetTask(strings.TrimPrefix(topic, "tasks."))
+	if err != nil {
+		return false, err
+	} else if task == nil {
+		return false, nil
+	}
+
+	var user kit.User
+	if appSession, _ := a.frontend.session(uint(session.Id)); appSession != nil {
+		user = appSession.GetUser()
+	}
+
+	return methods.CanAccessTask(user, task), nil
+}
+
 func convertResponse(response kit.Response) *turnpike.CallResult {
 	result := &turnpike.CallResult{}
 
@@ -210,17 +301,23 @@ func (f *Frontend) registerMethod(method kit.Method) {
 
 		// Find session.
 		sessionId := uint(details["session_id"].(turnpike.ID))
-		session := f.sessions[sessionId]
+		session, ctx := f.session(sessionId)
 		if session == nil {
 			s, err := f.registry.UserService().StartSession(nil, "wamp")
 			if err != nil {
 				response = kit.NewErrorResponse(err)
 			} else {
+				f.sessionsLock.Lock()
 				f.sessions[sessionId] = s
+				f.sessionsLock.Unlock()
 				session = s
 			}
 		}
 
+		if ctx != nil {
+			request.SetGoContext(ctx)
+		}
+
 		request.SetSession(session)
 		if session.GetUser() != nil {
 			request.SetUser(session.GetUser())
@@ -266,6 +363,18 @@ func (f *Frontend) registerMethod(method kit.Method) {
 	}, nil)
 }
 
+// Publish sends data to all clients subscribed to the topic.
+func (f *Frontend) Publish(topic string, data map[string]interface{}) apperror.Error {
+	if f.client == nil {
+		return apperror.New("wamp_not_initialized", "The WAMP frontend was not initialized")
+	}
+
+	if err := f.client.Publish(topic, nil, nil, data); err != nil {
+		return apperror.Wrap(err, "wamp_publish_error")
+	}
+	return nil
+}
+
 func (f *Frontend) Start() apperror.Error {
 	// Register methods.
 	for _, method := range f.registry.Methods() {
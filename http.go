@@ -1,6 +1,7 @@
 package appkit
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"io/ioutil"
@@ -65,7 +66,8 @@ type AppRequest struct {
 	Path       string
 	HttpMethod string
 
-	Context *Context
+	Context   *Context
+	GoContext context.Context
 
 	RawData      []byte
 	Data         interface{}
@@ -142,6 +144,17 @@ func (r *AppRequest) SetContext(x *Context) {
 	r.Context = x
 }
 
+func (r *AppRequest) GetGoContext() context.Context {
+	if r.GoContext == nil {
+		return context.Background()
+	}
+	return r.GoContext
+}
+
+func (r *AppRequest) SetGoContext(x context.Context) {
+	r.GoContext = x
+}
+
 func (r *AppRequest) GetTransferData() TransferData {
 	return r.TransferData
 }
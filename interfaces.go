@@ -1,6 +1,7 @@
 package appkit
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"time"
@@ -19,6 +20,74 @@ type Model interface {
 	SetStrId(id string) error
 }
 
+// SoftDeleteModel is implemented by models that are marked as deleted
+// instead of being removed.
+// Resources with a SoftDeleteModel exclude deleted models from queries.
+type SoftDeleteModel interface {
+	Model
+
+	GetDeletedAt() *time.Time
+	SetDeletedAt(t *time.Time)
+
+	// GetDeletedBy returns the id of the user who deleted the model.
+	GetDeletedBy() string
+	SetDeletedBy(userId string)
+}
+
+// OptimisticLockModel is implemented by models that are protected against
+// concurrent updates.
+// Updates are rejected if the token of the update does not match the token
+// of the stored model.
+type OptimisticLockModel interface {
+	Model
+
+	// GetLockToken returns the token of the current state, or an empty
+	// string if the model was never saved.
+	GetLockToken() string
+
+	// NextLockToken changes the token before the model is saved.
+	NextLockToken()
+
+	// SetLockToken restores a token returned by GetLockToken.
+	SetLockToken(token string) error
+}
+
+// ModelVersion is a snapshot of a model that versioned resources store on
+// every change.
+type ModelVersion interface {
+	Model
+
+	GetModelId() string
+	GetVersion() int
+
+	// GetData returns the snapshot of the model.
+	GetData() map[string]interface{}
+
+	// GetUserId returns the id of the user who made the change.
+	GetUserId() string
+	GetCreatedAt() time.Time
+}
+
+// AccessGrant grants a user, or all users with a role, access to a model of a
+// shared resource.
+type AccessGrant interface {
+	Model
+
+	GetModelId() string
+
+	// GetUserId returns the id of the user, or an empty string for role
+	// grants.
+	GetUserId() string
+	GetRole() string
+
+	// GetLevel returns the access level: read, write or admin.
+	GetLevel() string
+
+	// GetGrantedBy returns the id of the user who shared the model.
+	GetGrantedBy() string
+	GetCreatedAt() time.Time
+}
+
 /**
  * EventHandler.
  */
@@ -158,6 +227,9 @@ type TaskRunner interface {
 }
 
 type TaskService interface {
+	// NewTask returns a new, unsaved task model.
+	NewTask() Task
+
 	Queue(task Task) apperror.Error
 
 	GetTask(id string) (Task, apperror.Error)
@@ -330,6 +402,11 @@ type Request interface {
 	GetContext() *Context
 	SetContext(context *Context)
 
+	// GetGoContext returns the context.Context that is cancelled once the
+	// request times out or the client goes away.
+	GetGoContext() context.Context
+	SetGoContext(ctx context.Context)
+
 	GetTransferData() TransferData
 	SetTransferData(data TransferData)
 
@@ -478,6 +555,66 @@ type Cache interface {
 	Cleanup() apperror.Error
 }
 
+// CounterCache is implemented by caches that can increment counters
+// atomically.
+type CounterCache interface {
+	// Increment adds delta to the counter stored under key and returns the
+	// new value. Missing counters start at 0.
+	Increment(key string, delta int, expiresAt *time.Time) (int, apperror.Error)
+}
+
+/**
+ * Rate limiting.
+ */
+
+// RateLimitStore persists the state of rate limits.
+type RateLimitStore interface {
+	// Get returns the stored state for the key, or an empty string.
+	Get(key string) (string, apperror.Error)
+
+	// Set stores the state for the key. It may be removed after ttl.
+	Set(key string, state string, ttl time.Duration) apperror.Error
+}
+
+// RateLimitCounterStore is implemented by stores that can count requests
+// atomically. Sliding window limits on these stores hold exactly, even when
+// the store is shared by multiple instances.
+type RateLimitCounterStore interface {
+	RateLimitStore
+
+	// Increment adds delta to the counter stored under key and returns the
+	// new value. The counter may be removed after ttl.
+	Increment(key string, delta int, ttl time.Duration) (int, apperror.Error)
+}
+
+type RateLimiter interface {
+	Store() RateLimitStore
+	SetStore(store RateLimitStore)
+
+	// Allow counts a request for the key against the policy.
+	// If the limit is exceeded, false is returned with the time the client
+	// has to wait before the next request is allowed.
+	Allow(policy *RateLimitPolicy, key string) (allowed bool, retryAfter time.Duration, err apperror.Error)
+
+	// Check counts the request against the policy, using the key specified
+	// by the policy.
+	// Returns a rate_limit_exceeded error if the limit is exceeded.
+	Check(policy *RateLimitPolicy, r Request) apperror.Error
+
+	// SetTrustedProxies sets the ips or CIDR ranges of the proxies whose
+	// X-Forwarded-For header is used to find the client ip.
+	// The header is ignored for all other requests.
+	SetTrustedProxies(proxies []string) apperror.Error
+
+	// AddRoutePolicy applies a policy to all HTTP requests with a path
+	// starting with pathPrefix.
+	// If httpMethod is empty, the policy applies to all HTTP methods.
+	AddRoutePolicy(httpMethod, pathPrefix string, policy *RateLimitPolicy)
+
+	// RoutePolicies returns the policies that apply to an HTTP request.
+	RoutePolicies(r Request) []*RateLimitPolicy
+}
+
 /**
  * Emails.
  */
@@ -558,6 +695,34 @@ type MethodHandler func(registry Registry, r Request, unblock func()) Response
 type Method interface {
 	GetName() string
 	IsBlocking() bool
+
+	// IsAsync returns true if the method is run as a task.
+	// The caller receives the task id instead of waiting for the result.
+	IsAsync() bool
+
+	// GetTimeout returns the maximum time the method may run.
+	// If it returns 0, the methods.timeout config setting is used.
+	GetTimeout() time.Duration
+
+	// GetArguments returns the arguments the method accepts, or nil if
+	// the arguments are not validated.
+	GetArguments() []*Argument
+
+	// GetArgumentsStruct returns a struct the validated arguments will be
+	// decoded into, or nil.
+	GetArgumentsStruct() interface{}
+
+	// GetPermissions returns the requirements a user must fulfill to run
+	// the method, or nil if the method is public.
+	GetPermissions() *MethodPermissions
+
+	// GetCache returns the cache policy of the method, or nil if the results
+	// should not be cached.
+	GetCache() *MethodCache
+
+	// GetRateLimit returns the rate limit policy of the method, or nil.
+	GetRateLimit() *RateLimitPolicy
+
 	GetHandler() MethodHandler
 }
 
@@ -575,6 +740,10 @@ type Resource interface {
 	Backend() db.Backend
 	SetBackend(db.Backend)
 
+	// WithBackend returns a copy of the resource that uses a different
+	// backend, for example a transaction.
+	WithBackend(db.Backend) Resource
+
 	ModelInfo() *db.ModelInfo
 
 	IsPublic() bool
@@ -592,11 +761,78 @@ type Resource interface {
 	Query(query *db.Query, targetSlice ...interface{}) ([]Model, apperror.Error)
 	FindOne(id interface{}) (Model, apperror.Error)
 
+	// IsSoftDelete returns true if the models of the resource are marked as
+	// deleted instead of being removed.
+	IsSoftDelete() bool
+
+	// QIncludeDeleted returns a new query that includes deleted models.
+	QIncludeDeleted() *db.Query
+
+	// FindOneIncludeDeleted finds a model even if it was deleted.
+	FindOneIncludeDeleted(id interface{}) (Model, apperror.Error)
+
+	// FindOneForRequest finds a model, restricted by the row policy of the
+	// request. Models the policy excludes are not found.
+	FindOneForRequest(id interface{}, r Request) (Model, apperror.Error)
+
 	Count(query *db.Query) (int, apperror.Error)
 
 	ApiFindOne(string, Request) Response
 	ApiFind(*db.Query, Request) Response
 
+	// ApiCount counts the models matching the query that the row policy
+	// lets the user see.
+	ApiCount(*db.Query, Request) Response
+
+	// CheckQuery enforces the query policy of the resource on a raw query
+	// supplied by a client, before it is parsed.
+	// Queries without a limit receive the default limit of the policy.
+	CheckQuery(rawQuery map[string]interface{}) apperror.Error
+
+	// CountTotal returns true if find responses contain the total number of
+	// results by default. Requests can override it with a count parameter.
+	CountTotal() bool
+	SetCountTotal(bool)
+
+	// Cache returns the cache policy of the resource, or nil if query
+	// results are not cached.
+	Cache() *ResourceCache
+	SetCache(*ResourceCache)
+
+	// CacheStats returns the number of cache hits and misses.
+	CacheStats() CacheStats
+
+	// Csv returns the csv export and import settings of the resource, or nil
+	// if both are disabled.
+	Csv() *ResourceCsv
+	SetCsv(*ResourceCsv)
+
+	// UnreadableFields returns the names of the fields of the model the user
+	// may not read. Api methods clear these fields.
+	UnreadableFields(model Model, user User) []string
+
+	// ClearUnreadableFields clears the fields the user may not read in the
+	// models of the response and in their loaded relations.
+	ClearUnreadableFields(response Response, user User) Response
+
+	// ReadableVersionData returns the snapshot of the version without the
+	// fields the user may not read in the model.
+	ReadableVersionData(model Model, version ModelVersion, user User) map[string]interface{}
+
+	// ComputedFieldNames returns the names of the read-only computed fields
+	// that api methods add to the serialized models.
+	ComputedFieldNames() []string
+
+	// UnreadableComputedFields returns the names of the computed fields of
+	// the model the user may not read. Api methods do not add these fields.
+	UnreadableComputedFields(model Model, user User) []string
+
+	// Validate checks the validation rules of the model. Create, Update
+	// and PartialUpdate validate models before they are saved.
+	// The returned validation_failed error contains an invalid_field error
+	// for every failed rule.
+	Validate(obj Model) apperror.Error
+
 	Create(obj Model, user User) apperror.Error
 	ApiCreate(obj Model, r Request) Response
 
@@ -611,6 +847,66 @@ type Resource interface {
 
 	Delete(obj Model, user User) apperror.Error
 	ApiDelete(id string, r Request) Response
+
+	// BulkCreate, BulkUpdate and BulkDelete run the hooks for every model
+	// and write the models in chunks, each inside one transaction if the
+	// backend supports it. A failed model is left out of its chunk.
+	// The results contain the error of every model. The returned error is
+	// only set if the operation could not run at all, for example with too
+	// many models, or, inside an existing transaction, if a model failed.
+	BulkCreate(objs []Model, user User) ([]*BulkResult, apperror.Error)
+	BulkUpdate(objs []Model, user User) ([]*BulkResult, apperror.Error)
+	BulkDelete(objs []Model, user User) ([]*BulkResult, apperror.Error)
+
+	ApiBulkCreate(objs []Model, r Request) Response
+	ApiBulkUpdate(objs []Model, r Request) Response
+	ApiBulkDelete(ids []string, r Request) Response
+
+	// ApiDeleteByQuery deletes all models matching the query that the user
+	// may delete.
+	ApiDeleteByQuery(query *db.Query, r Request) Response
+
+	// Restore restores a deleted model of a soft delete resource.
+	Restore(obj Model, user User) apperror.Error
+
+	// ApiRestore restores a deleted model the row policy lets the user see.
+	ApiRestore(id string, r Request) Response
+
+	// Purge permanently removes a model, even for soft delete resources.
+	Purge(obj Model, user User) apperror.Error
+
+	// ApiPurge permanently removes a model the row policy lets the user see.
+	ApiPurge(id string, r Request) Response
+
+	// IsVersioned returns true if a snapshot of the model is stored in the
+	// <collection>_versions collection on every change.
+	IsVersioned() bool
+	SetVersioned(bool)
+
+	// Versions returns all versions of a model, oldest first.
+	Versions(id interface{}) ([]ModelVersion, apperror.Error)
+	Version(id interface{}, version int) (ModelVersion, apperror.Error)
+
+	// Revert restores a model to a version with a regular update, which
+	// stores a new version.
+	Revert(id interface{}, version int, user User) (Model, apperror.Error)
+
+	// IsShared returns true if models can be shared with other users and
+	// roles. Grants are stored in the <collection>_grants collection.
+	IsShared() bool
+	SetShared(bool)
+
+	// Share grants a user, or all users with the role if userId is empty,
+	// access to a model with the level read, write or admin.
+	Share(id interface{}, userId, role, level string, user User) (AccessGrant, apperror.Error)
+	Unshare(id interface{}, userId, role string) apperror.Error
+
+	// Grants returns all grants of a model.
+	Grants(id interface{}) ([]AccessGrant, apperror.Error)
+
+	// AccessLevel returns the access level of the user to a model, or an
+	// empty string if the user has no access.
+	AccessLevel(model Model, user User) string
 }
 
 /**
@@ -954,6 +1250,12 @@ type Registry interface {
 	// AllModelInfo returns the model info from all registered backends.
 	AllModelInfo() map[string]*db.ModelInfo
 
+	// WithTransaction runs fn inside a transaction of the default backend,
+	// which is rolled back if fn returns an error.
+	// If the backend does not support transactions, fn receives the
+	// default backend.
+	WithTransaction(fn func(tx db.Backend) error) apperror.Error
+
 	// Resources.
 
 	Resource(name string) Resource
@@ -991,6 +1293,9 @@ type Registry interface {
 	TaskService() TaskService
 	SetTaskService(service TaskService)
 
+	RateLimiter() RateLimiter
+	SetRateLimiter(limiter RateLimiter)
+
 	EmailService() EmailService
 	SetEmailService(EmailService)
 
@@ -1109,6 +1414,13 @@ type Frontend interface {
 	Shutdown() (shutdownChan chan bool, err apperror.Error)
 }
 
+// PublishingFrontend is a frontend that can push data to subscribed clients.
+type PublishingFrontend interface {
+	Frontend
+
+	Publish(topic string, data map[string]interface{}) apperror.Error
+}
+
 type HttpFrontend interface {
 	Frontend
 
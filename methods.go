@@ -0,0 +1,53 @@
+package appkit
+
+import (
+	"time"
+)
+
+// MethodPermissions describes the requirements a user must fulfill to
+// run a method.
+type MethodPermissions struct {
+	// Authenticated methods can only be run by logged in users.
+	Authenticated bool `json:"authenticated"`
+
+	// Roles restricts the method to users that have at least one of the roles.
+	Roles []string `json:"roles,omitempty"`
+
+	// Permissions restricts the method to users that have all of the
+	// permissions.
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// MethodCache describes how the results of a method are cached.
+// Only successful responses are cached.
+type MethodCache struct {
+	// Cache is the name of the cache to use.
+	// If empty, the default cache is used.
+	Cache string
+
+	// TTL is the time results stay cached. If 0, results do not expire.
+	TTL time.Duration
+
+	// PerUser caches the results separately for each user.
+	PerUser bool
+
+	// Tags are added to all cached results, so they can be removed with
+	// Cache.ClearTag.
+	Tags []string
+
+	// Collections lists the collections the results depend on.
+	// The cached results are cleared when a model in one of the collections
+	// is created, updated or deleted.
+	Collections []string
+}
+
+// MethodInfo is the serializable description of a method.
+// The timeout is specified in seconds.
+type MethodInfo struct {
+	Name        string             `json:"name"`
+	Blocking    bool               `json:"blocking"`
+	Async       bool               `json:"async"`
+	Timeout     float64            `json:"timeout,omitempty"`
+	Arguments   []*Argument        `json:"arguments,omitempty"`
+	Permissions *MethodPermissions `json:"permissions,omitempty"`
+}
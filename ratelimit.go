@@ -0,0 +1,45 @@
+package appkit
+
+import (
+	"time"
+)
+
+type RateLimitAlgorithm string
+
+const (
+	// RateLimitTokenBucket allows bursts of up to Burst requests, and refills
+	// the bucket with Limit tokens per Interval.
+	RateLimitTokenBucket RateLimitAlgorithm = "token_bucket"
+
+	// RateLimitSlidingWindow allows Limit requests in any window of the
+	// length of Interval.
+	RateLimitSlidingWindow RateLimitAlgorithm = "sliding_window"
+)
+
+// RateLimitKey specifies what requests are grouped by when counting them.
+type RateLimitKey string
+
+const (
+	RateLimitByIP      RateLimitKey = "ip"
+	RateLimitByUser    RateLimitKey = "user"
+	RateLimitBySession RateLimitKey = "session"
+	RateLimitByApiKey  RateLimitKey = "api_key"
+)
+
+// RateLimitPolicy describes a rate limit.
+type RateLimitPolicy struct {
+	// Name identifies the policy in the rate limiter store.
+	// Policies with the same name share their limits.
+	Name string
+
+	Algorithm RateLimitAlgorithm
+	KeyBy     RateLimitKey
+
+	// Limit is the number of requests allowed per Interval.
+	Limit    int
+	Interval time.Duration
+
+	// Burst is the bucket size of the token bucket algorithm.
+	// Defaults to Limit.
+	Burst int
+}
@@ -0,0 +1,425 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/theduke/go-apperror"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+type routePolicy struct {
+	httpMethod string
+	pathPrefix string
+	policy     *kit.RateLimitPolicy
+}
+
+type Limiter struct {
+	sync.Mutex
+
+	store  kit.RateLimitStore
+	routes []*routePolicy
+
+	// trustedProxies are the networks of proxies whose X-Forwarded-For
+	// header is honoured.
+	trustedProxies []*net.IPNet
+}
+
+// Ensure that Limiter implements kit.RateLimiter.
+var _ kit.RateLimiter = (*Limiter)(nil)
+
+func New(store kit.RateLimitStore) *Limiter {
+	return &Limiter{
+		store:  store,
+		routes: make([]*routePolicy, 0),
+	}
+}
+
+func (l *Limiter) Store() kit.RateLimitStore {
+	return l.store
+}
+
+func (l *Limiter) SetStore(store kit.RateLimitStore) {
+	l.store = store
+}
+
+// tokenBucket is the state of the token bucket algorithm.
+type tokenBucket struct {
+	Tokens    float64 `json:"tokens"`
+	UpdatedAt int64   `json:"updatedAt"`
+}
+
+// slidingWindow is the state of the sliding window algorithm.
+// The number of requests in the sliding window is estimated from the counts
+// of the current and the previous fixed window.
+type slidingWindow struct {
+	WindowStart int64 `json:"windowStart"`
+	Current     int   `json:"current"`
+	Previous    int   `json:"previous"`
+}
+
+// Allow counts a request for the key against the policy.
+//
+// Sliding window limits use atomic counters if the store implements
+// kit.RateLimitCounterStore, which the memory store and cache stores for
+// Redis do. They hold exactly, even when the store is shared by multiple
+// instances.
+//
+// All other limits read and write the state under the lock of the limiter.
+// They hold exactly within one instance, but with a store shared by
+// multiple instances, concurrent requests on different instances can
+// overwrite each other's state, so more requests than the limit may pass.
+func (l *Limiter) Allow(policy *kit.RateLimitPolicy, key string) (bool, time.Duration, apperror.Error) {
+	if policy.Limit < 1 || policy.Interval <= 0 {
+		return false, 0, apperror.New("invalid_rate_limit_policy", fmt.Sprintf("The rate limit policy %v needs a limit and interval", policy.Name))
+	}
+
+	storeKey := "ratelimit." + policy.Name + "." + key
+
+	if counters, ok := l.store.(kit.RateLimitCounterStore); ok && policy.Algorithm == kit.RateLimitSlidingWindow {
+		return countRequestAtomic(policy, counters, storeKey, time.Now().UnixNano())
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	rawState, err := l.store.Get(storeKey)
+	if err != nil {
+		return false, 0, err
+	}
+
+	now := time.Now().UnixNano()
+
+	var allowed bool
+	var retryAfter time.Duration
+	var state interface{}
+	var ttl time.Duration
+
+	switch policy.Algorithm {
+	case kit.RateLimitTokenBucket, "":
+		bucket := &tokenBucket{}
+		if err := unmarshalState(rawState, bucket); err != nil {
+			return false, 0, err
+		}
+		allowed, retryAfter, ttl = takeToken(policy, bucket, now)
+		state = bucket
+
+	case kit.RateLimitSlidingWindow:
+		window := &slidingWindow{}
+		if err := unmarshalState(rawState, window); err != nil {
+			return false, 0, err
+		}
+		allowed, retryAfter = countRequest(policy, window, now)
+		state = window
+		ttl = 2 * policy.Interval
+
+	default:
+		return false, 0, apperror.New("unknown_rate_limit_algorithm", fmt.Sprintf("Unknown rate limit algorithm %v", policy.Algorithm))
+	}
+
+	js, jsonErr := json.Marshal(state)
+	if jsonErr != nil {
+		return false, 0, apperror.Wrap(jsonErr, "json_marshal_error")
+	}
+	if err := l.store.Set(storeKey, string(js), ttl); err != nil {
+		return false, 0, err
+	}
+
+	return allowed, retryAfter, nil
+}
+
+func unmarshalState(rawState string, state interface{}) apperror.Error {
+	if rawState == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(rawState), state); err != nil {
+		return apperror.Wrap(err, "invalid_rate_limit_state")
+	}
+	return nil
+}
+
+func takeToken(policy *kit.RateLimitPolicy, bucket *tokenBucket, now int64) (bool, time.Duration, time.Duration) {
+	burst := float64(policy.Burst)
+	if burst < 1 {
+		burst = float64(policy.Limit)
+	}
+
+	// Tokens added per nanosecond.
+	rate := float64(policy.Limit) / float64(policy.Interval)
+
+	if bucket.UpdatedAt == 0 {
+		bucket.Tokens = burst
+	} else {
+		bucket.Tokens = math.Min(burst, bucket.Tokens+float64(now-bucket.UpdatedAt)*rate)
+	}
+	bucket.UpdatedAt = now
+
+	// The state can be dropped once the bucket is full again.
+	ttl := time.Duration(burst/rate) + time.Second
+
+	if bucket.Tokens < 1 {
+		return false, time.Duration((1 - bucket.Tokens) / rate), ttl
+	}
+
+	bucket.Tokens--
+	return true, 0, ttl
+}
+
+func countRequest(policy *kit.RateLimitPolicy, window *slidingWindow, now int64) (bool, time.Duration) {
+	interval := int64(policy.Interval)
+
+	// Advance the fixed windows.
+	if elapsed := now - window.WindowStart; elapsed >= 2*interval {
+		window.WindowStart = now - now%interval
+		window.Previous = 0
+		window.Current = 0
+	} else if elapsed >= interval {
+		window.WindowStart += interval
+		window.Previous = window.Current
+		window.Current = 0
+	}
+
+	elapsed := float64(now - window.WindowStart)
+	weight := 1 - elapsed/float64(interval)
+	estimate := float64(window.Previous)*weight + float64(window.Current)
+
+	if estimate+1 <= float64(policy.Limit) {
+		window.Current++
+		return true, 0
+	}
+
+	return false, slidingWindowWait(policy, window, now)
+}
+
+// slidingWindowWait estimates the time until the weighted count of the
+// previous window has dropped enough to allow another request.
+func slidingWindowWait(policy *kit.RateLimitPolicy, window *slidingWindow, now int64) time.Duration {
+	interval := float64(policy.Interval)
+	elapsed := float64(now - window.WindowStart)
+	limit := float64(policy.Limit)
+
+	var wait float64
+	if window.Previous > 0 && float64(window.Current)+1 <= limit {
+		needed := interval * (1 - (limit-float64(window.Current)-1)/float64(window.Previous))
+		wait = needed - elapsed
+	} else {
+		wait = interval - elapsed
+	}
+
+	return time.Duration(math.Max(wait, 0))
+}
+
+// countRequestAtomic implements the sliding window algorithm with a counter
+// per fixed window. Every request increments the counter of the current
+// window first, so concurrent requests never see the same count.
+func countRequestAtomic(policy *kit.RateLimitPolicy, store kit.RateLimitCounterStore, storeKey string, now int64) (bool, time.Duration, apperror.Error) {
+	interval := int64(policy.Interval)
+	index := now / interval
+	ttl := 2 * policy.Interval
+
+	currentKey := fmt.Sprintf("%v.%v", storeKey, index)
+
+	previous, err := store.Increment(fmt.Sprintf("%v.%v", storeKey, index-1), 0, ttl)
+	if err != nil {
+		return false, 0, err
+	}
+	current, err := store.Increment(currentKey, 1, ttl)
+	if err != nil {
+		return false, 0, err
+	}
+
+	window := &slidingWindow{
+		WindowStart: index * interval,
+		Current:     current - 1,
+		Previous:    previous,
+	}
+
+	weight := 1 - float64(now-window.WindowStart)/float64(interval)
+	if float64(previous)*weight+float64(current) <= float64(policy.Limit) {
+		return true, 0, nil
+	}
+
+	// Rejected requests do not count against the limit.
+	if _, err := store.Increment(currentKey, -1, ttl); err != nil {
+		return false, 0, err
+	}
+
+	return false, slidingWindowWait(policy, window, now), nil
+}
+
+// RequestKey returns the key of the request that is used to count requests
+// for the given key type.
+// Falls back to the ip if no user, session or api key is available.
+// Requests without an ip, like WAMP calls, fall back to the session, so that
+// their clients do not share one bucket.
+func (l *Limiter) RequestKey(keyBy kit.RateLimitKey, r kit.Request) string {
+	switch keyBy {
+	case kit.RateLimitByUser:
+		if user := r.GetUser(); user != nil {
+			return fmt.Sprintf("user.%v", user.GetId())
+		}
+	case kit.RateLimitBySession:
+		if session := r.GetSession(); session != nil && session.GetToken() != "" {
+			return "session." + session.GetToken()
+		}
+	case kit.RateLimitByApiKey:
+		if key := apiKey(r); key != "" {
+			return "apikey." + key
+		}
+	}
+
+	if ip := l.requestIp(r); ip != "" {
+		return "ip." + ip
+	}
+	if session := r.GetSession(); session != nil && session.GetToken() != "" {
+		return "session." + session.GetToken()
+	}
+	return "ip.unknown"
+}
+
+func apiKey(r kit.Request) string {
+	if httpRequest := r.GetHttpRequest(); httpRequest != nil {
+		if key := httpRequest.Header.Get("X-Api-Key"); key != "" {
+			return key
+		}
+	}
+	return r.GetContext().String("api_key")
+}
+
+func (l *Limiter) SetTrustedProxies(proxies []string) apperror.Error {
+	networks := make([]*net.IPNet, 0)
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return apperror.Wrap(err, "invalid_trusted_proxy", fmt.Sprintf("Invalid trusted proxy %v", proxy))
+		}
+		networks = append(networks, network)
+	}
+
+	l.Lock()
+	l.trustedProxies = networks
+	l.Unlock()
+
+	return nil
+}
+
+func (l *Limiter) isTrustedProxy(ip net.IP) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	for _, network := range l.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestIp returns the ip of the client, or an empty string for requests
+// that did not come over http.
+// The X-Forwarded-For header is only used if the request comes from a
+// trusted proxy. The client ip is then the last entry that is not a
+// trusted proxy, since clients can prepend arbitrary entries.
+func (l *Limiter) requestIp(r kit.Request) string {
+	httpRequest := r.GetHttpRequest()
+	if httpRequest == nil {
+		return ""
+	}
+
+	addr := httpRequest.RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil || !l.isTrustedProxy(ip) {
+		return addr
+	}
+
+	forwarded := strings.Split(httpRequest.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		entry := strings.TrimSpace(forwarded[i])
+		forwardedIp := net.ParseIP(entry)
+		if forwardedIp == nil {
+			break
+		}
+		addr = entry
+		if !l.isTrustedProxy(forwardedIp) {
+			break
+		}
+	}
+
+	return addr
+}
+
+// LimitExceededError builds the error returned for requests that exceeded
+// a rate limit.
+func LimitExceededError(policy *kit.RateLimitPolicy, retryAfter time.Duration) apperror.Error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return &apperror.Err{
+		Code:    "rate_limit_exceeded",
+		Message: fmt.Sprintf("Rate limit exceeded, retry in %v seconds", seconds),
+		Data: map[string]interface{}{
+			"policy":     policy.Name,
+			"retryAfter": seconds,
+		},
+		Public: true,
+		Status: 429,
+	}
+}
+
+func (l *Limiter) Check(policy *kit.RateLimitPolicy, r kit.Request) apperror.Error {
+	allowed, retryAfter, err := l.Allow(policy, l.RequestKey(policy.KeyBy, r))
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return LimitExceededError(policy, retryAfter)
+	}
+	return nil
+}
+
+func (l *Limiter) AddRoutePolicy(httpMethod, pathPrefix string, policy *kit.RateLimitPolicy) {
+	l.routes = append(l.routes, &routePolicy{
+		httpMethod: strings.ToUpper(httpMethod),
+		pathPrefix: pathPrefix,
+		policy:     policy,
+	})
+}
+
+func (l *Limiter) RoutePolicies(r kit.Request) []*kit.RateLimitPolicy {
+	httpRequest := r.GetHttpRequest()
+	if httpRequest == nil {
+		return nil
+	}
+
+	policies := make([]*kit.RateLimitPolicy, 0)
+	for _, route := range l.routes {
+		if route.httpMethod != "" && route.httpMethod != httpRequest.Method {
+			continue
+		}
+		if strings.HasPrefix(httpRequest.URL.Path, route.pathPrefix) {
+			policies = append(policies, route.policy)
+		}
+	}
+
+	return policies
+}
@@ -0,0 +1,155 @@
+package ratelimit_test
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/ratelimit"
+	"github.com/app-kit/go-appkit/users"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Limiter", func() {
+	var limiter *Limiter
+
+	BeforeEach(func() {
+		limiter = New(NewMemoryStore())
+	})
+
+	allow := func(policy *kit.RateLimitPolicy, key string) bool {
+		allowed, _, err := limiter.Allow(policy, key)
+		Expect(err).To(BeNil())
+		return allowed
+	}
+
+	It("Should limit with the token bucket algorithm", func() {
+		policy := &kit.RateLimitPolicy{
+			Name:      "bucket",
+			Algorithm: kit.RateLimitTokenBucket,
+			Limit:     3,
+			Interval:  time.Minute,
+		}
+
+		Expect(allow(policy, "a")).To(BeTrue())
+		Expect(allow(policy, "a")).To(BeTrue())
+		Expect(allow(policy, "a")).To(BeTrue())
+
+		allowed, retryAfter, err := limiter.Allow(policy, "a")
+		Expect(err).To(BeNil())
+		Expect(allowed).To(BeFalse())
+		Expect(retryAfter).To(BeNumerically(">", 19*time.Second))
+		Expect(retryAfter).To(BeNumerically("<=", 20*time.Second))
+
+		// Other keys have their own limit.
+		Expect(allow(policy, "b")).To(BeTrue())
+	})
+
+	It("Should refill the token bucket", func() {
+		policy := &kit.RateLimitPolicy{
+			Name:      "refill",
+			Algorithm: kit.RateLimitTokenBucket,
+			Limit:     1,
+			Interval:  50 * time.Millisecond,
+		}
+
+		Expect(allow(policy, "a")).To(BeTrue())
+		Expect(allow(policy, "a")).To(BeFalse())
+		time.Sleep(60 * time.Millisecond)
+		Expect(allow(policy, "a")).To(BeTrue())
+	})
+
+	It("Should limit with the sliding window algorithm", func() {
+		policy := &kit.RateLimitPolicy{
+			Name:      "window",
+			Algorithm: kit.RateLimitSlidingWindow,
+			Limit:     2,
+			Interval:  time.Minute,
+		}
+
+		Expect(allow(policy, "a")).To(BeTrue())
+		Expect(allow(policy, "a")).To(BeTrue())
+
+		allowed, retryAfter, err := limiter.Allow(policy, "a")
+		Expect(err).To(BeNil())
+		Expect(allowed).To(BeFalse())
+		Expect(retryAfter).To(BeNumerically(">", 0))
+	})
+
+	It("Should not exceed sliding window limits with concurrent requests", func() {
+		policy := &kit.RateLimitPolicy{
+			Name:      "concurrent",
+			Algorithm: kit.RateLimitSlidingWindow,
+			Limit:     10,
+			Interval:  time.Minute,
+		}
+
+		var wg sync.WaitGroup
+		var lock sync.Mutex
+		count := 0
+
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				allowed, _, err := limiter.Allow(policy, "a")
+				Expect(err).To(BeNil())
+				if allowed {
+					lock.Lock()
+					count++
+					lock.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		Expect(count).To(Equal(10))
+	})
+
+	Describe("RequestKey", func() {
+		request := func(remoteAddr, forwarded string) kit.Request {
+			httpRequest, _ := http.NewRequest("GET", "/", nil)
+			httpRequest.RemoteAddr = remoteAddr
+			if forwarded != "" {
+				httpRequest.Header.Set("X-Forwarded-For", forwarded)
+			}
+
+			r := kit.NewRequest()
+			r.SetHttpRequest(httpRequest)
+			return r
+		}
+
+		It("Should ignore X-Forwarded-For from untrusted clients", func() {
+			r := request("1.2.3.4:5000", "9.9.9.9")
+			Expect(limiter.RequestKey(kit.RateLimitByIP, r)).To(Equal("ip.1.2.3.4"))
+		})
+
+		It("Should use X-Forwarded-For from trusted proxies", func() {
+			Expect(limiter.SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})).To(BeNil())
+
+			r := request("10.0.0.1:5000", "9.9.9.9, 5.6.7.8, 192.168.1.1")
+			Expect(limiter.RequestKey(kit.RateLimitByIP, r)).To(Equal("ip.5.6.7.8"))
+		})
+
+		It("Should key requests without an ip by their session", func() {
+			r := kit.NewRequest()
+			r.SetFrontend("wamp")
+			r.SetSession(&users.Session{Token: "token"})
+			Expect(limiter.RequestKey(kit.RateLimitByIP, r)).To(Equal("session.token"))
+		})
+
+		It("Should reject invalid trusted proxies", func() {
+			Expect(limiter.SetTrustedProxies([]string{"invalid"})).ToNot(BeNil())
+		})
+	})
+
+	It("Should return a rate_limit_exceeded error", func() {
+		err := LimitExceededError(&kit.RateLimitPolicy{Name: "p"}, 1500*time.Millisecond)
+		Expect(err.GetCode()).To(Equal("rate_limit_exceeded"))
+		Expect(err.GetStatus()).To(Equal(429))
+	})
+})
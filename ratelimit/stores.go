@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/theduke/go-apperror"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+type memoryItem struct {
+	state     string
+	expiresAt time.Time
+}
+
+// MemoryStore keeps the rate limit state in memory.
+// Limits are not shared between multiple instances of the app.
+type MemoryStore struct {
+	sync.Mutex
+
+	items map[string]*memoryItem
+
+	lastCleanup time.Time
+}
+
+// Ensure that MemoryStore implements kit.RateLimitCounterStore.
+var _ kit.RateLimitCounterStore = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		items:       make(map[string]*memoryItem),
+		lastCleanup: time.Now(),
+	}
+}
+
+func (s *MemoryStore) Get(key string) (string, apperror.Error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.get(key), nil
+}
+
+// get must be called with the store locked.
+func (s *MemoryStore) get(key string) string {
+	item := s.items[key]
+	if item == nil || time.Now().After(item.expiresAt) {
+		return ""
+	}
+	return item.state
+}
+
+func (s *MemoryStore) Set(key string, state string, ttl time.Duration) apperror.Error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.set(key, state, ttl)
+	return nil
+}
+
+func (s *MemoryStore) Increment(key string, delta int, ttl time.Duration) (int, apperror.Error) {
+	s.Lock()
+	defer s.Unlock()
+
+	count := 0
+	if state := s.get(key); state != "" {
+		n, err := strconv.Atoi(state)
+		if err != nil {
+			return 0, apperror.Wrap(err, "invalid_rate_limit_counter")
+		}
+		count = n
+	}
+	count += delta
+
+	s.set(key, strconv.Itoa(count), ttl)
+	return count, nil
+}
+
+// set must be called with the store locked.
+func (s *MemoryStore) set(key string, state string, ttl time.Duration) {
+	now := time.Now()
+	s.items[key] = &memoryItem{
+		state:     state,
+		expiresAt: now.Add(ttl),
+	}
+
+	// Remove expired items once a minute.
+	if now.Sub(s.lastCleanup) > time.Minute {
+		for key, item := range s.items {
+			if now.After(item.expiresAt) {
+				delete(s.items, key)
+			}
+		}
+		s.lastCleanup = now
+	}
+}
+
+// CacheStore keeps the rate limit state in a cache.
+// With a shared cache like Redis, limits are shared between all instances
+// of the app.
+// The state is read and written without locking the cache, so only caches
+// that implement kit.CounterCache enforce sliding window limits exactly
+// across instances. See Limiter.Allow().
+type CacheStore struct {
+	cache kit.Cache
+}
+
+// Ensure that CacheStore implements kit.RateLimitStore.
+var _ kit.RateLimitStore = (*CacheStore)(nil)
+
+// NewCacheStore builds a store for the cache.
+// If the cache implements kit.CounterCache, the store implements
+// kit.RateLimitCounterStore.
+func NewCacheStore(cache kit.Cache) kit.RateLimitStore {
+	store := &CacheStore{
+		cache: cache,
+	}
+
+	if counters, ok := cache.(kit.CounterCache); ok {
+		return &CounterCacheStore{
+			CacheStore: store,
+			counters:   counters,
+		}
+	}
+	return store
+}
+
+func (s *CacheStore) Get(key string) (string, apperror.Error) {
+	return s.cache.GetString(key)
+}
+
+func (s *CacheStore) Set(key string, state string, ttl time.Duration) apperror.Error {
+	expiresAt := time.Now().Add(ttl)
+	return s.cache.SetString(key, state, &expiresAt, nil)
+}
+
+// CounterCacheStore is a CacheStore for caches that support atomic counters.
+type CounterCacheStore struct {
+	*CacheStore
+
+	counters kit.CounterCache
+}
+
+// Ensure that CounterCacheStore implements kit.RateLimitCounterStore.
+var _ kit.RateLimitCounterStore = (*CounterCacheStore)(nil)
+
+func (s *CounterCacheStore) Increment(key string, delta int, ttl time.Duration) (int, apperror.Error) {
+	expiresAt := time.Now().Add(ttl)
+	return s.counters.Increment(key, delta, &expiresAt)
+}
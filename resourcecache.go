@@ -0,0 +1,23 @@
+package appkit
+
+import (
+	"time"
+)
+
+// ResourceCache describes how the query results of a resource are cached.
+// Results are cleared when a model is created, updated or deleted through
+// the resource.
+type ResourceCache struct {
+	// Cache is the name of the cache to use.
+	// If empty, the default cache is used.
+	Cache string
+
+	// TTL is the time results stay cached. If 0, results do not expire.
+	TTL time.Duration
+}
+
+// CacheStats counts the cache hits and misses of a resource.
+type CacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
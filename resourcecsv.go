@@ -0,0 +1,15 @@
+package appkit
+
+// ResourceCsv enables the csv and xlsx export and import of a resource.
+type ResourceCsv struct {
+	// Export enables GET /api/<collection>/export.csv and
+	// /api/<collection>/export.xlsx.
+	Export bool
+
+	// Import enables POST /api/<collection>/import for csv and xlsx files.
+	Import bool
+
+	// Columns lists the exported columns by their marshal names.
+	// If empty, all attributes and computed fields are exported.
+	Columns []string
+}
@@ -0,0 +1,390 @@
+package resources
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/theduke/go-apperror"
+	db "github.com/theduke/go-dukedb"
+	expr "github.com/theduke/go-dukedb/expressions"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// Access levels of shared models.
+// Every level includes the lower levels.
+const (
+	AccessRead  = "read"
+	AccessWrite = "write"
+	AccessAdmin = "admin"
+)
+
+var accessLevels = map[string]int{
+	AccessRead:  1,
+	AccessWrite: 2,
+	AccessAdmin: 3,
+}
+
+// AccessAllows returns true if the access level includes the required level.
+func AccessAllows(level, required string) bool {
+	return level != "" && accessLevels[level] >= accessLevels[required]
+}
+
+// AccessGrant grants a user or all users with a role access to a model of a
+// shared resource.
+// The grants of a resource are stored in the <collection>_grants collection.
+type AccessGrant struct {
+	db.IntIdModel
+
+	collection string
+
+	ModelId   string `db:"required;max:255"`
+	UserId    string `db:"max:255"`
+	Role      string `db:"max:100"`
+	Level     string `db:"required;max:20"`
+	GrantedBy string `db:"max:255"`
+	CreatedAt time.Time
+}
+
+// Ensure AccessGrant implements kit.AccessGrant.
+var _ kit.AccessGrant = (*AccessGrant)(nil)
+
+func (g *AccessGrant) Collection() string {
+	return g.collection
+}
+
+func (g *AccessGrant) GetModelId() string {
+	return g.ModelId
+}
+
+func (g *AccessGrant) GetUserId() string {
+	return g.UserId
+}
+
+func (g *AccessGrant) GetRole() string {
+	return g.Role
+}
+
+func (g *AccessGrant) GetLevel() string {
+	return g.Level
+}
+
+func (g *AccessGrant) GetGrantedBy() string {
+	return g.GrantedBy
+}
+
+func (g *AccessGrant) GetCreatedAt() time.Time {
+	return g.CreatedAt
+}
+
+// GrantsCollection returns the collection that stores the grants of models
+// in the given collection.
+func GrantsCollection(collection string) string {
+	return collection + "_grants"
+}
+
+func (res *Resource) IsShared() bool {
+	return res.shared
+}
+
+func (res *Resource) SetShared(shared bool) {
+	res.shared = shared
+	if shared && res.backend != nil {
+		res.registerGrantModel()
+	}
+}
+
+func (res *Resource) registerGrantModel() {
+	collection := GrantsCollection(res.Collection())
+	if !res.backend.HasCollection(collection) {
+		res.backend.RegisterModel(&AccessGrant{collection: collection})
+	}
+}
+
+func (res *Resource) sharingDisabled() apperror.Error {
+	return apperror.New("sharing_disabled", fmt.Sprintf("The collection %v is not shared", res.Collection()), true)
+}
+
+// Grants returns all grants of a model.
+func (res *Resource) Grants(id interface{}) ([]kit.AccessGrant, apperror.Error) {
+	if !res.shared {
+		return nil, res.sharingDisabled()
+	}
+
+	collection := GrantsCollection(res.Collection())
+	items, err := res.backend.Q(collection).Filter("model_id", fmt.Sprintf("%v", id)).Find()
+	if err != nil {
+		return nil, err
+	}
+
+	grants := make([]kit.AccessGrant, 0)
+	for _, item := range items {
+		grant := item.(*AccessGrant)
+		grant.collection = collection
+		grants = append(grants, grant)
+	}
+	return grants, nil
+}
+
+// findGrant returns the grant of a model for the user or role, or nil.
+func (res *Resource) findGrant(id interface{}, userId, role string) (*AccessGrant, apperror.Error) {
+	grants, err := res.Grants(id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, grant := range grants {
+		if grant.GetUserId() == userId && grant.GetRole() == role {
+			return grant.(*AccessGrant), nil
+		}
+	}
+	return nil, nil
+}
+
+// Share grants a user, or all users with the role if userId is empty, access
+// to a model. An existing grant of the user or role is updated.
+func (res *Resource) Share(id interface{}, userId, role, level string, user kit.User) (kit.AccessGrant, apperror.Error) {
+	if !res.shared {
+		return nil, res.sharingDisabled()
+	}
+	if (userId == "") == (role == "") {
+		return nil, apperror.New("invalid_grant", "Either a user or a role must be given", true)
+	}
+	if _, ok := accessLevels[level]; !ok {
+		return nil, apperror.New("invalid_access_level", fmt.Sprintf("Unknown access level %v", level), true)
+	}
+
+	model, err := res.FindOne(id)
+	if err != nil {
+		return nil, err
+	} else if model == nil {
+		return nil, apperror.New("not_found", "")
+	}
+
+	grant, err := res.findGrant(id, userId, role)
+	if err != nil {
+		return nil, err
+	}
+
+	if grant != nil {
+		grant.Level = level
+		err = res.backend.Update(grant)
+	} else {
+		grant = &AccessGrant{
+			collection: GrantsCollection(res.Collection()),
+			ModelId:    model.GetStrId(),
+			UserId:     userId,
+			Role:       role,
+			Level:      level,
+			CreatedAt:  time.Now(),
+		}
+		if user != nil {
+			grant.GrantedBy = user.GetStrId()
+		}
+		err = res.backend.Create(grant)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Cached results depend on the grants.
+	res.afterCommit(func() {
+		res.clearCache(model)
+	})
+
+	return grant, nil
+}
+
+// Unshare removes the grant of a user or role.
+func (res *Resource) Unshare(id interface{}, userId, role string) apperror.Error {
+	grant, err := res.findGrant(id, userId, role)
+	if err != nil {
+		return err
+	} else if grant == nil {
+		return apperror.New("unknown_grant", "The model is not shared with the user or role", true)
+	}
+
+	if err := res.backend.Delete(grant); err != nil {
+		return err
+	}
+
+	if model, _ := res.FindOneIncludeDeleted(id); model != nil {
+		res.afterCommit(func() {
+			res.clearCache(model)
+		})
+	}
+	return nil
+}
+
+// preloadGrants loads the grants of all models with a single query and
+// returns a copy of the resource that uses them for access checks.
+func (res *Resource) preloadGrants(models []kit.Model) (*Resource, apperror.Error) {
+	if !res.shared || len(models) == 0 {
+		return res, nil
+	}
+
+	ids := make([]string, 0, len(models))
+	grants := make(map[string][]kit.AccessGrant, len(models))
+	for _, model := range models {
+		id := model.GetStrId()
+		ids = append(ids, id)
+		grants[id] = make([]kit.AccessGrant, 0)
+	}
+
+	collection := GrantsCollection(res.Collection())
+	items, err := res.backend.Q(collection).FilterExpr(expr.In("", "model_id", ids)).Find()
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		grant := item.(*AccessGrant)
+		grant.collection = collection
+		grants[grant.ModelId] = append(grants[grant.ModelId], grant)
+	}
+
+	scoped := *res
+	scoped.preloadedGrants = grants
+	return &scoped, nil
+}
+
+// deleteGrants removes all grants of a model that is removed.
+func (res *Resource) deleteGrants(obj kit.Model) apperror.Error {
+	if !res.shared {
+		return nil
+	}
+
+	grants, err := res.Grants(obj.GetId())
+	if err != nil {
+		return err
+	}
+	for _, grant := range grants {
+		if err := res.backend.Delete(grant); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AccessLevel returns the access level of the user to a model.
+// Admins and the owner of the model have admin access. Otherwise, the
+// highest level of the grants for the user and its roles is returned, or
+// an empty string.
+func (res *Resource) AccessLevel(model kit.Model, user kit.User) string {
+	if user == nil {
+		return ""
+	}
+	if user.HasRole("admin") || isOwner(model, user, false) {
+		return AccessAdmin
+	}
+	if !res.shared {
+		return ""
+	}
+
+	grants, ok := res.preloadedGrants[model.GetStrId()]
+	if !ok {
+		var err apperror.Error
+		if grants, err = res.Grants(model.GetId()); err != nil {
+			return ""
+		}
+	}
+
+	level := ""
+	for _, grant := range grants {
+		if grant.GetUserId() != "" && grant.GetUserId() != user.GetStrId() {
+			continue
+		}
+		if grant.GetRole() != "" && !user.HasRole(grant.GetRole()) {
+			continue
+		}
+		if accessLevels[grant.GetLevel()] > accessLevels[level] {
+			level = grant.GetLevel()
+		}
+	}
+	return level
+}
+
+// sharedIds returns the ids of the models that are shared with the user or
+// its roles.
+func sharedIds(res kit.Resource, user kit.User) ([]string, apperror.Error) {
+	conditions := []expr.Expression{expr.Eq("", "user_id", user.GetStrId())}
+	if roles := user.GetRoles(); len(roles) > 0 {
+		conditions = append(conditions, expr.In("", "role", roles))
+	}
+
+	items, err := res.Backend().Q(GrantsCollection(res.Collection())).FilterExpr(expr.Or(conditions...)).Find()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0)
+	for _, item := range items {
+		ids = append(ids, item.(*AccessGrant).ModelId)
+	}
+	return ids, nil
+}
+
+// SharedResource is a resource mixin for models that implement the
+// appkit.UserModel interface and can be shared with other users and roles.
+// The owner and admins have full access. Other users need a read grant to
+// find, a write grant to update and an admin grant to delete or share a
+// model.
+// Queries through the api only return models the user owns or that are
+// shared with the user.
+type SharedResource struct{}
+
+func (SharedResource) AllowFind(res kit.Resource, model kit.Model, user kit.User) bool {
+	return AccessAllows(res.AccessLevel(model, user), AccessRead)
+}
+
+func (SharedResource) AllowCreate(res kit.Resource, obj kit.Model, user kit.User) bool {
+	return UserResource{}.AllowCreate(res, obj, user)
+}
+
+func (SharedResource) AllowUpdate(res kit.Resource, obj kit.Model, old kit.Model, user kit.User) bool {
+	if user != nil && user.HasPermission(res.Collection()+".update") {
+		return true
+	}
+	// The level is checked on the stored model, since the update could
+	// change the owner.
+	if old == nil {
+		old = obj
+	}
+	return AccessAllows(res.AccessLevel(old, user), AccessWrite)
+}
+
+func (SharedResource) AllowDelete(res kit.Resource, obj kit.Model, user kit.User) bool {
+	if user != nil && user.HasPermission(res.Collection()+".delete") {
+		return true
+	}
+	return AccessAllows(res.AccessLevel(obj, user), AccessAdmin)
+}
+
+func (SharedResource) ApiAlterQuery(res kit.Resource, query *db.Query, r kit.Request) apperror.Error {
+	user := r.GetUser()
+	if user == nil || user.HasRole("admin") {
+		// Models of anonymous users are removed by AllowFind.
+		return nil
+	}
+
+	ownerField := "user_id"
+	if attr := res.ModelInfo().FindAttribute("UserId"); attr != nil {
+		ownerField = attr.BackendName()
+	}
+	conditions := []expr.Expression{expr.Eq("", ownerField, user.GetId())}
+
+	if res.IsShared() {
+		ids, err := sharedIds(res, user)
+		if err != nil {
+			return err
+		}
+
+		// The shared ids are matched with a single IN condition.
+		if len(ids) > 0 {
+			idField := res.ModelInfo().PkAttribute().BackendName()
+			conditions = append(conditions, expr.In("", idField, ids))
+		}
+	}
+
+	query.FilterExpr(expr.Or(conditions...))
+	return nil
+}
@@ -0,0 +1,381 @@
+package resources
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/theduke/go-apperror"
+	db "github.com/theduke/go-dukedb"
+	expr "github.com/theduke/go-dukedb/expressions"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// bulkOperation runs the operation for a single model of a bulk operation and
+// returns the resulting model.
+type bulkOperation func(res *Resource, obj kit.Model) (kit.Model, apperror.Error)
+
+// bulkLimits returns the number of models written per transaction and the
+// maximum number of models of a bulk operation.
+func (res *Resource) bulkLimits() (int, int) {
+	chunkSize, maxModels := 100, 1000
+	if res.registry != nil {
+		config := res.registry.Config()
+		chunkSize = config.UInt("resources.bulk.chunkSize", chunkSize)
+		maxModels = config.UInt("resources.bulk.maxModels", maxModels)
+	}
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+	return chunkSize, maxModels
+}
+
+// bulk runs the operation for every model.
+// The models are written in chunks, each inside one transaction if the
+// backend supports transactions. A failed model rolls back its chunk, which
+// then runs again without the failed model, so every model gets its own
+// result and no failed writes, including those of hooks, are kept.
+// If preload is true, the stored models of a chunk are loaded with a single
+// query for the updates, restricted by the row policy of r if it is set.
+// If the resource is already used inside a transaction, a failure can not be
+// rolled back on its own, so the first error aborts the bulk operation.
+func (res *Resource) bulk(objs []kit.Model, preload bool, r kit.Request, op bulkOperation) ([]*kit.BulkResult, apperror.Error) {
+	chunkSize, maxModels := res.bulkLimits()
+	if maxModels > 0 && len(objs) > maxModels {
+		return nil, &apperror.Err{
+			Code:    "too_many_models",
+			Message: fmt.Sprintf("A bulk operation may contain at most %v models", maxModels),
+			Public:  true,
+			Status:  400,
+		}
+	}
+
+	results := make([]*kit.BulkResult, len(objs))
+	_, inTransaction := res.backend.(db.Transaction)
+	_, canBegin := res.backend.(db.TransactionBackend)
+
+	for start := 0; start < len(objs); start += chunkSize {
+		end := start + chunkSize
+		if end > len(objs) {
+			end = len(objs)
+		}
+
+		if inTransaction || !canBegin {
+			if err := res.bulkSequential(objs[start:end], results[start:end], preload, r, op); err != nil && inTransaction {
+				return nil, err
+			}
+			continue
+		}
+		res.bulkChunk(objs[start:end], results[start:end], preload, r, op)
+	}
+
+	return results, nil
+}
+
+// bulkSequential runs the operation for a chunk of models without a
+// transaction of its own, so failed models can not be rolled back.
+// It stops at the first error if the resource runs inside a transaction.
+func (res *Resource) bulkSequential(objs []kit.Model, results []*kit.BulkResult, preload bool, r kit.Request, op bulkOperation) apperror.Error {
+	_, inTransaction := res.backend.(db.Transaction)
+
+	if preload {
+		scoped, err := res.withPreloadedModels(objs, r)
+		if err != nil {
+			for index, obj := range objs {
+				results[index] = &kit.BulkResult{Model: obj, Error: err}
+			}
+			return err
+		}
+		res = scoped
+	}
+
+	for index, obj := range objs {
+		model, err := op(res, obj)
+		if model == nil {
+			model = obj
+		}
+		if err != nil && inTransaction {
+			return err
+		}
+		results[index] = &kit.BulkResult{Model: model, Error: err}
+	}
+	return nil
+}
+
+// bulkChunk writes a chunk of models in one transaction and fills in their
+// results. The chunk is repeated without failed models until it succeeds.
+func (res *Resource) bulkChunk(objs []kit.Model, results []*kit.BulkResult, preload bool, r kit.Request, op bulkOperation) {
+	failed := make(map[int]apperror.Error)
+
+	for {
+		// Failed runs may have changed the models, like setting the id of
+		// a created model that was rolled back.
+		copies := copyModels(objs)
+
+		models := make([]kit.Model, len(objs))
+		failedIndex := -1
+		err := res.transaction(func(res *Resource) apperror.Error {
+			if preload {
+				scoped, err := res.withPreloadedModels(objs, r)
+				if err != nil {
+					return err
+				}
+				res = scoped
+			}
+
+			for index, obj := range objs {
+				if failed[index] != nil {
+					continue
+				}
+
+				model, err := op(res, obj)
+				if model == nil {
+					model = obj
+				}
+				models[index] = model
+				if err != nil {
+					failedIndex = index
+					return err
+				}
+			}
+			return nil
+		})
+
+		if err != nil && failedIndex != -1 {
+			failed[failedIndex] = err
+			restoreModels(objs, copies)
+			continue
+		}
+
+		for index, obj := range objs {
+			switch {
+			case failed[index] != nil:
+				results[index] = &kit.BulkResult{Model: obj, Error: failed[index]}
+			case err != nil:
+				// The transaction could not be started or committed.
+				results[index] = &kit.BulkResult{Model: obj, Error: err}
+			default:
+				results[index] = &kit.BulkResult{Model: models[index]}
+			}
+		}
+		return
+	}
+}
+
+// copyModels returns shallow copies of the models.
+func copyModels(objs []kit.Model) []reflect.Value {
+	copies := make([]reflect.Value, len(objs))
+	for index, obj := range objs {
+		value := reflect.ValueOf(obj).Elem()
+		copies[index] = reflect.New(value.Type()).Elem()
+		copies[index].Set(value)
+	}
+	return copies
+}
+
+// restoreModels restores the models from their copies.
+func restoreModels(objs []kit.Model, copies []reflect.Value) {
+	for index, obj := range objs {
+		reflect.ValueOf(obj).Elem().Set(copies[index])
+	}
+}
+
+// withPreloadedModels returns a copy of the resource that uses the stored
+// models with the ids of objs in updates instead of loading them one by one.
+// If r is set, only the models the row policy of the request allows are
+// loaded.
+func (res *Resource) withPreloadedModels(objs []kit.Model, r kit.Request) (*Resource, apperror.Error) {
+	ids := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		if id := obj.GetStrId(); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	preloaded := make(map[string]kit.Model)
+	if len(ids) > 0 {
+		idField := res.modelInfo.PkAttribute().BackendName()
+		query := res.Q().FilterExpr(expr.In("", idField, ids))
+		if r != nil {
+			res.applyRowPolicy(query, r)
+		}
+		stored, err := res.Query(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, model := range stored {
+			preloaded[model.GetStrId()] = model
+		}
+	}
+
+	scoped := *res
+	scoped.preloadedModels = preloaded
+	scoped.preloadedRequest = r
+	return &scoped, nil
+}
+
+func (res *Resource) BulkCreate(objs []kit.Model, user kit.User) ([]*kit.BulkResult, apperror.Error) {
+	return res.bulk(objs, false, nil, func(res *Resource, obj kit.Model) (kit.Model, apperror.Error) {
+		return obj, res.Create(obj, user)
+	})
+}
+
+func (res *Resource) BulkUpdate(objs []kit.Model, user kit.User) ([]*kit.BulkResult, apperror.Error) {
+	return res.bulk(objs, true, nil, func(res *Resource, obj kit.Model) (kit.Model, apperror.Error) {
+		return obj, res.Update(obj, user)
+	})
+}
+
+func (res *Resource) BulkDelete(objs []kit.Model, user kit.User) ([]*kit.BulkResult, apperror.Error) {
+	return res.bulk(objs, false, nil, func(res *Resource, obj kit.Model) (kit.Model, apperror.Error) {
+		return obj, res.Delete(obj, user)
+	})
+}
+
+// bulkResponse builds the response of a bulk operation.
+// The data contains the successful models, and the results in the meta
+// contain the id and error of every model.
+func (res *Resource) bulkResponse(results []*kit.BulkResult, user kit.User) kit.Response {
+	models := make([]kit.Model, 0)
+	infos := make([]map[string]interface{}, 0)
+
+	for index, result := range results {
+		info := map[string]interface{}{
+			"index": index,
+			"id":    result.Model.GetStrId(),
+		}
+		if lockModel, ok := result.Model.(kit.OptimisticLockModel); ok {
+			info["lock_token"] = lockModel.GetLockToken()
+		}
+		if result.Error != nil {
+			info["error"] = result.Error
+		} else {
+			models = append(models, result.Model)
+		}
+		infos = append(infos, info)
+	}
+
+	response := &kit.AppResponse{
+		Data: models,
+		Meta: map[string]interface{}{
+			"results": infos,
+			"failed":  len(results) - len(models),
+		},
+	}
+
+	return res.clearUnreadableFields(response, user)
+}
+
+// apiResult converts the response of an api hook to a bulk result.
+func apiResult(obj kit.Model, response kit.Response) (kit.Model, apperror.Error) {
+	if model, ok := response.GetData().(kit.Model); ok {
+		obj = model
+	}
+	return obj, response.GetError()
+}
+
+func (res *Resource) ApiBulkCreate(objs []kit.Model, r kit.Request) kit.Response {
+	user := r.GetUser()
+
+	results, err := res.bulk(objs, false, r, func(res *Resource, obj kit.Model) (kit.Model, apperror.Error) {
+		if err := res.checkWritableFields(obj, nil, user, false); err != nil {
+			return obj, err
+		}
+
+		if createHook, ok := res.hooks.(ApiCreateHook); ok {
+			return apiResult(obj, createHook.ApiCreate(res, obj, r))
+		}
+		return obj, res.Create(obj, user)
+	})
+	if err != nil {
+		return kit.NewErrorResponse(err)
+	}
+
+	return res.bulkResponse(results, user)
+}
+
+func (res *Resource) ApiBulkUpdate(objs []kit.Model, r kit.Request) kit.Response {
+	user := r.GetUser()
+
+	// The If-Match header can only match a single model. The lock token of
+	// every model is part of its own data, and checked by the update.
+	results, err := res.bulk(objs, true, r, func(res *Resource, obj kit.Model) (kit.Model, apperror.Error) {
+		if err := res.checkUpdatedFields(obj, r, false); err != nil {
+			return obj, err
+		}
+		if err := res.checkRowPolicy(obj, r); err != nil {
+			return obj, err
+		}
+
+		if updateHook, ok := res.hooks.(ApiUpdateHook); ok {
+			return apiResult(obj, updateHook.ApiUpdate(res, obj, r))
+		}
+		return obj, res.Update(obj, user)
+	})
+	if err != nil {
+		return kit.NewErrorResponse(err)
+	}
+
+	return res.bulkResponse(results, user)
+}
+
+// apiDeleteOne deletes the stored model with the id of obj.
+func (res *Resource) apiDeleteOne(obj kit.Model, r kit.Request) (kit.Model, apperror.Error) {
+	if deleteHook, ok := res.hooks.(ApiDeleteHook); ok {
+		return apiResult(obj, deleteHook.ApiDelete(res, obj.GetStrId(), r))
+	}
+
+	old, err := res.findOneForRequest(obj.GetId(), r, false)
+	if err != nil {
+		return obj, err
+	} else if old == nil {
+		return obj, apperror.New("not_found", "")
+	}
+
+	return old, res.Delete(old, r.GetUser())
+}
+
+func (res *Resource) ApiBulkDelete(ids []string, r kit.Request) kit.Response {
+	objs := make([]kit.Model, 0)
+	for _, id := range ids {
+		obj := res.CreateModel()
+		if err := obj.SetStrId(id); err != nil {
+			return kit.NewErrorResponse("invalid_id", err, true)
+		}
+		objs = append(objs, obj)
+	}
+
+	results, err := res.bulk(objs, false, r, func(res *Resource, obj kit.Model) (kit.Model, apperror.Error) {
+		return res.apiDeleteOne(obj, r)
+	})
+	if err != nil {
+		return kit.NewErrorResponse(err)
+	}
+
+	return res.bulkResponse(results, r.GetUser())
+}
+
+func (res *Resource) ApiDeleteByQuery(query *db.Query, r kit.Request) kit.Response {
+	if res.IsSoftDelete() {
+		excludeDeleted(query)
+	}
+	if alterQuery, ok := res.hooks.(ApiAlterQueryHook); ok {
+		if err := alterQuery.ApiAlterQuery(res, query, r); err != nil {
+			return kit.NewErrorResponse(err)
+		}
+	}
+	res.applyRowPolicy(query, r)
+
+	objs, err := res.Query(query)
+	if err != nil {
+		return kit.NewErrorResponse(err)
+	}
+
+	// Delete runs the AllowDelete hook for every model.
+	results, err := res.BulkDelete(objs, r.GetUser())
+	if err != nil {
+		return kit.NewErrorResponse(err)
+	}
+
+	return res.bulkResponse(results, r.GetUser())
+}
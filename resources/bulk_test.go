@@ -0,0 +1,115 @@
+package resources_test
+
+import (
+	"net/http"
+
+	"github.com/theduke/go-apperror"
+	db "github.com/theduke/go-dukedb"
+	"github.com/theduke/go-dukedb/backends/memory"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/resources"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type BulkItem struct {
+	db.IntIdModel
+
+	Name string `db:"max:100"`
+}
+
+func (BulkItem) Collection() string {
+	return "bulk_items"
+}
+
+type BulkItemHooks struct{}
+
+func (BulkItemHooks) BeforeCreate(res kit.Resource, obj kit.Model, user kit.User) apperror.Error {
+	if obj.(*BulkItem).Name == "invalid" {
+		return apperror.New("invalid_item", "The item is invalid", true)
+	}
+	return nil
+}
+
+var _ = Describe("Bulk operations", func() {
+	var res *Resource
+
+	BeforeEach(func() {
+		backend := memory.New()
+		res = NewResource(&BulkItem{}, BulkItemHooks{}, true)
+		res.SetBackend(backend)
+		backend.Build()
+	})
+
+	It("Should check the lock token of every model and ignore If-Match", func() {
+		backend := memory.New()
+		res := NewResource(&LockedNote{}, nil, true)
+		res.SetBackend(backend)
+		backend.Build()
+
+		first := &LockedNote{Title: "first"}
+		second := &LockedNote{Title: "second"}
+		Expect(res.Create(first, nil)).To(BeNil())
+		Expect(res.Create(second, nil)).To(BeNil())
+
+		httpRequest, _ := http.NewRequest("PATCH", "/api/locked-notes", nil)
+		httpRequest.Header.Set("If-Match", ETag(first))
+		r := kit.NewRequest()
+		r.SetHttpRequest(httpRequest)
+
+		stale := &LockedNote{Title: "stale"}
+		Expect(stale.SetStrId(second.GetStrId())).To(BeNil())
+		stale.LockVersion = second.LockVersion + 1
+		first.Title = "updated"
+
+		response := res.ApiBulkUpdate([]kit.Model{first, stale}, r)
+		Expect(response.GetError()).To(BeNil())
+
+		results := response.GetMeta()["results"].([]map[string]interface{})
+		Expect(results[0]).ToNot(HaveKey("error"))
+		Expect(results[0]["lock_token"]).To(Equal("2"))
+		Expect(results[1]["error"].(apperror.Error).GetCode()).To(Equal("conflict"))
+	})
+
+	It("Should write the other models if a model in the middle fails", func() {
+		objs := []kit.Model{
+			&BulkItem{Name: "first"},
+			&BulkItem{Name: "invalid"},
+			&BulkItem{Name: "last"},
+		}
+
+		results, err := res.BulkCreate(objs, nil)
+		Expect(err).To(BeNil())
+		Expect(results).To(HaveLen(3))
+
+		Expect(results[0].Error).To(BeNil())
+		Expect(results[1].Error).ToNot(BeNil())
+		Expect(results[1].Error.GetCode()).To(Equal("invalid_item"))
+		Expect(results[2].Error).To(BeNil())
+
+		count, err := res.Count(res.Q())
+		Expect(err).To(BeNil())
+		Expect(count).To(Equal(2))
+
+		stored, err := res.Query(res.Q().Filter("name", "invalid"))
+		Expect(err).To(BeNil())
+		Expect(stored).To(BeEmpty())
+	})
+
+	It("Should reject too many models", func() {
+		objs := make([]kit.Model, 1001)
+		for index := range objs {
+			objs[index] = &BulkItem{Name: "item"}
+		}
+
+		_, err := res.BulkCreate(objs, nil)
+		Expect(err).ToNot(BeNil())
+		Expect(err.GetCode()).To(Equal("too_many_models"))
+
+		count, err := res.Count(res.Q())
+		Expect(err).To(BeNil())
+		Expect(count).To(Equal(0))
+	})
+})
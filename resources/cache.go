@@ -0,0 +1,458 @@
+package resources
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/theduke/go-apperror"
+	db "github.com/theduke/go-dukedb"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// cacheStats is shared by all copies of a resource, like the copies used in
+// transactions.
+type cacheStats struct {
+	hits   int64
+	misses int64
+}
+
+func (res *Resource) Cache() *kit.ResourceCache {
+	return res.cache
+}
+
+func (res *Resource) SetCache(cache *kit.ResourceCache) {
+	res.cache = cache
+	if res.cacheStats == nil {
+		res.cacheStats = &cacheStats{}
+	}
+}
+
+func (res *Resource) CacheStats() kit.CacheStats {
+	if res.cacheStats == nil {
+		return kit.CacheStats{}
+	}
+	return kit.CacheStats{
+		Hits:   atomic.LoadInt64(&res.cacheStats.hits),
+		Misses: atomic.LoadInt64(&res.cacheStats.misses),
+	}
+}
+
+// CollectionCacheTag returns the tag of all cached query results of a
+// collection.
+func CollectionCacheTag(collection string) string {
+	return "resources." + collection
+}
+
+// ModelCacheTag returns the tag of all cached results that contain a model.
+func ModelCacheTag(collection, id string) string {
+	return "resources." + collection + "." + id
+}
+
+var (
+	hiddenFieldsCache     = make(map[reflect.Type]bool)
+	hiddenFieldsCacheLock sync.RWMutex
+)
+
+// hasHiddenFields returns true if the struct has unexported data fields,
+// which the cache encoding would lose.
+// Fields of embedded structs are checked as well.
+func hasHiddenFields(typ reflect.Type) bool {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	hiddenFieldsCacheLock.RLock()
+	hidden, ok := hiddenFieldsCache[typ]
+	hiddenFieldsCacheLock.RUnlock()
+	if ok {
+		return hidden
+	}
+
+	hidden = false
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if hasHiddenFields(field.Type) {
+				hidden = true
+				break
+			}
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.Func, reflect.Chan:
+			continue
+		}
+		if field.PkgPath != "" {
+			hidden = true
+			break
+		}
+	}
+
+	hiddenFieldsCacheLock.Lock()
+	hiddenFieldsCache[typ] = hidden
+	hiddenFieldsCacheLock.Unlock()
+
+	return hidden
+}
+
+// resultCache returns the cache of the resource, or nil if caching is
+// disabled or the cache does not exist.
+// Models with unexported fields are not cached, since their values would be
+// lost.
+func (res *Resource) resultCache() kit.Cache {
+	if res.cache == nil || res.registry == nil {
+		return nil
+	}
+	if hasHiddenFields(reflect.TypeOf(res.model)) {
+		return nil
+	}
+	if res.cache.Cache != "" {
+		return res.registry.Cache(res.cache.Cache)
+	}
+	return res.registry.DefaultCache()
+}
+
+// readCache returns the cache to read results from.
+// Inside transactions, uncommitted results must not be cached, so nil is
+// returned.
+func (res *Resource) readCache() kit.Cache {
+	if _, ok := res.backend.(db.Transaction); ok {
+		return nil
+	}
+	return res.resultCache()
+}
+
+// clearCache clears the cached results affected by a write of the model.
+func (res *Resource) clearCache(obj kit.Model) {
+	cache := res.resultCache()
+	if cache == nil {
+		return
+	}
+
+	tags := []string{CollectionCacheTag(res.Collection())}
+	if id := obj.GetStrId(); id != "" {
+		tags = append(tags, ModelCacheTag(res.Collection(), id))
+	}
+
+	for _, tag := range tags {
+		if err := cache.ClearTag(tag); err != nil && res.registry.Logger() != nil {
+			res.registry.Logger().Errorf("Could not clear cache tag %v: %v", tag, err)
+		}
+	}
+}
+
+// mapKeys sorts map keys by their string representation.
+type mapKeys []reflect.Value
+
+func (k mapKeys) Len() int           { return len(k) }
+func (k mapKeys) Swap(i, j int)      { k[i], k[j] = k[j], k[i] }
+func (k mapKeys) Less(i, j int) bool { return fmt.Sprint(k[i]) < fmt.Sprint(k[j]) }
+
+// writeCacheKey writes a deterministic representation of a value, including
+// unexported fields, to the hash.
+// Backends, functions and channels are skipped.
+func writeCacheKey(hash func(string), v reflect.Value, visited map[uintptr]bool) {
+	if !v.IsValid() {
+		hash("nil")
+		return
+	}
+
+	backendType := reflect.TypeOf((*db.Backend)(nil)).Elem()
+
+	switch v.Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return
+
+	case reflect.Interface:
+		if v.IsNil() {
+			hash("nil")
+			return
+		}
+		writeCacheKey(hash, v.Elem(), visited)
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			hash("nil")
+			return
+		}
+		if v.Type().Implements(backendType) || visited[v.Pointer()] {
+			return
+		}
+		visited[v.Pointer()] = true
+		writeCacheKey(hash, v.Elem(), visited)
+		delete(visited, v.Pointer())
+
+	case reflect.Struct:
+		if v.Type().Implements(backendType) {
+			return
+		}
+		hash(v.Type().String() + "{")
+		for i := 0; i < v.NumField(); i++ {
+			hash(v.Type().Field(i).Name + ":")
+			writeCacheKey(hash, v.Field(i), visited)
+		}
+		hash("}")
+
+	case reflect.Slice, reflect.Array:
+		hash("[")
+		for i := 0; i < v.Len(); i++ {
+			writeCacheKey(hash, v.Index(i), visited)
+			hash(",")
+		}
+		hash("]")
+
+	case reflect.Map:
+		keys := mapKeys(v.MapKeys())
+		sort.Sort(keys)
+		hash("{")
+		for _, key := range keys {
+			writeCacheKey(hash, key, visited)
+			hash(":")
+			writeCacheKey(hash, v.MapIndex(key), visited)
+			hash(",")
+		}
+		hash("}")
+
+	case reflect.String:
+		hash(fmt.Sprintf("%q", v.String()))
+	case reflect.Bool:
+		hash(fmt.Sprint(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		hash(fmt.Sprint(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		hash(fmt.Sprint(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		hash(fmt.Sprint(v.Float()))
+	default:
+		hash(v.Kind().String())
+	}
+}
+
+// cacheKey builds the cache key of a query from its select statement and
+// the additional values, like the permission scope of the user.
+// The state of the query object itself, like its backend, is not part of
+// the key.
+func (res *Resource) cacheKey(kind string, query *db.Query, values ...interface{}) string {
+	sum := sha1.New()
+	hash := func(s string) {
+		sum.Write([]byte(s))
+	}
+
+	visited := make(map[uintptr]bool)
+	writeCacheKey(hash, reflect.ValueOf(query.GetStatement()), visited)
+	for _, value := range values {
+		writeCacheKey(hash, reflect.ValueOf(value), visited)
+	}
+
+	return fmt.Sprintf("resources.%v.%v.%v", res.Collection(), kind, hex.EncodeToString(sum.Sum(nil)))
+}
+
+// userScope returns the permission scope of a user for cache keys.
+func userScope(user kit.User) string {
+	if user == nil {
+		return "anonymous"
+	}
+	return "user:" + user.GetStrId()
+}
+
+// queryCacheTags returns the tags of a query result: the tag of the
+// collection, and the tags of the collections of all joins, so that writes to
+// joined models clear the result as well.
+// Only writes through resources with the same cache clear its tags, so if a
+// joined collection is not known or not cached in the same cache, the result
+// can not be cleared reliably and false is returned.
+func (res *Resource) queryCacheTags(query *db.Query) ([]string, bool) {
+	tags := []string{CollectionCacheTag(res.Collection())}
+
+	var addJoins func(joins []*db.RelationQuery) bool
+	addJoins = func(joins []*db.RelationQuery) bool {
+		for _, join := range joins {
+			collection := join.GetCollection()
+			if collection == "" || res.registry == nil || res.cache == nil {
+				return false
+			}
+			related := res.registry.Resource(collection)
+			if related == nil || related.Cache() == nil || related.Cache().Cache != res.cache.Cache {
+				return false
+			}
+			tags = append(tags, CollectionCacheTag(collection))
+			if !addJoins(join.GetJoins()) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !addJoins(query.GetJoins()) {
+		return nil, false
+	}
+	return tags, true
+}
+
+// cachedResult holds the gob encoded models, which keeps all exported
+// fields, including those hidden from json.
+type cachedResult struct {
+	Models []byte                 `json:"models"`
+	Meta   map[string]interface{} `json:"meta,omitempty"`
+}
+
+// encodeModels gob encodes models as a slice of the model type of the
+// resource.
+func (res *Resource) encodeModels(models []kit.Model) ([]byte, error) {
+	slice := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(res.model)), 0, len(models))
+	for _, model := range models {
+		slice = reflect.Append(slice, reflect.ValueOf(model))
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(slice.Interface()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (res *Resource) decodeModels(data []byte) ([]kit.Model, error) {
+	models := reflect.New(reflect.SliceOf(reflect.TypeOf(res.model)))
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(models.Interface()); err != nil {
+		return nil, err
+	}
+
+	slice := models.Elem()
+	items := make([]kit.Model, 0, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		items = append(items, slice.Index(i).Interface().(kit.Model))
+	}
+	return items, nil
+}
+
+// cached returns the cached models and meta for the key, or false.
+func (res *Resource) cached(key string) ([]kit.Model, map[string]interface{}, bool) {
+	cache := res.readCache()
+	if cache == nil {
+		return nil, nil, false
+	}
+
+	value, err := cache.GetString(key)
+	if err == nil && value != "" {
+		var result cachedResult
+		if err := json.Unmarshal([]byte(value), &result); err == nil {
+			if items, err := res.decodeModels(result.Models); err == nil {
+				atomic.AddInt64(&res.cacheStats.hits, 1)
+				return items, restoreInts(result.Meta), true
+			}
+		}
+	}
+
+	atomic.AddInt64(&res.cacheStats.misses, 1)
+	return nil, nil, false
+}
+
+// restoreInts converts whole numbers in json decoded metadata back to
+// integers.
+func restoreInts(meta map[string]interface{}) map[string]interface{} {
+	for key, val := range meta {
+		switch v := val.(type) {
+		case float64:
+			if v == math.Trunc(v) {
+				meta[key] = int(v)
+			}
+		case map[string]interface{}:
+			meta[key] = restoreInts(v)
+		}
+	}
+	return meta
+}
+
+// store caches models and meta under the key, tagged with the tags and the
+// ids of the models.
+func (res *Resource) store(key string, models []kit.Model, meta map[string]interface{}, tags []string) {
+	cache := res.readCache()
+	if cache == nil {
+		return
+	}
+
+	encoded, err := res.encodeModels(models)
+	if err != nil {
+		if res.registry.Logger() != nil {
+			res.registry.Logger().Errorf("Could not encode query result %v: %v", key, err)
+		}
+		return
+	}
+	value, err := json.Marshal(cachedResult{Models: encoded, Meta: meta})
+	if err != nil {
+		return
+	}
+
+	tags = append([]string{}, tags...)
+	for _, model := range models {
+		tags = append(tags, ModelCacheTag(res.Collection(), model.GetStrId()))
+	}
+
+	var expiresAt *time.Time
+	if res.cache.TTL > 0 {
+		t := time.Now().Add(res.cache.TTL)
+		expiresAt = &t
+	}
+
+	if err := cache.SetString(key, string(value), expiresAt, tags); err != nil && res.registry.Logger() != nil {
+		res.registry.Logger().Errorf("Could not cache query result %v: %v", key, err)
+	}
+}
+
+// cachedQuery runs the query, or returns the cached results.
+// Queries with joins of unknown collections are not cached.
+func (res *Resource) cachedQuery(q *db.Query) ([]kit.Model, apperror.Error) {
+	tags, cacheable := res.queryCacheTags(q)
+	key := ""
+	if cacheable {
+		key = res.cacheKey("query", q)
+		if models, _, ok := res.cached(key); ok {
+			return models, nil
+		}
+	}
+
+	items, err := res.backend.Query(q)
+	if err != nil {
+		return nil, err
+	}
+
+	models := make([]kit.Model, 0, len(items))
+	for _, item := range items {
+		models = append(models, item.(kit.Model))
+	}
+	if cacheable {
+		res.store(key, models, nil, tags)
+	}
+
+	return models, nil
+}
+
+// cachedFindOne finds a model by id, or returns the cached model.
+// Cached models are only cleared by writes to the model itself.
+func (res *Resource) cachedFindOne(rawId interface{}) (kit.Model, apperror.Error) {
+	key := fmt.Sprintf("resources.%v.id.%v", res.Collection(), rawId)
+	if models, _, ok := res.cached(key); ok && len(models) == 1 {
+		return models[0], nil
+	}
+
+	item, err := res.backend.FindOne(res.model.Collection(), rawId)
+	if err != nil || item == nil {
+		return nil, err
+	}
+
+	model := item.(kit.Model)
+	res.store(key, []kit.Model{model}, nil, nil)
+	return model, nil
+}
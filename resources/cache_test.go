@@ -0,0 +1,93 @@
+package resources_test
+
+import (
+	"os"
+	"path"
+
+	db "github.com/theduke/go-dukedb"
+	"github.com/theduke/go-dukedb/backends/memory"
+
+	kit "github.com/app-kit/go-appkit"
+	"github.com/app-kit/go-appkit/app"
+	"github.com/app-kit/go-appkit/caches/fs"
+	. "github.com/app-kit/go-appkit/resources"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Account struct {
+	db.IntIdModel
+
+	Name   string `db:"max:100"`
+	Secret string `db:"max:100" json:"-"`
+}
+
+func (Account) Collection() string {
+	return "accounts"
+}
+
+type Session struct {
+	db.IntIdModel
+
+	Name  string `db:"max:100"`
+	token string
+}
+
+func (Session) Collection() string {
+	return "sessions"
+}
+
+var _ = Describe("Query cache", func() {
+	var registry kit.Registry
+	var backend db.Backend
+
+	BeforeEach(func() {
+		tmpDir := path.Join(os.TempDir(), "appkit_resources_cache_test")
+		os.RemoveAll(tmpDir)
+		cache, err := fs.New(tmpDir)
+		Expect(err).To(BeNil())
+
+		registry = app.NewRegistry()
+		registry.SetDefaultCache(cache)
+
+		backend = memory.New()
+	})
+
+	buildResource := func(model kit.Model) *Resource {
+		res := NewResource(model, nil, true)
+		res.SetRegistry(registry)
+		res.SetBackend(backend)
+		res.SetCache(&kit.ResourceCache{})
+		backend.Build()
+		return res
+	}
+
+	It("Should keep fields hidden from json", func() {
+		res := buildResource(&Account{})
+
+		account := &Account{Name: "name", Secret: "secret"}
+		Expect(res.Create(account, nil)).To(BeNil())
+
+		_, err := res.FindOne(account.GetId())
+		Expect(err).To(BeNil())
+
+		cached, err := res.FindOne(account.GetId())
+		Expect(err).To(BeNil())
+		Expect(res.CacheStats().Hits).To(Equal(int64(1)))
+		Expect(cached.(*Account).Secret).To(Equal("secret"))
+	})
+
+	It("Should not cache models with unexported fields", func() {
+		res := buildResource(&Session{})
+
+		session := &Session{Name: "name"}
+		Expect(res.Create(session, nil)).To(BeNil())
+
+		for i := 0; i < 2; i++ {
+			_, err := res.FindOne(session.GetId())
+			Expect(err).To(BeNil())
+		}
+		Expect(res.CacheStats()).To(Equal(kit.CacheStats{}))
+	})
+})
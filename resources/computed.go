@@ -0,0 +1,144 @@
+package resources
+
+import (
+	"strings"
+
+	"github.com/theduke/go-apperror"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// ComputedField is a read-only field that is added to the serialized models
+// of a resource.
+type ComputedField struct {
+	// Name is the attribute name in the serialized model.
+	Name string
+
+	// Func computes the value for a single model.
+	Func func(res kit.Resource, model kit.Model, r kit.Request) interface{}
+
+	// Batch computes the values for all models of a response at once, to
+	// avoid a query for every model. The values are keyed by the model id.
+	// If set, Func is not used.
+	Batch func(res kit.Resource, models []kit.Model, r kit.Request) (map[string]interface{}, apperror.Error)
+
+	// Read restricts who may read the field.
+	Read *FieldAccess
+}
+
+func (res *Resource) computedFields() []*ComputedField {
+	if hook, ok := res.hooks.(ComputedFieldsHook); ok {
+		return hook.ComputedFields(res)
+	}
+	return nil
+}
+
+// ComputedFieldNames returns the names of the computed fields.
+func (res *Resource) ComputedFieldNames() []string {
+	names := make([]string, 0)
+	for _, field := range res.computedFields() {
+		names = append(names, field.Name)
+	}
+	return names
+}
+
+// UnreadableComputedFields returns the names of the computed fields of the
+// model the user may not read.
+func (res *Resource) UnreadableComputedFields(model kit.Model, user kit.User) []string {
+	names := make([]string, 0)
+	for _, field := range res.computedFields() {
+		if !field.Read.allows(res, model, user, false) {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
+
+// requestedFields returns the sparse fieldset of the collection in the
+// fields[collection] parameter, or nil if all fields were requested.
+func requestedFields(collection string, r kit.Request) map[string]bool {
+	param := r.GetContext().String("fields[" + collection + "]")
+	if param == "" {
+		return nil
+	}
+
+	fields := make(map[string]bool)
+	for _, name := range strings.Split(param, ",") {
+		fields[strings.TrimSpace(name)] = true
+	}
+	return fields
+}
+
+// addComputedFields computes the fields for the models of a response and
+// stores them in the computed meta, which serializers add to the
+// attributes: {"collection": {"id": {"field": value}}}.
+func (res *Resource) addComputedFields(response kit.Response, r kit.Request) kit.Response {
+	fields := res.computedFields()
+	if response.GetError() != nil || len(fields) == 0 {
+		return response
+	}
+
+	var models []kit.Model
+	switch data := response.GetData().(type) {
+	case kit.Model:
+		models = []kit.Model{data}
+	case []kit.Model:
+		models = data
+	}
+	if len(models) == 0 {
+		return response
+	}
+
+	user := r.GetUser()
+	requested := requestedFields(res.Collection(), r)
+
+	values := make(map[string]map[string]interface{})
+	for _, model := range models {
+		values[model.GetStrId()] = make(map[string]interface{})
+	}
+
+	for _, field := range fields {
+		if requested != nil && !requested[field.Name] {
+			continue
+		}
+
+		readable := make([]kit.Model, 0)
+		for _, model := range models {
+			if field.Read.allows(res, model, user, false) {
+				readable = append(readable, model)
+			}
+		}
+		if len(readable) == 0 {
+			continue
+		}
+
+		if field.Batch != nil {
+			batch, err := field.Batch(res, readable, r)
+			if err != nil {
+				return kit.NewErrorResponse(apperror.Wrap(err, "computed_field_error", ""))
+			}
+			for _, model := range readable {
+				values[model.GetStrId()][field.Name] = batch[model.GetStrId()]
+			}
+		} else if field.Func != nil {
+			for _, model := range readable {
+				values[model.GetStrId()][field.Name] = field.Func(res, model, r)
+			}
+		}
+	}
+
+	meta := response.GetMeta()
+	if meta == nil {
+		meta = make(map[string]interface{})
+	}
+
+	computed, _ := meta["computed"].(map[string]map[string]map[string]interface{})
+	if computed == nil {
+		computed = make(map[string]map[string]map[string]interface{})
+	}
+	computed[res.Collection()] = values
+	meta["computed"] = computed
+	response.SetMeta(meta)
+
+	return response
+}
@@ -0,0 +1,370 @@
+package resources
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/theduke/go-apperror"
+	db "github.com/theduke/go-dukedb"
+	"github.com/theduke/go-reflector"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// ImportTaskName is the name of the task that imports csv and xlsx files.
+const ImportTaskName = "resources.import"
+
+// ImportRowError describes why a row of an import failed.
+type ImportRowError struct {
+	// Row is the line number of the row, counting the header as line 1.
+	Row     int    `json:"row"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	// Fields lists the invalid fields of validation errors.
+	Fields []string `json:"fields,omitempty"`
+}
+
+// ImportResult summarizes an import.
+type ImportResult struct {
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Failed  int               `json:"failed"`
+	Errors  []*ImportRowError `json:"errors"`
+}
+
+func (res *Resource) Csv() *kit.ResourceCsv {
+	return res.csv
+}
+
+func (res *Resource) SetCsv(csv *kit.ResourceCsv) {
+	res.csv = csv
+}
+
+// CsvColumns returns the exported columns of a resource: the configured
+// columns, or the id, the attributes and the computed fields.
+func CsvColumns(res kit.Resource) []string {
+	if res.Csv() != nil && len(res.Csv().Columns) > 0 {
+		return res.Csv().Columns
+	}
+
+	info := res.ModelInfo()
+	pk := info.PkAttribute()
+
+	names := make([]string, 0)
+	for _, attr := range info.Attributes() {
+		if attr != pk {
+			names = append(names, attr.MarshalName())
+		}
+	}
+	sort.Strings(names)
+
+	columns := append([]string{"id"}, names...)
+	return append(columns, res.ComputedFieldNames()...)
+}
+
+// csvValue formats a value for a csv cell.
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct, reflect.Ptr:
+		js, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprintf("%v", value)
+		}
+		return string(js)
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+// CsvRecord returns the cells of a model for the columns. computed contains
+// the computed fields of the model.
+func CsvRecord(res kit.Resource, model kit.Model, columns []string, computed map[string]interface{}) ([]string, apperror.Error) {
+	data, err := res.Backend().ModelToMap(model, true, false)
+	if err != nil {
+		return nil, apperror.Wrap(err, "model_convert_error", "")
+	}
+	data["id"] = model.GetStrId()
+	for name, value := range computed {
+		data[name] = value
+	}
+
+	record := make([]string, 0, len(columns))
+	for _, column := range columns {
+		record = append(record, csvValue(data[column]))
+	}
+	return record, nil
+}
+
+// NewCsvReader returns a reader for csv data as written by spreadsheet
+// applications. A byte order mark is skipped, and the delimiter is detected
+// from the header: commas, semicolons and tabs are supported.
+func NewCsvReader(r io.Reader) *csv.Reader {
+	buffered := bufio.NewReaderSize(r, 64*1024)
+	if bom, _ := buffered.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		buffered.Discard(3)
+	}
+
+	// Peek returns the buffered data along with ErrBufferFull or EOF.
+	header, _ := buffered.Peek(buffered.Size())
+	if index := bytes.IndexByte(header, '\n'); index > -1 {
+		header = header[:index]
+	}
+
+	delimiter := ','
+	count := bytes.Count(header, []byte(","))
+	for _, candidate := range []rune{';', '\t'} {
+		if n := bytes.Count(header, []byte(string(candidate))); n > count {
+			delimiter = candidate
+			count = n
+		}
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader
+}
+
+func invalidCsv(err error) apperror.Error {
+	return apperror.Wrap(err, "invalid_csv", "The csv data is invalid", true)
+}
+
+// csvAttributes maps the header columns to attributes by their marshal,
+// backend or struct name. Unknown columns are mapped to nil and ignored.
+func csvAttributes(info *db.ModelInfo, header []string) []*db.Attribute {
+	attrs := make([]*db.Attribute, len(header))
+	for index, column := range header {
+		column = strings.TrimSpace(column)
+		if column == "id" {
+			attrs[index] = info.PkAttribute()
+			continue
+		}
+		for _, attr := range info.Attributes() {
+			if attr.MarshalName() == column || attr.BackendName() == column || attr.Name() == column {
+				attrs[index] = attr
+				break
+			}
+		}
+	}
+	return attrs
+}
+
+// convertCsvValue converts a cell to the type of an attribute.
+func convertCsvValue(value string, typ reflect.Type) (interface{}, error) {
+	if typ == reflect.TypeOf(time.Time{}) {
+		return time.Parse(time.RFC3339, value)
+	}
+	return reflector.R(value).ConvertTo(typ)
+}
+
+func invalidCsvValue(attr *db.Attribute) apperror.Error {
+	return kit.NewFieldError(attr.MarshalName(), "type", fmt.Sprintf("%v has an invalid value", attr.MarshalName()))
+}
+
+// importRow creates or, if the row contains the id of an existing model,
+// updates a model from a row with the api methods of the resource.
+// Empty cells are skipped.
+func importRow(res kit.Resource, attrs []*db.Attribute, record []string, r kit.Request) (bool, apperror.Error) {
+	pk := res.ModelInfo().PkAttribute()
+
+	model := res.CreateModel()
+	created := true
+	for index, attr := range attrs {
+		if attr == pk && index < len(record) && record[index] != "" {
+			response := res.ApiFindOne(record[index], r)
+			if err := response.GetError(); err != nil && err.GetCode() != "not_found" {
+				return false, err
+			} else if err == nil {
+				// Existing models are updated partially with the set cells.
+				if err := model.SetStrId(record[index]); err != nil {
+					return false, apperror.Wrap(err, "invalid_id", "", true)
+				}
+				created = false
+			}
+		}
+	}
+
+	fields := reflect.ValueOf(model).Elem()
+	for index, attr := range attrs {
+		if attr == nil || attr == pk || index >= len(record) || record[index] == "" {
+			continue
+		}
+
+		field := fields.FieldByName(attr.Name())
+		value, err := convertCsvValue(record[index], attr.Type())
+		if err != nil || value == nil || !reflect.TypeOf(value).AssignableTo(field.Type()) {
+			return false, invalidCsvValue(attr)
+		}
+		field.Set(reflect.ValueOf(value))
+	}
+
+	if created {
+		return true, res.ApiCreate(model, r).GetError()
+	}
+	return false, res.ApiPartialUpdate(model, r).GetError()
+}
+
+// rowError converts the error of a row.
+func rowError(row int, err apperror.Error) *ImportRowError {
+	rowErr := &ImportRowError{
+		Row:     row,
+		Code:    err.GetCode(),
+		Message: err.GetMessage(),
+	}
+	if !err.IsPublic() {
+		rowErr.Message = ""
+	}
+
+	if fieldErr, ok := err.(*kit.FieldError); ok {
+		rowErr.Fields = append(rowErr.Fields, fieldErr.Field)
+	}
+	for _, e := range err.GetErrors() {
+		if fieldErr, ok := e.(*kit.FieldError); ok {
+			rowErr.Fields = append(rowErr.Fields, fieldErr.Field)
+		}
+	}
+
+	return rowErr
+}
+
+// RowReader reads the rows of an import, like csv.Reader.
+// Read returns io.EOF after the last row.
+type RowReader interface {
+	Read() ([]string, error)
+}
+
+// sliceRows reads rows from a slice.
+type sliceRows struct {
+	rows [][]string
+}
+
+func (r *sliceRows) Read() ([]string, error) {
+	if len(r.rows) == 0 {
+		return nil, io.EOF
+	}
+	row := r.rows[0]
+	r.rows = r.rows[1:]
+	return row, nil
+}
+
+// isEmptyRow returns true for rows of empty lines.
+func isEmptyRow(record []string) bool {
+	return len(record) == 1 && strings.TrimSpace(record[0]) == ""
+}
+
+// ImportRows creates or updates a model for every row after the header.
+// total is the number of rows after the header, and is used for the
+// progress.
+// The rows run through the api methods of the resource for the request,
+// including the permission checks and the validation. Failed rows are
+// reported and do not stop the import.
+// progress is called after every row.
+func ImportRows(res kit.Resource, rows RowReader, total int, r kit.Request, progress func(done, total int)) (*ImportResult, apperror.Error) {
+	header, err := rows.Read()
+	if err == io.EOF {
+		return nil, apperror.New("invalid_import", "The file has no header", true)
+	} else if err != nil {
+		return nil, invalidCsv(err)
+	}
+
+	attrs := csvAttributes(res.ModelInfo(), header)
+
+	result := &ImportResult{
+		Total:  total,
+		Errors: make([]*ImportRowError, 0),
+	}
+
+	for index := 0; ; index++ {
+		record, err := rows.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, invalidCsv(err)
+		}
+
+		// Skip empty lines.
+		if isEmptyRow(record) {
+			result.Total--
+			continue
+		}
+
+		created, err2 := importRow(res, attrs, record, r)
+		if err2 != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, rowError(index+2, err2))
+		} else if created {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+
+		if progress != nil && total > 0 {
+			progress(index+1, total)
+		}
+	}
+
+	return result, nil
+}
+
+// ImportCsv imports the rows of a csv file with ImportRows.
+// The file is read twice, first to count the rows, so that the data does
+// not have to be kept in memory.
+func ImportCsv(res kit.Resource, file io.ReadSeeker, r kit.Request, progress func(done, total int)) (*ImportResult, apperror.Error) {
+	reader := NewCsvReader(file)
+	total := -1
+	for {
+		if _, err := reader.Read(); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, invalidCsv(err)
+		}
+		total++
+	}
+	if total < 0 {
+		return nil, apperror.New("invalid_csv", "The csv data has no header", true)
+	}
+
+	if _, err := file.Seek(0, 0); err != nil {
+		return nil, apperror.Wrap(err, "file_read_error", "")
+	}
+
+	return ImportRows(res, NewCsvReader(file), total, r, progress)
+}
+
+// ImportXlsx imports the rows of the first sheet of an xlsx file with
+// ImportRows.
+func ImportXlsx(res kit.Resource, file io.ReaderAt, size int64, r kit.Request, progress func(done, total int)) (*ImportResult, apperror.Error) {
+	rows, err := ReadXlsx(file, size)
+	if err != nil {
+		return nil, err
+	}
+	return ImportRows(res, &sliceRows{rows: rows}, len(rows)-1, r, progress)
+}
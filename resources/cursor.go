@@ -0,0 +1,266 @@
+package resources
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/theduke/go-apperror"
+	db "github.com/theduke/go-dukedb"
+	expr "github.com/theduke/go-dukedb/expressions"
+	"github.com/theduke/go-reflector"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// CursorPage describes a page of a query with cursor pagination.
+// The models are sorted by the order fields and the primary key, and the
+// cursors contain the values of these fields of the first or last model of a
+// page.
+type CursorPage struct {
+	After  string
+	Before string
+	Size   int
+
+	// Order lists the fields to sort by, prefixed with - for descending
+	// order.
+	Order []string
+}
+
+type sortField struct {
+	attr      *db.Attribute
+	ascending bool
+}
+
+// CursorPageFromRequest builds the cursor page from the page[after],
+// page[before], page[size] and order parameters of the request context.
+// Returns nil if the request does not use cursor pagination.
+func CursorPageFromRequest(r kit.Request) (*CursorPage, apperror.Error) {
+	ctx := r.GetContext()
+	if !(ctx.Has("page[after]") || ctx.Has("page[before]") || ctx.Has("page[size]")) {
+		return nil, nil
+	}
+
+	page := &CursorPage{
+		After:  ctx.String("page[after]"),
+		Before: ctx.String("page[before]"),
+	}
+	if page.After != "" && page.Before != "" {
+		return nil, apperror.New("invalid_page", "page[after] and page[before] can not be combined", true)
+	}
+
+	if ctx.Has("page[size]") {
+		size, err := ctx.Int("page[size]")
+		if err != nil || size < 1 {
+			return nil, apperror.New("invalid_page_size", "page[size] must be a positive number", true)
+		}
+		page.Size = size
+	}
+
+	rawOrder, _ := ctx.Get("order")
+	switch order := rawOrder.(type) {
+	case string:
+		if order != "" {
+			page.Order = strings.Split(order, ",")
+		}
+	case []string:
+		page.Order = order
+	case []interface{}:
+		for _, field := range order {
+			page.Order = append(page.Order, fmt.Sprintf("%v", field))
+		}
+	}
+
+	return page, nil
+}
+
+// sortFields returns the fields the page is sorted by, ending with the
+// primary key to make the order unique.
+func (res *Resource) sortFields(page *CursorPage) ([]*sortField, apperror.Error) {
+	fields := make([]*sortField, 0)
+	pk := res.modelInfo.PkAttribute()
+	hasPk := false
+
+	for _, name := range page.Order {
+		name = strings.TrimSpace(name)
+		ascending := true
+		if strings.HasPrefix(name, "-") {
+			ascending = false
+			name = name[1:]
+		}
+
+		attr := res.modelInfo.FindAttribute(name)
+		if attr == nil {
+			return nil, &apperror.Err{
+				Code:    "invalid_order_field",
+				Message: fmt.Sprintf("Can not order by the unknown field %v", name),
+				Public:  true,
+			}
+		}
+
+		fields = append(fields, &sortField{attr: attr, ascending: ascending})
+		if attr == pk {
+			hasPk = true
+		}
+	}
+
+	if !hasPk {
+		fields = append(fields, &sortField{attr: pk, ascending: true})
+	}
+
+	return fields, nil
+}
+
+func encodeCursor(fields []*sortField, model kit.Model) string {
+	r := reflector.R(model).MustStruct()
+
+	values := make([]interface{}, 0)
+	for _, field := range fields {
+		values = append(values, r.Field(field.attr.Name()).Interface())
+	}
+
+	js, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func decodeCursor(fields []*sortField, cursor string) ([]interface{}, apperror.Error) {
+	invalid := apperror.New("invalid_cursor", "The cursor is invalid", true)
+
+	js, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+
+	var rawValues []interface{}
+	if err := json.Unmarshal(js, &rawValues); err != nil || len(rawValues) != len(fields) {
+		return nil, invalid
+	}
+
+	values := make([]interface{}, 0)
+	for index, field := range fields {
+		typ := field.attr.Type()
+
+		var value interface{}
+		if typ == reflect.TypeOf(time.Time{}) {
+			str, _ := rawValues[index].(string)
+			t, err := time.Parse(time.RFC3339Nano, str)
+			if err != nil {
+				return nil, invalid
+			}
+			value = t
+		} else {
+			converted, err := reflector.R(rawValues[index]).ConvertTo(typ)
+			if err != nil {
+				return nil, invalid
+			}
+			value = converted
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// applyCursor sorts the query and restricts it to the models after or
+// before the cursor.
+// For pages before a cursor, the order is reversed, and the result has to be
+// reversed again.
+func (res *Resource) applyCursor(query *db.Query, page *CursorPage, fields []*sortField) apperror.Error {
+	backwards := page.Before != ""
+
+	for _, field := range fields {
+		query.Order(field.attr.BackendName(), field.ascending != backwards)
+	}
+
+	size := page.Size
+	if size == 0 {
+		size = query.GetLimit()
+	}
+	if size == 0 {
+		size = 20
+	}
+	page.Size = size
+
+	// Fetch one more model to know if there is another page.
+	query.Limit(size + 1).Offset(0)
+
+	cursor := page.After
+	if backwards {
+		cursor = page.Before
+	}
+	if cursor == "" {
+		return nil
+	}
+
+	values, err := decodeCursor(fields, cursor)
+	if err != nil {
+		return err
+	}
+
+	// Keyset condition: (a > va) OR (a = va AND b > vb) OR ...
+	conditions := make([]expr.Expression, 0)
+	for index, field := range fields {
+		parts := make([]expr.Expression, 0)
+		for i := 0; i < index; i++ {
+			parts = append(parts, expr.Eq("", fields[i].attr.BackendName(), values[i]))
+		}
+
+		if field.ascending != backwards {
+			parts = append(parts, expr.Gt("", field.attr.BackendName(), values[index]))
+		} else {
+			parts = append(parts, expr.Lt("", field.attr.BackendName(), values[index]))
+		}
+
+		conditions = append(conditions, expr.And(parts...))
+	}
+	query.FilterExpr(expr.Or(conditions...))
+
+	return nil
+}
+
+// cursorResult trims the extra model of a cursor query and builds the page
+// metadata with the next and previous cursors.
+func (res *Resource) cursorResult(result []kit.Model, page *CursorPage, fields []*sortField) ([]kit.Model, map[string]interface{}) {
+	backwards := page.Before != ""
+
+	hasMore := len(result) > page.Size
+	if hasMore {
+		result = result[:page.Size]
+	}
+
+	if backwards {
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	}
+
+	meta := map[string]interface{}{
+		"size": page.Size,
+		"next": nil,
+		"prev": nil,
+	}
+
+	if len(result) > 0 {
+		first := encodeCursor(fields, result[0])
+		last := encodeCursor(fields, result[len(result)-1])
+
+		if backwards {
+			meta["next"] = last
+			if hasMore {
+				meta["prev"] = first
+			}
+		} else {
+			if hasMore {
+				meta["next"] = last
+			}
+			if page.After != "" {
+				meta["prev"] = first
+			}
+		}
+	}
+
+	return result, meta
+}
@@ -0,0 +1,350 @@
+package resources
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/theduke/go-apperror"
+	"github.com/theduke/go-reflector"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// FieldAccess describes who may read or write a field.
+// Access is granted if any of the conditions is met.
+type FieldAccess struct {
+	// Owner grants access to the user who owns the model, or to the user
+	// itself for user models.
+	Owner bool
+
+	// Roles grants access to users with any of the roles.
+	Roles []string
+
+	// Permissions grants access to users with all of the permissions.
+	Permissions []string
+
+	// Func grants access if it returns true.
+	Func func(res kit.Resource, model kit.Model, user kit.User) bool
+}
+
+// FieldPolicy restricts who may read or write a field.
+// A nil FieldAccess does not restrict access.
+type FieldPolicy struct {
+	Read  *FieldAccess
+	Write *FieldAccess
+
+	// RejectWrite rejects creates and updates that change a field the user
+	// may not write. By default, the changes are ignored.
+	RejectWrite bool
+}
+
+// isOwner returns true if the user owns the model.
+// On creation, models without a user are owned by their creator.
+func isOwner(model kit.Model, user kit.User, creating bool) bool {
+	if user == nil {
+		return false
+	}
+
+	if u, ok := model.(kit.User); ok {
+		return u.GetStrId() == user.GetStrId()
+	}
+
+	if userModel, ok := model.(kit.UserModel); ok {
+		id := userModel.GetUserId()
+		if id == nil || reflector.R(id).IsZero() {
+			return creating
+		}
+		return fmt.Sprintf("%v", id) == user.GetStrId()
+	}
+
+	return false
+}
+
+func (a *FieldAccess) allows(res kit.Resource, model kit.Model, user kit.User, creating bool) bool {
+	if a == nil {
+		return true
+	}
+
+	if a.Owner && isOwner(model, user, creating) {
+		return true
+	}
+
+	if user != nil {
+		if len(a.Roles) > 0 && user.HasRole(a.Roles...) {
+			return true
+		}
+
+		if len(a.Permissions) > 0 {
+			hasAll := true
+			for _, perm := range a.Permissions {
+				if !user.HasPermission(perm) {
+					hasAll = false
+					break
+				}
+			}
+			if hasAll {
+				return true
+			}
+		}
+	}
+
+	return a.Func != nil && a.Func(res, model, user)
+}
+
+func (res *Resource) fieldPolicies() map[string]*FieldPolicy {
+	if hook, ok := res.hooks.(FieldPoliciesHook); ok {
+		return hook.FieldPolicies(res)
+	}
+	return nil
+}
+
+// UnreadableFields returns the names of the fields of the model the user may
+// not read.
+func (res *Resource) UnreadableFields(model kit.Model, user kit.User) []string {
+	fields := make([]string, 0)
+	for name, policy := range res.fieldPolicies() {
+		if !policy.Read.allows(res, model, user, false) {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func modelField(model kit.Model, name string) reflect.Value {
+	return reflect.ValueOf(model).Elem().FieldByName(name)
+}
+
+// clearFields sets the fields of the model to their zero value.
+func clearFields(model kit.Model, fields []string) {
+	for _, name := range fields {
+		if field := modelField(model, name); field.IsValid() && field.CanSet() {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+}
+
+// clearUnreadableFields clears the fields the user may not read in the
+// models of a response and in their loaded relations, so that no
+// serializer can expose them.
+func (res *Resource) clearUnreadableFields(response kit.Response, user kit.User) kit.Response {
+	if response.GetError() != nil {
+		return response
+	}
+
+	var models []kit.Model
+	switch data := response.GetData().(type) {
+	case kit.Model:
+		models = []kit.Model{data}
+	case []kit.Model:
+		models = data
+	}
+
+	seen := make(map[kit.Model]bool)
+	for _, model := range models {
+		res.clearModelFields(model, user, seen)
+	}
+
+	return response
+}
+
+// ClearUnreadableFields clears the fields the user may not read in the
+// models of a response and in their loaded relations.
+func (res *Resource) ClearUnreadableFields(response kit.Response, user kit.User) kit.Response {
+	return res.clearUnreadableFields(response, user)
+}
+
+// modelResource returns the resource of the collection of the model, or nil
+// if it is not registered.
+func (res *Resource) modelResource(model kit.Model) kit.Resource {
+	if model.Collection() == res.Collection() {
+		return res
+	}
+	if res.registry == nil {
+		return nil
+	}
+	return res.registry.Resource(model.Collection())
+}
+
+// clearModelFields clears the unreadable fields of the model with the
+// policies of its resource, and recurses into the related models.
+func (res *Resource) clearModelFields(model kit.Model, user kit.User, seen map[kit.Model]bool) {
+	if model == nil || seen[model] {
+		return
+	}
+	seen[model] = true
+
+	if modelRes := res.modelResource(model); modelRes != nil {
+		clearFields(model, modelRes.UnreadableFields(model, user))
+	}
+
+	if res.backend == nil {
+		return
+	}
+	info := res.backend.ModelInfo(model.Collection())
+	if info == nil {
+		return
+	}
+
+	for name := range info.Relations() {
+		for _, related := range relatedModels(model, name) {
+			res.clearModelFields(related, user, seen)
+		}
+	}
+}
+
+// relatedModels returns the models loaded into a relation field.
+func relatedModels(model kit.Model, name string) []kit.Model {
+	field := modelField(model, name)
+	if !field.IsValid() {
+		return nil
+	}
+
+	models := make([]kit.Model, 0)
+	add := func(val reflect.Value) {
+		if val.Kind() != reflect.Ptr {
+			if !val.CanAddr() {
+				return
+			}
+			val = val.Addr()
+		}
+		if val.IsNil() {
+			return
+		}
+		if related, ok := val.Interface().(kit.Model); ok {
+			models = append(models, related)
+		}
+	}
+
+	if field.Kind() == reflect.Slice {
+		for i := 0; i < field.Len(); i++ {
+			add(field.Index(i))
+		}
+	} else {
+		add(field)
+	}
+
+	return models
+}
+
+// ReadableVersionData returns the data of a version without the fields the
+// user may not read in the model.
+func (res *Resource) ReadableVersionData(model kit.Model, version kit.ModelVersion, user kit.User) map[string]interface{} {
+	return res.readableSnapshot(model, version.GetData(), user)
+}
+
+func (res *Resource) readableSnapshot(model kit.Model, data map[string]interface{}, user kit.User) map[string]interface{} {
+	unreadable := res.UnreadableFields(model, user)
+
+	readable := make(map[string]interface{}, len(data))
+	for key, val := range data {
+		readable[key] = val
+	}
+	for _, name := range unreadable {
+		delete(readable, jsonFieldName(model, name))
+	}
+
+	return readable
+}
+
+// jsonFieldName returns the key of a struct field in the json
+// representation of the model.
+func jsonFieldName(model kit.Model, name string) string {
+	field, ok := reflect.TypeOf(model).Elem().FieldByName(name)
+	if !ok {
+		return name
+	}
+
+	tag := field.Tag.Get("json")
+	if index := strings.Index(tag, ","); index != -1 {
+		tag = tag[:index]
+	}
+	if tag == "" || tag == "-" {
+		return name
+	}
+	return tag
+}
+
+func fieldPermissionError(collection, field string) apperror.Error {
+	return &apperror.Err{
+		Code:    "field_permission_denied",
+		Message: fmt.Sprintf("You may not change the field %v of %v", field, collection),
+		Data: map[string]interface{}{
+			"field": field,
+		},
+		Public: true,
+		Status: 403,
+	}
+}
+
+// checkWritableFields handles fields the user may not write.
+// old is nil for new models.
+// Changed fields are rejected with an error if the policy demands it, and
+// otherwise reset to the old value.
+// For partial updates, zero fields are unchanged, so ignored fields are
+// reset to zero.
+func (res *Resource) checkWritableFields(obj, old kit.Model, user kit.User, partial bool) apperror.Error {
+	for name, policy := range res.fieldPolicies() {
+		checked := obj
+		if old != nil {
+			checked = old
+		}
+		if policy.Write.allows(res, checked, user, old == nil) {
+			continue
+		}
+
+		field := modelField(obj, name)
+		if !field.IsValid() || !field.CanSet() {
+			continue
+		}
+
+		var oldValue reflect.Value
+		if old != nil {
+			oldValue = modelField(old, name)
+		} else {
+			oldValue = reflect.Zero(field.Type())
+		}
+
+		changed := !reflect.DeepEqual(field.Interface(), oldValue.Interface())
+		if partial && reflector.R(field.Interface()).IsZero() {
+			changed = false
+		}
+		if !changed {
+			continue
+		}
+
+		if policy.RejectWrite {
+			return fieldPermissionError(res.Collection(), name)
+		}
+
+		if partial {
+			field.Set(reflect.Zero(field.Type()))
+		} else {
+			field.Set(oldValue)
+		}
+	}
+
+	return nil
+}
+
+// checkUpdatedFields loads the stored model and checks the fields of an
+// update the user may not write.
+func (res *Resource) checkUpdatedFields(obj kit.Model, r kit.Request, partial bool) apperror.Error {
+	if len(res.fieldPolicies()) == 0 {
+		return nil
+	}
+
+	// The stored model is loaded through the row policy, so that models
+	// the user may not see respond with not_found.
+	old, err := res.findOneForRequest(obj.GetId(), r, false)
+	if err != nil {
+		return err
+	} else if old == nil {
+		// The update responds with not_found.
+		return nil
+	}
+
+	return res.checkWritableFields(obj, old, r.GetUser(), partial)
+}
@@ -0,0 +1,67 @@
+package resources_test
+
+import (
+	db "github.com/theduke/go-dukedb"
+	"github.com/theduke/go-dukedb/backends/memory"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/resources"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Contact struct {
+	db.IntIdModel
+
+	Name  string `db:"max:100" json:"name"`
+	Email string `db:"max:100" json:"email"`
+}
+
+func (Contact) Collection() string {
+	return "contacts"
+}
+
+type ContactHooks struct{}
+
+func (ContactHooks) FieldPolicies(res kit.Resource) map[string]*FieldPolicy {
+	return map[string]*FieldPolicy{
+		"Email": &FieldPolicy{
+			Read: &FieldAccess{Roles: []string{"admin"}},
+		},
+	}
+}
+
+var _ = Describe("Field policies", func() {
+	var res *Resource
+
+	BeforeEach(func() {
+		backend := memory.New()
+		res = NewResource(&Contact{}, ContactHooks{}, true)
+		res.SetVersioned(true)
+		res.SetBackend(backend)
+		backend.Build()
+	})
+
+	It("Should clear unreadable fields of response models", func() {
+		contact := &Contact{Name: "john", Email: "john@example.com"}
+		response := res.ClearUnreadableFields(&kit.AppResponse{Data: contact}, nil)
+
+		cleared := response.GetData().(*Contact)
+		Expect(cleared.Name).To(Equal("john"))
+		Expect(cleared.Email).To(Equal(""))
+	})
+
+	It("Should remove unreadable fields from version data", func() {
+		contact := &Contact{Name: "john", Email: "john@example.com"}
+		Expect(res.Create(contact, nil)).To(BeNil())
+
+		version, err := res.Version(contact.GetId(), 1)
+		Expect(err).To(BeNil())
+		Expect(version.GetData()).To(HaveKey("email"))
+
+		data := res.ReadableVersionData(contact, version, nil)
+		Expect(data).To(HaveKeyWithValue("name", "john"))
+		Expect(data).ToNot(HaveKey("email"))
+	})
+})
@@ -3,6 +3,7 @@ package resources
 import (
 	"github.com/theduke/go-apperror"
 	db "github.com/theduke/go-dukedb"
+	expr "github.com/theduke/go-dukedb/expressions"
 
 	kit "github.com/app-kit/go-appkit"
 )
@@ -22,6 +23,29 @@ type MethodsHook interface {
 	Methods(kit.Resource) []kit.Method
 }
 
+// FieldPoliciesHook allows a resource to restrict who may read or write
+// single fields, keyed by the struct field name.
+type FieldPoliciesHook interface {
+	FieldPolicies(kit.Resource) map[string]*FieldPolicy
+}
+
+// ComputedFieldsHook allows a resource to add read-only computed fields to
+// its serialized models.
+type ComputedFieldsHook interface {
+	ComputedFields(kit.Resource) []*ComputedField
+}
+
+// ValidationRulesHook allows a resource to add validation rules, like
+// cross-field and conditional rules, to the rules from the struct tags.
+type ValidationRulesHook interface {
+	ValidationRules(kit.Resource) []*ValidationRule
+}
+
+// QueryPolicyHook allows a resource to restrict the queries clients can run.
+type QueryPolicyHook interface {
+	QueryPolicy(kit.Resource) *QueryPolicy
+}
+
 /**
  * Find hooks.
  */
@@ -43,6 +67,14 @@ type ApiAlterQueryHook interface {
 	ApiAlterQuery(res kit.Resource, query *db.Query, r kit.Request) apperror.Error
 }
 
+// RowPolicyHook restricts the models a user may access through the api.
+// The returned filter, like user_id = user OR published = true, is added to
+// every api query. A nil filter does not restrict access.
+// Requests with a system context bypass the policy.
+type RowPolicyHook interface {
+	RowPolicy(res kit.Resource, user kit.User) expr.Expression
+}
+
 type ApiAfterFindHook interface {
 	ApiAfterFind(res kit.Resource, objects []kit.Model, req kit.Request, resp kit.Response) apperror.Error
 }
@@ -0,0 +1,75 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/theduke/go-apperror"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// ETag returns the http entity tag of a model that implements
+// kit.OptimisticLockModel, or an empty string for other models.
+func ETag(obj kit.Model) string {
+	lockModel, ok := obj.(kit.OptimisticLockModel)
+	if !ok || lockModel.GetLockToken() == "" {
+		return ""
+	}
+	return fmt.Sprintf("\"%v-%v-%v\"", obj.Collection(), obj.GetStrId(), lockModel.GetLockToken())
+}
+
+func nextLockToken(obj kit.Model) {
+	if lockModel, ok := obj.(kit.OptimisticLockModel); ok {
+		lockModel.NextLockToken()
+	}
+}
+
+func conflictError(collection, id string) apperror.Error {
+	return &apperror.Err{
+		Code:    "conflict",
+		Message: fmt.Sprintf("The model %v with id %v was changed since it was loaded", collection, id),
+		Public:  true,
+		Status:  409,
+	}
+}
+
+// checkIfMatch compares the If-Match header of an http request with the
+// ETag of the stored model.
+func (res *Resource) checkIfMatch(obj kit.Model, r kit.Request) apperror.Error {
+	httpRequest := r.GetHttpRequest()
+	if httpRequest == nil {
+		return nil
+	}
+
+	ifMatch := httpRequest.Header.Get("If-Match")
+	if ifMatch == "" || ifMatch == "*" {
+		return nil
+	}
+
+	current, err := res.findOneForRequest(obj.GetId(), r, false)
+	if err != nil {
+		return err
+	} else if current == nil {
+		// The update responds with not_found.
+		return nil
+	}
+
+	etag := ETag(current)
+	if etag == "" {
+		return nil
+	}
+
+	for _, tag := range strings.Split(ifMatch, ",") {
+		if strings.TrimSpace(tag) == etag {
+			return nil
+		}
+	}
+
+	return &apperror.Err{
+		Code:    "precondition_failed",
+		Message: "The If-Match header does not match the current version of the model",
+		Public:  true,
+		Status:  412,
+	}
+}
@@ -0,0 +1,98 @@
+package resources
+
+import (
+	"strconv"
+	"time"
+)
+
+/**
+ * Extendable models.
+ */
+
+// SoftDeletable can be embedded in a model to enable soft delete for its
+// resource.
+// Deleted models are only marked as deleted and excluded from queries.
+type SoftDeletable struct {
+	DeletedAt *time.Time
+	DeletedBy string `db:"max:255"`
+}
+
+func (m *SoftDeletable) GetDeletedAt() *time.Time {
+	return m.DeletedAt
+}
+
+func (m *SoftDeletable) SetDeletedAt(t *time.Time) {
+	m.DeletedAt = t
+}
+
+func (m *SoftDeletable) GetDeletedBy() string {
+	return m.DeletedBy
+}
+
+func (m *SoftDeletable) SetDeletedBy(userId string) {
+	m.DeletedBy = userId
+}
+
+// VersionLock can be embedded in a model to protect it against concurrent
+// updates with a version number that is incremented on every update.
+type VersionLock struct {
+	LockVersion int
+}
+
+func (m *VersionLock) GetLockToken() string {
+	if m.LockVersion == 0 {
+		return ""
+	}
+	return strconv.Itoa(m.LockVersion)
+}
+
+func (m *VersionLock) NextLockToken() {
+	m.LockVersion++
+}
+
+func (m *VersionLock) SetLockToken(token string) error {
+	if token == "" {
+		m.LockVersion = 0
+		return nil
+	}
+
+	version, err := strconv.Atoi(token)
+	if err != nil {
+		return err
+	}
+	m.LockVersion = version
+	return nil
+}
+
+// UpdatedAtLock can be embedded in a model to protect it against concurrent
+// updates with the time of the last update.
+type UpdatedAtLock struct {
+	UpdatedAt time.Time
+}
+
+func (m *UpdatedAtLock) GetLockToken() string {
+	if m.UpdatedAt.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(m.UpdatedAt.UnixNano(), 10)
+}
+
+func (m *UpdatedAtLock) NextLockToken() {
+	// Backends like postgres do not store nanoseconds, so the time is
+	// truncated to keep the token stable.
+	m.UpdatedAt = time.Now().Truncate(time.Millisecond)
+}
+
+func (m *UpdatedAtLock) SetLockToken(token string) error {
+	if token == "" {
+		m.UpdatedAt = time.Time{}
+		return nil
+	}
+
+	nanos, err := strconv.ParseInt(token, 10, 64)
+	if err != nil {
+		return err
+	}
+	m.UpdatedAt = time.Unix(0, nanos)
+	return nil
+}
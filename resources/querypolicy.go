@@ -0,0 +1,369 @@
+package resources
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/theduke/go-apperror"
+
+	"github.com/app-kit/go-appkit/utils"
+)
+
+// QueryPolicy restricts the queries clients can run on a resource.
+type QueryPolicy struct {
+	// DefaultLimit is used for queries without a limit.
+	// If it is not set, MaxLimit is used.
+	DefaultLimit int
+
+	// MaxLimit is the maximum number of models a query can return.
+	MaxLimit int
+
+	// FilterFields, SortFields, JoinFields and Fields list the fields
+	// clients may filter by, sort by, join and select. If nil, all fields
+	// are allowed.
+	// Fields of joined models are given with the relation, like "user.id".
+	FilterFields []string
+	SortFields   []string
+	JoinFields   []string
+	Fields       []string
+
+	// MaxJoinDepth limits nested joins like "a.b.c", which have a depth of 3.
+	MaxJoinDepth int
+
+	// DisallowedOperators lists filter operators clients may not use,
+	// for example "$like".
+	DisallowedOperators []string
+
+	// MaxCost is the maximum estimated cost of a query.
+	// The cost is 1, plus 1 per filter, plus 2 per join level, plus 1 per
+	// 100 models of the limit.
+	MaxCost int
+}
+
+// QueryPolicy returns the query policy of the resource, or the default
+// policy built from the config.
+// Without configuration, the default policy does not restrict queries.
+func (res *Resource) QueryPolicy() *QueryPolicy {
+	if hook, ok := res.hooks.(QueryPolicyHook); ok {
+		if policy := hook.QueryPolicy(res); policy != nil {
+			return policy
+		}
+	}
+
+	policy := &QueryPolicy{}
+	if res.registry != nil {
+		config := res.registry.Config()
+		policy.DefaultLimit = config.UInt("resources.query.defaultLimit", 0)
+		policy.MaxLimit = config.UInt("resources.query.maxLimit", 0)
+		policy.MaxJoinDepth = config.UInt("resources.query.maxJoinDepth", 0)
+		policy.MaxCost = config.UInt("resources.query.maxCost", 0)
+	}
+	return policy
+}
+
+func queryPolicyError(code, message string) apperror.Error {
+	return &apperror.Err{
+		Code:    code,
+		Message: message,
+		Public:  true,
+		Status:  400,
+	}
+}
+
+// normalizeField returns the backend name of a field, so that fields can be
+// given with their struct, marshal or backend name.
+func (res *Resource) normalizeField(name string) string {
+	if res.modelInfo != nil {
+		if attr := res.modelInfo.FindAttribute(name); attr != nil {
+			return attr.BackendName()
+		}
+	}
+	return name
+}
+
+func (res *Resource) fieldAllowed(allowed []string, name string) bool {
+	if allowed == nil {
+		return true
+	}
+
+	name = res.normalizeField(strings.TrimPrefix(name, "-"))
+	for _, field := range allowed {
+		if res.normalizeField(field) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFilters checks filters like {"field": {"$gt": 1}, "$or": [...]} and
+// returns the number of filters.
+// The prefix is added to the fields of the filters of joined models.
+func (res *Resource) checkFilters(policy *QueryPolicy, filters map[string]interface{}, prefix string) (int, apperror.Error) {
+	count := 0
+
+	for key, val := range filters {
+		if strings.HasPrefix(key, "$") {
+			// Logical operators like $or contain a list of filters.
+			if err := res.checkOperator(policy, key); err != nil {
+				return 0, err
+			}
+
+			items, _ := val.([]interface{})
+			for _, item := range items {
+				nested, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				n, err := res.checkFilters(policy, nested, prefix)
+				if err != nil {
+					return 0, err
+				}
+				count += n
+			}
+			continue
+		}
+
+		if !res.fieldAllowed(policy.FilterFields, prefix+key) {
+			return 0, queryPolicyError("filter_field_not_allowed", fmt.Sprintf("Filtering by %v is not allowed", prefix+key))
+		}
+		count++
+
+		if conditions, ok := val.(map[string]interface{}); ok {
+			for operator := range conditions {
+				if err := res.checkOperator(policy, operator); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+
+	return count, nil
+}
+
+func (res *Resource) checkOperator(policy *QueryPolicy, operator string) apperror.Error {
+	for _, disallowed := range policy.DisallowedOperators {
+		if disallowed == operator {
+			return queryPolicyError("filter_operator_not_allowed", fmt.Sprintf("The filter operator %v is not allowed", operator))
+		}
+	}
+	return nil
+}
+
+func stringList(raw interface{}) []string {
+	switch val := raw.(type) {
+	case string:
+		if val == "" {
+			return nil
+		}
+		return strings.Split(val, ",")
+	case []string:
+		return val
+	case []interface{}:
+		items := make([]string, 0)
+		for _, item := range val {
+			items = append(items, fmt.Sprintf("%v", item))
+		}
+		return items
+	}
+	return nil
+}
+
+// joinList returns the joins of a raw query. Joins are either relation names,
+// or sub-queries with the relation name and their own filters, fields and
+// joins.
+func joinList(raw interface{}) []interface{} {
+	if items, ok := raw.([]interface{}); ok {
+		return items
+	}
+
+	items := make([]interface{}, 0)
+	for _, name := range stringList(raw) {
+		items = append(items, name)
+	}
+	return items
+}
+
+// checkJoin checks a join against the allowed joins and the maximum depth.
+func (res *Resource) checkJoin(policy *QueryPolicy, join string) apperror.Error {
+	depth := len(strings.Split(join, "."))
+	if policy.MaxJoinDepth > 0 && depth > policy.MaxJoinDepth {
+		return queryPolicyError("join_too_deep", fmt.Sprintf("Joins may not be nested deeper than %v levels", policy.MaxJoinDepth))
+	}
+
+	if policy.JoinFields != nil {
+		allowed := false
+		for _, field := range policy.JoinFields {
+			if field == join {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return queryPolicyError("join_not_allowed", fmt.Sprintf("Joining %v is not allowed", join))
+		}
+	}
+
+	return nil
+}
+
+// checkFields checks the selected fields. Fields of joined models, like
+// "user.id", also have to be allowed joins.
+func (res *Resource) checkFields(policy *QueryPolicy, fields []string, prefix string) apperror.Error {
+	for _, field := range fields {
+		field = prefix + field
+		if !res.fieldAllowed(policy.Fields, field) {
+			return queryPolicyError("field_not_allowed", fmt.Sprintf("Selecting %v is not allowed", field))
+		}
+		if index := strings.LastIndex(field, "."); index != -1 {
+			if err := res.checkJoin(policy, field[:index]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkJoins checks the joins of a query, including the filters, fields and
+// joins of join sub-queries, and returns their cost and number of filters.
+func (res *Resource) checkJoins(policy *QueryPolicy, joins []interface{}, prefix string) (int, int, apperror.Error) {
+	cost := 0
+	filterCount := 0
+
+	for _, item := range joins {
+		var name string
+		var subQuery map[string]interface{}
+
+		switch val := item.(type) {
+		case string:
+			name = val
+		case map[string]interface{}:
+			subQuery = val
+			name, _ = val["relation"].(string)
+			if name == "" {
+				name, _ = val["name"].(string)
+			}
+		default:
+			name = fmt.Sprintf("%v", val)
+		}
+		if name == "" {
+			return 0, 0, queryPolicyError("invalid_join", "Joins must contain a relation name")
+		}
+
+		join := prefix + name
+		if err := res.checkJoin(policy, join); err != nil {
+			return 0, 0, err
+		}
+		cost += 2 * len(strings.Split(join, "."))
+
+		if subQuery == nil {
+			continue
+		}
+
+		filters, _ := subQuery["filters"].(map[string]interface{})
+		n, err := res.checkFilters(policy, filters, join+".")
+		if err != nil {
+			return 0, 0, err
+		}
+		filterCount += n
+
+		if err := res.checkFields(policy, stringList(subQuery["fields"]), join+"."); err != nil {
+			return 0, 0, err
+		}
+
+		nestedCost, nestedCount, err := res.checkJoins(policy, joinList(subQuery["joins"]), join+".")
+		if err != nil {
+			return 0, 0, err
+		}
+		cost += nestedCost
+		filterCount += nestedCount
+	}
+
+	return cost, filterCount, nil
+}
+
+// CheckQuery enforces the query policy of the resource on a client supplied
+// raw query before it is parsed.
+// Queries without a limit receive the default limit.
+func (res *Resource) CheckQuery(rawQuery map[string]interface{}) apperror.Error {
+	policy := res.QueryPolicy()
+
+	// Limit.
+	limit := 0
+	if rawLimit, ok := rawQuery["limit"]; ok {
+		switch val := rawLimit.(type) {
+		case float64:
+			limit = int(val)
+		case int:
+			limit = val
+		default:
+			return queryPolicyError("invalid_limit", "The limit must be a number")
+		}
+	}
+	if limit < 1 {
+		limit = policy.DefaultLimit
+		if limit < 1 {
+			limit = policy.MaxLimit
+		}
+		if limit > 0 {
+			rawQuery["limit"] = limit
+		}
+	}
+	if policy.MaxLimit > 0 && limit > policy.MaxLimit {
+		return queryPolicyError("limit_too_high", fmt.Sprintf("The limit may not exceed %v", policy.MaxLimit))
+	}
+
+	// Filters.
+	filters, _ := utils.GetMapDictKey(rawQuery, "filters")
+	filterCount, err := res.checkFilters(policy, filters, "")
+	if err != nil {
+		return err
+	}
+
+	// Selected fields.
+	if err := res.checkFields(policy, stringList(rawQuery["fields"]), ""); err != nil {
+		return err
+	}
+
+	// Sorting.
+	for _, field := range stringList(rawQuery["order"]) {
+		if !res.fieldAllowed(policy.SortFields, field) {
+			return queryPolicyError("sort_field_not_allowed", fmt.Sprintf("Sorting by %v is not allowed", field))
+		}
+	}
+
+	// Joins.
+	joinCost, joinFilterCount, err := res.checkJoins(policy, joinList(rawQuery["joins"]), "")
+	if err != nil {
+		return err
+	}
+	filterCount += joinFilterCount
+
+	// Cost estimate.
+	if policy.MaxCost > 0 {
+		cost := 1 + filterCount + joinCost + int(math.Ceil(float64(limit)/100))
+		if cost > policy.MaxCost {
+			return queryPolicyError("query_too_expensive", fmt.Sprintf("The query is too expensive (cost %v, maximum %v)", cost, policy.MaxCost))
+		}
+	}
+
+	return nil
+}
+
+// checkCursorPage enforces the maximum limit and the sort fields of the query
+// policy on a cursor page.
+func (res *Resource) checkCursorPage(page *CursorPage) apperror.Error {
+	policy := res.QueryPolicy()
+
+	if policy.MaxLimit > 0 && page.Size > policy.MaxLimit {
+		return queryPolicyError("limit_too_high", fmt.Sprintf("The page size may not exceed %v", policy.MaxLimit))
+	}
+
+	for _, field := range page.Order {
+		if !res.fieldAllowed(policy.SortFields, strings.TrimSpace(field)) {
+			return queryPolicyError("sort_field_not_allowed", fmt.Sprintf("Sorting by %v is not allowed", field))
+		}
+	}
+
+	return nil
+}
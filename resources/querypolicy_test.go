@@ -0,0 +1,66 @@
+package resources_test
+
+import (
+	db "github.com/theduke/go-dukedb"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/resources"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type PolicyNote struct {
+	db.IntIdModel
+
+	Title string `db:"max:100"`
+}
+
+func (PolicyNote) Collection() string {
+	return "policy_notes"
+}
+
+type PolicyNoteHooks struct{}
+
+func (PolicyNoteHooks) QueryPolicy(res kit.Resource) *QueryPolicy {
+	return &QueryPolicy{
+		FilterFields:        []string{"title"},
+		Fields:              []string{"id", "title", "user.id"},
+		DisallowedOperators: []string{"$like"},
+	}
+}
+
+var _ = Describe("Query policies", func() {
+	var res *Resource
+
+	BeforeEach(func() {
+		res = NewResource(&PolicyNote{}, PolicyNoteHooks{}, true)
+	})
+
+	It("Should reject fields that are not allowed", func() {
+		err := res.CheckQuery(map[string]interface{}{
+			"fields": []interface{}{"id", "secret"},
+		})
+		Expect(err).ToNot(BeNil())
+		Expect(err.GetCode()).To(Equal("field_not_allowed"))
+
+		Expect(res.CheckQuery(map[string]interface{}{
+			"fields": []interface{}{"id", "user.id"},
+		})).To(BeNil())
+	})
+
+	It("Should check the filters of join sub-queries", func() {
+		err := res.CheckQuery(map[string]interface{}{
+			"joins": []interface{}{
+				map[string]interface{}{
+					"relation": "user",
+					"filters": map[string]interface{}{
+						"password": map[string]interface{}{"$like": "a%"},
+					},
+				},
+			},
+		})
+		Expect(err).ToNot(BeNil())
+		Expect(err.GetCode()).To(Equal("filter_field_not_allowed"))
+	})
+})
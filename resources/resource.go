@@ -1,10 +1,13 @@
 package resources
 
 import (
+	"fmt"
 	"math"
+	"time"
 
 	"github.com/theduke/go-apperror"
 	db "github.com/theduke/go-dukedb"
+	expr "github.com/theduke/go-dukedb/expressions"
 	"github.com/theduke/go-reflector"
 
 	kit "github.com/app-kit/go-appkit"
@@ -21,6 +24,35 @@ type Resource struct {
 
 	isPublic bool
 
+	// versioned enables storing a snapshot of models on every change.
+	versioned bool
+
+	// shared enables sharing models with other users and roles.
+	shared bool
+
+	// preloadedGrants holds the grants of the models of a find request, by
+	// model id, so that permission checks do not query them per model.
+	preloadedGrants map[string][]kit.AccessGrant
+
+	// preloadedModels holds the stored models of a bulk update, by model id,
+	// so that updates do not load them one by one.
+	preloadedModels map[string]kit.Model
+
+	// preloadedRequest is the request whose row policy restricted the
+	// preloaded models, if any.
+	preloadedRequest kit.Request
+
+	// countTotal adds the total number of results to find responses by
+	// default.
+	countTotal bool
+
+	// cache enables caching of query results.
+	cache      *kit.ResourceCache
+	cacheStats *cacheStats
+
+	// csv enables the csv export and import.
+	csv *kit.ResourceCsv
+
 	model kit.Model
 }
 
@@ -69,6 +101,19 @@ func (res *Resource) SetBackend(b db.Backend) {
 		b.RegisterModel(res.Model())
 	}
 	res.modelInfo = b.ModelInfo(res.Collection())
+
+	if res.versioned {
+		res.registerVersionModel()
+	}
+	if res.shared {
+		res.registerGrantModel()
+	}
+}
+
+func (res *Resource) WithBackend(b db.Backend) kit.Resource {
+	r := *res
+	r.backend = b
+	return &r
 }
 
 func (res *Resource) ModelInfo() *db.ModelInfo {
@@ -104,6 +149,14 @@ func (res *Resource) SetHooks(h interface{}) {
 	res.hooks = h
 }
 
+// trigger triggers an event on the event bus of the registry.
+func (res *Resource) trigger(event string, obj kit.Model) {
+	if res.registry == nil || res.registry.EventBus() == nil {
+		return
+	}
+	res.registry.EventBus().Trigger(event, obj)
+}
+
 /**
  * Queries.
  */
@@ -112,6 +165,10 @@ func (res *Resource) SetHooks(h interface{}) {
  * Perform a query.
  */
 func (res Resource) Query(q *db.Query, targetSlice ...interface{}) ([]kit.Model, apperror.Error) {
+	if len(targetSlice) == 0 && res.readCache() != nil {
+		return res.cachedQuery(q)
+	}
+
 	items, err := res.backend.Query(q, targetSlice...)
 	if err != nil {
 		return nil, err
@@ -125,16 +182,54 @@ func (res Resource) Count(q *db.Query) (int, apperror.Error) {
 
 /**
  * Return a new query initialized with the backend.
+ * For soft delete resources, deleted models are excluded.
  */
 func (res Resource) Q() *db.Query {
+	q := res.backend.Q(res.model.Collection())
+	if res.IsSoftDelete() {
+		excludeDeleted(q)
+	}
+	return q
+}
+
+func (res Resource) QIncludeDeleted() *db.Query {
 	return res.backend.Q(res.model.Collection())
 }
 
+func excludeDeleted(q *db.Query) *db.Query {
+	return q.FilterExpr(expr.Eq("", "deleted_at", nil))
+}
+
+/**
+ * Soft delete.
+ */
+
+func (res Resource) IsSoftDelete() bool {
+	_, ok := res.model.(kit.SoftDeleteModel)
+	return ok
+}
+
+// includeDeleted returns true if an admin requested to include deleted
+// models with the include_deleted parameter.
+func includeDeleted(r kit.Request) bool {
+	val := r.GetContext().String("include_deleted")
+	if val != "1" && val != "true" {
+		return false
+	}
+
+	user := r.GetUser()
+	return user != nil && user.HasRole("admin")
+}
+
 /**
  * FindOne
  */
 
-func (res *Resource) FindOne(rawId interface{}) (kit.Model, apperror.Error) {
+func (res *Resource) FindOneIncludeDeleted(rawId interface{}) (kit.Model, apperror.Error) {
+	if res.readCache() != nil {
+		return res.cachedFindOne(rawId)
+	}
+
 	item, err := res.backend.FindOne(res.model.Collection(), rawId)
 	if err != nil {
 		return nil, err
@@ -145,17 +240,35 @@ func (res *Resource) FindOne(rawId interface{}) (kit.Model, apperror.Error) {
 	return item.(kit.Model), nil
 }
 
+func (res *Resource) FindOne(rawId interface{}) (kit.Model, apperror.Error) {
+	item, err := res.FindOneIncludeDeleted(rawId)
+	if err != nil || item == nil {
+		return nil, err
+	}
+
+	if model, ok := item.(kit.SoftDeleteModel); ok && model.GetDeletedAt() != nil {
+		return nil, nil
+	}
+	return item, nil
+}
+
 /**
  * Find.
  */
 
+// ApiFindOne finds a model, clears the fields the user may not read and adds
+// the computed fields.
 func (res *Resource) ApiFindOne(rawId string, r kit.Request) kit.Response {
+	return res.addComputedFields(res.clearUnreadableFields(res.apiFindOne(rawId, r), r.GetUser()), r)
+}
+
+func (res *Resource) apiFindOne(rawId string, r kit.Request) kit.Response {
 	hook, ok := res.hooks.(ApiFindOneHook)
 	if ok {
 		return hook.ApiFindOne(res, rawId, r)
 	}
 
-	result, err := res.FindOne(rawId)
+	result, err := res.findOneForRequest(rawId, r, includeDeleted(r))
 	if err != nil {
 		return kit.NewErrorResponse(err)
 	} else if result == nil {
@@ -174,19 +287,113 @@ func (res *Resource) ApiFindOne(rawId string, r kit.Request) kit.Response {
 	}
 }
 
+// ApiFind queries models, clears the fields the user may not read and adds
+// the computed fields.
 func (res *Resource) ApiFind(query *db.Query, r kit.Request) kit.Response {
+	return res.addComputedFields(res.clearUnreadableFields(res.apiFind(query, r), r.GetUser()), r)
+}
+
+// ApiCount counts the models matching the query, restricted like ApiFind by
+// the row policy and the ApiAlterQuery hook.
+func (res *Resource) ApiCount(query *db.Query, r kit.Request) kit.Response {
+	if query == nil {
+		query = res.QIncludeDeleted()
+	}
+	if res.IsSoftDelete() && !includeDeleted(r) {
+		excludeDeleted(query)
+	}
+	res.applyRowPolicy(query, r)
+
+	if alterQuery, ok := res.hooks.(ApiAlterQueryHook); ok {
+		if err := alterQuery.ApiAlterQuery(res, query, r); err != nil {
+			return kit.NewErrorResponse(err)
+		}
+	}
+
+	count, err := res.backend.Count(query.Limit(0).Offset(0))
+	if err != nil {
+		return kit.NewErrorResponse(apperror.Wrap(err, "count_error", ""))
+	}
+
+	return &kit.AppResponse{
+		Data: map[string]interface{}{
+			"count": count,
+		},
+	}
+}
+
+func (res *Resource) apiFind(query *db.Query, r kit.Request) kit.Response {
 	// If query is empty, query for all records.
 	if query == nil {
-		query = res.Q()
+		query = res.QIncludeDeleted()
+	}
+	if res.IsSoftDelete() && !includeDeleted(r) {
+		excludeDeleted(query)
 	}
 
+	// The row policy restricts the query, so that pages and counts only
+	// contain accessible models.
+	res.applyRowPolicy(query, r)
+
 	apiFindHook, ok := res.hooks.(ApiFindHook)
 	if ok {
 		return apiFindHook.ApiFind(res, query, r)
 	}
 
 	if alterQuery, ok := res.hooks.(ApiAlterQueryHook); ok {
-		alterQuery.ApiAlterQuery(res, query, r)
+		if err := alterQuery.ApiAlterQuery(res, query, r); err != nil {
+			return kit.NewErrorResponse(err)
+		}
+	}
+
+	page, err := CursorPageFromRequest(r)
+	if err != nil {
+		return kit.NewErrorResponse(err)
+	}
+	if page != nil {
+		if err := res.checkCursorPage(page); err != nil {
+			return kit.NewErrorResponse(err)
+		}
+	}
+
+	countTotal := res.requestsTotal(r)
+	total := 0
+
+	// Cached results are scoped to the user, since the hooks may filter
+	// them by user.
+	cacheKey := ""
+	cacheTags, cacheable := res.queryCacheTags(query)
+	if res.readCache() != nil && cacheable {
+		cacheKey = res.cacheKey("find", query, page, countTotal, userScope(r.GetUser()))
+		if models, meta, ok := res.cached(cacheKey); ok {
+			return &kit.AppResponse{
+				Data: models,
+				Meta: meta,
+			}
+		}
+	}
+
+	var sortFields []*sortField
+	if page != nil {
+		if page.Size == 0 {
+			page.Size = query.GetLimit()
+		}
+
+		// Count before the cursor restricts the query.
+		if countTotal {
+			query.Limit(0).Offset(0)
+			if total, err = res.backend.Count(query); err != nil {
+				return kit.NewErrorResponse(apperror.Wrap(err, "count_error", ""))
+			}
+		}
+
+		sortFields, err = res.sortFields(page)
+		if err != nil {
+			return kit.NewErrorResponse(err)
+		}
+		if err := res.applyCursor(query, page, sortFields); err != nil {
+			return kit.NewErrorResponse(err)
+		}
 	}
 
 	result, err := res.Query(query)
@@ -194,11 +401,21 @@ func (res *Resource) ApiFind(query *db.Query, r kit.Request) kit.Response {
 		return kit.NewErrorResponse(err)
 	}
 
+	var pageMeta map[string]interface{}
+	if page != nil {
+		result, pageMeta = res.cursorResult(result, page, sortFields)
+	}
+
 	user := r.GetUser()
 	if allowFind, ok := res.hooks.(AllowFindHook); ok {
+		scoped, err := res.preloadGrants(result)
+		if err != nil {
+			return kit.NewErrorResponse(err)
+		}
+
 		finalItems := make([]kit.Model, 0)
 		for _, item := range result {
-			if allowFind.AllowFind(res, item, user) {
+			if allowFind.AllowFind(scoped, item, user) {
 				finalItems = append(finalItems, item)
 			}
 		}
@@ -209,10 +426,18 @@ func (res *Resource) ApiFind(query *db.Query, r kit.Request) kit.Response {
 		Data: result,
 	}
 
-	// If a limit was set, count the total number of results
-	// and set count parameter in metadata.
+	meta := make(map[string]interface{})
+
+	// If a limit was set or the total was requested, count the total number
+	// of results and set count parameter in metadata.
 	limit := query.GetLimit()
-	if limit > 0 {
+	offset := query.GetOffset()
+	if page != nil {
+		meta["page"] = pageMeta
+		if countTotal {
+			meta["total"] = total
+		}
+	} else if limit > 0 || countTotal {
 		query.Limit(0).Offset(0)
 		count, err := res.backend.Count(query)
 		if err != nil {
@@ -221,10 +446,20 @@ func (res *Resource) ApiFind(query *db.Query, r kit.Request) kit.Response {
 			}
 		}
 
-		response.SetMeta(map[string]interface{}{
-			"count":       count,
-			"total_pages": math.Ceil(float64(count) / float64(limit)),
-		})
+		meta["count"] = count
+		if limit > 0 {
+			meta["total_pages"] = math.Ceil(float64(count) / float64(limit))
+		}
+
+		if countTotal {
+			for key, val := range paginationMeta(count, limit, offset) {
+				meta[key] = val
+			}
+		}
+	}
+
+	if len(meta) > 0 {
+		response.SetMeta(meta)
 	}
 
 	if hook, ok := res.hooks.(ApiAfterFindHook); ok {
@@ -233,14 +468,66 @@ func (res *Resource) ApiFind(query *db.Query, r kit.Request) kit.Response {
 		}
 	}
 
+	if models, ok := response.GetData().([]kit.Model); ok && cacheKey != "" {
+		res.store(cacheKey, models, response.GetMeta(), cacheTags)
+	}
+
 	return response
 }
 
+// requestsTotal returns true if the total number of results should be
+// counted, either because the request has a count parameter or because the
+// resource counts by default.
+func (res *Resource) requestsTotal(r kit.Request) bool {
+	switch r.GetContext().String("count") {
+	case "1", "true":
+		return true
+	case "0", "false":
+		return false
+	}
+	return res.countTotal
+}
+
+func (res *Resource) CountTotal() bool {
+	return res.countTotal
+}
+
+func (res *Resource) SetCountTotal(countTotal bool) {
+	res.countTotal = countTotal
+}
+
+// paginationMeta builds the pagination metadata of an offset paginated query.
+func paginationMeta(total, limit, offset int) map[string]interface{} {
+	if limit < 1 {
+		return map[string]interface{}{
+			"total":    total,
+			"page":     1,
+			"per_page": total,
+			"pages":    1,
+		}
+	}
+
+	return map[string]interface{}{
+		"total":    total,
+		"page":     offset/limit + 1,
+		"per_page": limit,
+		"pages":    int(math.Ceil(float64(total) / float64(limit))),
+	}
+}
+
 /**
  * Create.
  */
 
+// Create runs the create hooks and the backend write in a transaction, if
+// the backend supports transactions.
 func (res *Resource) Create(obj kit.Model, user kit.User) apperror.Error {
+	return res.transaction(func(res *Resource) apperror.Error {
+		return res.create(obj, user)
+	})
+}
+
+func (res *Resource) create(obj kit.Model, user kit.User) apperror.Error {
 	if hook, ok := res.hooks.(CreateHook); ok {
 		return hook.Create(res, obj, user)
 	}
@@ -265,9 +552,20 @@ func (res *Resource) Create(obj kit.Model, user kit.User) apperror.Error {
 		}
 	}
 
+	if err := res.Validate(obj); err != nil {
+		return err
+	}
+
+	if lockModel, ok := obj.(kit.OptimisticLockModel); ok && lockModel.GetLockToken() == "" {
+		lockModel.NextLockToken()
+	}
+
 	if err := res.backend.Create(obj); err != nil {
 		return err
 	}
+	if err := res.saveVersion(obj, user); err != nil {
+		return err
+	}
 
 	if afterCreate, ok := res.hooks.(AfterCreateHook); ok {
 		if err := afterCreate.AfterCreate(res, obj, user); err != nil {
@@ -275,10 +573,24 @@ func (res *Resource) Create(obj kit.Model, user kit.User) apperror.Error {
 		}
 	}
 
+	res.afterCommit(func() {
+		res.clearCache(obj)
+		res.trigger(kit.EventResourceCreated, obj)
+	})
+
 	return nil
 }
 
+// ApiCreate checks the fields the user may not write, creates the model,
+// clears the fields the user may not read and adds the computed fields.
 func (res *Resource) ApiCreate(obj kit.Model, r kit.Request) kit.Response {
+	if err := res.checkWritableFields(obj, nil, r.GetUser(), false); err != nil {
+		return kit.NewErrorResponse(err)
+	}
+	return res.addComputedFields(res.clearUnreadableFields(res.apiCreate(obj, r), r.GetUser()), r)
+}
+
+func (res *Resource) apiCreate(obj kit.Model, r kit.Request) kit.Response {
 	if createHook, ok := res.hooks.(ApiCreateHook); ok {
 		return createHook.ApiCreate(res, obj, r)
 	}
@@ -298,18 +610,39 @@ func (res *Resource) ApiCreate(obj kit.Model, r kit.Request) kit.Response {
  * Update.
  */
 
+// storedModel returns the stored version of obj, using the preloaded models
+// of a bulk update if possible.
+func (res *Resource) storedModel(obj kit.Model) (kit.Model, apperror.Error) {
+	if model, ok := res.preloadedModels[obj.GetStrId()]; ok {
+		// Every preloaded model is used once, since the update changes it.
+		delete(res.preloadedModels, obj.GetStrId())
+		return model, nil
+	}
+	return res.FindOne(obj.GetId())
+}
+
 func (res *Resource) update(obj kit.Model, user kit.User, partial bool) apperror.Error {
 	if hook, ok := res.hooks.(UpdateHook); ok {
 		return hook.Update(res, obj, user)
 	}
 
-	oldObj, err := res.FindOne(obj.GetId())
+	oldObj, err := res.storedModel(obj)
 	if err != nil {
 		return err
 	} else if oldObj == nil {
 		return apperror.New("not_found")
 	}
 
+	if lockModel, ok := obj.(kit.OptimisticLockModel); ok {
+		token := lockModel.GetLockToken()
+		current := oldObj.(kit.OptimisticLockModel).GetLockToken()
+
+		// Partial updates may omit the token.
+		if !(partial && token == "") && token != current {
+			return conflictError(res.Collection(), obj.GetStrId())
+		}
+	}
+
 	if allowUpdate, ok := res.hooks.(AllowUpdateHook); ok {
 		if !allowUpdate.AllowUpdate(res, obj, oldObj, user) {
 			return apperror.New("permission_denied")
@@ -324,7 +657,7 @@ func (res *Resource) update(obj kit.Model, user kit.User, partial bool) apperror
 
 	if partial {
 		rOld := reflector.Reflect(oldObj).MustStruct()
-		rNew := reflector.Reflect(oldObj).MustStruct()
+		rNew := reflector.Reflect(obj).MustStruct()
 
 		for fieldName, _ := range res.modelInfo.Attributes() {
 			val := rNew.Field(fieldName)
@@ -342,9 +675,18 @@ func (res *Resource) update(obj kit.Model, user kit.User, partial bool) apperror
 		obj = oldObj
 	}
 
+	if err := res.Validate(obj); err != nil {
+		return err
+	}
+
+	nextLockToken(obj)
+
 	if err := res.backend.Update(obj); err != nil {
 		return err
 	}
+	if err := res.saveVersion(obj, user); err != nil {
+		return err
+	}
 
 	if afterUpdate, ok := res.hooks.(AfterUpdateHook); ok {
 		if err := afterUpdate.AfterUpdate(res, obj, oldObj, user); err != nil {
@@ -352,23 +694,50 @@ func (res *Resource) update(obj kit.Model, user kit.User, partial bool) apperror
 		}
 	}
 
+	res.afterCommit(func() {
+		res.clearCache(obj)
+		res.trigger(kit.EventResourceUpdated, obj)
+	})
+
 	return nil
 }
 
+// Update runs the update hooks and the backend write in a transaction, if
+// the backend supports transactions.
 func (res *Resource) Update(obj kit.Model, user kit.User) apperror.Error {
-	return res.update(obj, user, false)
-
+	return res.transaction(func(res *Resource) apperror.Error {
+		return res.update(obj, user, false)
+	})
 }
 
 func (res *Resource) PartialUpdate(obj kit.Model, user kit.User) apperror.Error {
-	return res.update(obj, user, true)
+	return res.transaction(func(res *Resource) apperror.Error {
+		return res.update(obj, user, true)
+	})
 }
 
+// ApiUpdate checks the fields the user may not write, updates the model,
+// clears the fields the user may not read and adds the computed fields.
 func (res *Resource) ApiUpdate(obj kit.Model, r kit.Request) kit.Response {
+	if err := res.checkUpdatedFields(obj, r, false); err != nil {
+		return kit.NewErrorResponse(err)
+	}
+	return res.addComputedFields(res.clearUnreadableFields(res.apiUpdate(obj, r), r.GetUser()), r)
+}
+
+func (res *Resource) apiUpdate(obj kit.Model, r kit.Request) kit.Response {
 	if updateHook, ok := res.hooks.(ApiUpdateHook); ok {
 		return updateHook.ApiUpdate(res, obj, r)
 	}
 
+	if err := res.checkRowPolicy(obj, r); err != nil {
+		return kit.NewErrorResponse(err)
+	}
+
+	if err := res.checkIfMatch(obj, r); err != nil {
+		return kit.NewErrorResponse(err)
+	}
+
 	user := r.GetUser()
 	err := res.Update(obj, user)
 	if err != nil {
@@ -380,11 +749,28 @@ func (res *Resource) ApiUpdate(obj kit.Model, r kit.Request) kit.Response {
 	}
 }
 
+// ApiPartialUpdate is like ApiUpdate, but only sets the fields that are not
+// zero.
 func (res *Resource) ApiPartialUpdate(obj kit.Model, r kit.Request) kit.Response {
+	if err := res.checkUpdatedFields(obj, r, true); err != nil {
+		return kit.NewErrorResponse(err)
+	}
+	return res.addComputedFields(res.clearUnreadableFields(res.apiPartialUpdate(obj, r), r.GetUser()), r)
+}
+
+func (res *Resource) apiPartialUpdate(obj kit.Model, r kit.Request) kit.Response {
 	if updateHook, ok := res.hooks.(ApiUpdateHook); ok {
 		return updateHook.ApiUpdate(res, obj, r)
 	}
 
+	if err := res.checkRowPolicy(obj, r); err != nil {
+		return kit.NewErrorResponse(err)
+	}
+
+	if err := res.checkIfMatch(obj, r); err != nil {
+		return kit.NewErrorResponse(err)
+	}
+
 	user := r.GetUser()
 	err := res.PartialUpdate(obj, user)
 	if err != nil {
@@ -400,7 +786,15 @@ func (res *Resource) ApiPartialUpdate(obj kit.Model, r kit.Request) kit.Response
  * Delete.
  */
 
+// Delete runs the delete hooks and the backend write in a transaction, if
+// the backend supports transactions.
 func (res *Resource) Delete(obj kit.Model, user kit.User) apperror.Error {
+	return res.transaction(func(res *Resource) apperror.Error {
+		return res.delete(obj, user)
+	})
+}
+
+func (res *Resource) delete(obj kit.Model, user kit.User) apperror.Error {
 	if hook, ok := res.hooks.(DeleteHook); ok {
 		return hook.Delete(res, obj, user)
 	}
@@ -417,8 +811,25 @@ func (res *Resource) Delete(obj kit.Model, user kit.User) apperror.Error {
 		}
 	}
 
-	if err := res.backend.Delete(obj); err != nil {
-		return err
+	if model, ok := obj.(kit.SoftDeleteModel); ok {
+		// Mark the model as deleted instead of removing it.
+		now := time.Now()
+		model.SetDeletedAt(&now)
+		if user != nil {
+			model.SetDeletedBy(user.GetStrId())
+		}
+		nextLockToken(obj)
+
+		if err := res.backend.Update(obj); err != nil {
+			return err
+		}
+	} else {
+		if err := res.backend.Delete(obj); err != nil {
+			return err
+		}
+		if err := res.deleteGrants(obj); err != nil {
+			return err
+		}
 	}
 
 	if afterDelete, ok := res.hooks.(AfterDeleteHook); ok {
@@ -427,6 +838,11 @@ func (res *Resource) Delete(obj kit.Model, user kit.User) apperror.Error {
 		}
 	}
 
+	res.afterCommit(func() {
+		res.clearCache(obj)
+		res.trigger(kit.EventResourceDeleted, obj)
+	})
+
 	return nil
 }
 
@@ -435,7 +851,7 @@ func (res *Resource) ApiDelete(id string, r kit.Request) kit.Response {
 		return deleteHook.ApiDelete(res, id, r)
 	}
 
-	oldObj, err := res.FindOne(id)
+	oldObj, err := res.findOneForRequest(id, r, false)
 	if err != nil {
 		return kit.NewErrorResponse(err)
 	} else if oldObj == nil {
@@ -452,6 +868,95 @@ func (res *Resource) ApiDelete(id string, r kit.Request) kit.Response {
 	}
 }
 
+/**
+ * Restore and purge.
+ */
+
+func (res *Resource) Restore(obj kit.Model, user kit.User) apperror.Error {
+	model, ok := obj.(kit.SoftDeleteModel)
+	if !ok {
+		return apperror.New("soft_delete_disabled", fmt.Sprintf("The collection %v does not support restoring", res.Collection()), true)
+	} else if model.GetDeletedAt() == nil {
+		return apperror.New("not_deleted", "The model is not deleted", true)
+	}
+
+	// Users that may delete a model may also restore it.
+	if allowDelete, ok := res.hooks.(AllowDeleteHook); ok {
+		if !allowDelete.AllowDelete(res, obj, user) {
+			return apperror.New("permission_denied")
+		}
+	}
+
+	model.SetDeletedAt(nil)
+	model.SetDeletedBy("")
+	nextLockToken(obj)
+
+	return res.transaction(func(res *Resource) apperror.Error {
+		if err := res.backend.Update(obj); err != nil {
+			return err
+		}
+
+		res.afterCommit(func() {
+			res.clearCache(obj)
+			res.trigger(kit.EventResourceUpdated, obj)
+		})
+		return nil
+	})
+}
+
+// ApiRestore restores a deleted model. Models the row policy excludes are
+// not found.
+func (res *Resource) ApiRestore(id string, r kit.Request) kit.Response {
+	model, err := res.findOneForRequest(id, r, true)
+	if err != nil {
+		return kit.NewErrorResponse(err)
+	} else if model == nil {
+		return kit.NewErrorResponse("not_found", "")
+	}
+
+	if err := res.Restore(model, r.GetUser()); err != nil {
+		return kit.NewErrorResponse(err)
+	}
+
+	return res.addComputedFields(res.clearUnreadableFields(&kit.AppResponse{
+		Data: model,
+	}, r.GetUser()), r)
+}
+
+func (res *Resource) Purge(obj kit.Model, user kit.User) apperror.Error {
+	return res.transaction(func(res *Resource) apperror.Error {
+		if err := res.backend.Delete(obj); err != nil {
+			return err
+		}
+		if err := res.deleteGrants(obj); err != nil {
+			return err
+		}
+
+		res.afterCommit(func() {
+			res.clearCache(obj)
+			res.trigger(kit.EventResourceDeleted, obj)
+		})
+		return nil
+	})
+}
+
+// ApiPurge permanently removes a model. Models the row policy excludes are
+// not found.
+func (res *Resource) ApiPurge(id string, r kit.Request) kit.Response {
+	model, err := res.findOneForRequest(id, r, true)
+	if err != nil {
+		return kit.NewErrorResponse(err)
+	} else if model == nil {
+		return kit.NewErrorResponse("not_found", "")
+	}
+
+	if err := res.Purge(model, r.GetUser()); err != nil {
+		return kit.NewErrorResponse(err)
+	}
+
+	return &kit.AppResponse{}
+}
+
 // ReadOnlyResource is a resource mixin that prevents all create/update/delete
 // actions via the API.
 type ReadOnlyResource struct{}
@@ -0,0 +1,47 @@
+package resources_test
+
+import (
+	db "github.com/theduke/go-dukedb"
+	"github.com/theduke/go-dukedb/backends/memory"
+
+	. "github.com/app-kit/go-appkit/resources"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Article struct {
+	db.IntIdModel
+
+	Title string `db:"max:100"`
+	Body  string
+}
+
+func (Article) Collection() string {
+	return "articles"
+}
+
+var _ = Describe("Resource", func() {
+	var res *Resource
+
+	BeforeEach(func() {
+		backend := memory.New()
+		res = NewResource(&Article{}, nil, true)
+		res.SetBackend(backend)
+		backend.Build()
+	})
+
+	It("Should only change the set fields in partial updates", func() {
+		article := &Article{Title: "title", Body: "body"}
+		Expect(res.Create(article, nil)).To(BeNil())
+
+		update := &Article{Title: "changed"}
+		Expect(update.SetStrId(article.GetStrId())).To(BeNil())
+		Expect(res.PartialUpdate(update, nil)).To(BeNil())
+
+		stored, err := res.FindOne(article.GetId())
+		Expect(err).To(BeNil())
+		Expect(stored.(*Article).Title).To(Equal("changed"))
+		Expect(stored.(*Article).Body).To(Equal("body"))
+	})
+})
@@ -0,0 +1,79 @@
+package resources
+
+import (
+	"fmt"
+
+	"github.com/theduke/go-apperror"
+	db "github.com/theduke/go-dukedb"
+	expr "github.com/theduke/go-dukedb/expressions"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// rowPolicy returns the row policy filter for the request, or nil if the
+// resource has no policy or the request was made by the system.
+func (res *Resource) rowPolicy(r kit.Request) expr.Expression {
+	hook, ok := res.hooks.(RowPolicyHook)
+	if !ok || r.GetContext().IsSystem() {
+		return nil
+	}
+	return hook.RowPolicy(res, r.GetUser())
+}
+
+// applyRowPolicy adds the row policy filter of the request to the query.
+func (res *Resource) applyRowPolicy(query *db.Query, r kit.Request) {
+	if filter := res.rowPolicy(r); filter != nil {
+		query.FilterExpr(filter)
+	}
+}
+
+// findOneForRequest finds a model by id, restricted by the row policy of the
+// request. Models the policy excludes are not found.
+func (res *Resource) findOneForRequest(rawId interface{}, r kit.Request, includeDeleted bool) (kit.Model, apperror.Error) {
+	// The models of a bulk update are preloaded through the row policy.
+	if res.preloadedRequest != nil && res.preloadedRequest == r && !includeDeleted {
+		return res.preloadedModels[fmt.Sprint(rawId)], nil
+	}
+
+	filter := res.rowPolicy(r)
+	if filter == nil {
+		if includeDeleted {
+			return res.FindOneIncludeDeleted(rawId)
+		}
+		return res.FindOne(rawId)
+	}
+
+	query := res.QIncludeDeleted()
+	if res.IsSoftDelete() && !includeDeleted {
+		excludeDeleted(query)
+	}
+	query.Filter(res.modelInfo.PkAttribute().BackendName(), rawId).FilterExpr(filter).Limit(1)
+
+	models, err := res.Query(query)
+	if err != nil || len(models) == 0 {
+		return nil, err
+	}
+	return models[0], nil
+}
+
+// FindOneForRequest finds a model that is not deleted, restricted by the row
+// policy of the request.
+func (res *Resource) FindOneForRequest(rawId interface{}, r kit.Request) (kit.Model, apperror.Error) {
+	return res.findOneForRequest(rawId, r, false)
+}
+
+// checkRowPolicy returns a not_found error if the row policy of the request
+// excludes the stored version of a model that is updated.
+func (res *Resource) checkRowPolicy(obj kit.Model, r kit.Request) apperror.Error {
+	if res.rowPolicy(r) == nil {
+		return nil
+	}
+
+	model, err := res.findOneForRequest(obj.GetId(), r, false)
+	if err != nil {
+		return err
+	} else if model == nil {
+		return apperror.New("not_found", "", true)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package resources_test
+
+import (
+	db "github.com/theduke/go-dukedb"
+	"github.com/theduke/go-dukedb/backends/memory"
+	expr "github.com/theduke/go-dukedb/expressions"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/resources"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Post struct {
+	db.IntIdModel
+	SoftDeletable
+
+	Title     string `db:"max:100"`
+	Published bool
+}
+
+func (Post) Collection() string {
+	return "posts"
+}
+
+type PostHooks struct{}
+
+func (PostHooks) RowPolicy(res kit.Resource, user kit.User) expr.Expression {
+	return expr.Eq("", "published", true)
+}
+
+var _ = Describe("Row policies", func() {
+	var res *Resource
+
+	BeforeEach(func() {
+		backend := memory.New()
+		res = NewResource(&Post{}, PostHooks{}, true)
+		res.SetBackend(backend)
+		backend.Build()
+	})
+
+	create := func(title string, published bool) *Post {
+		post := &Post{Title: title, Published: published}
+		Expect(res.Create(post, nil)).To(BeNil())
+		return post
+	}
+
+	It("Should count only the models the policy allows", func() {
+		create("first", true)
+		create("second", true)
+		create("draft", false)
+
+		response := res.ApiCount(nil, kit.NewRequest())
+		Expect(response.GetError()).To(BeNil())
+		Expect(response.GetData()).To(Equal(map[string]interface{}{"count": 2}))
+	})
+
+	It("Should not restore models the policy excludes", func() {
+		draft := create("draft", false)
+		Expect(res.Delete(draft, nil)).To(BeNil())
+
+		response := res.ApiRestore(draft.GetStrId(), kit.NewRequest())
+		Expect(response.GetError()).ToNot(BeNil())
+		Expect(response.GetError().GetCode()).To(Equal("not_found"))
+	})
+
+	It("Should restore models the policy allows", func() {
+		post := create("post", true)
+		Expect(res.Delete(post, nil)).To(BeNil())
+
+		response := res.ApiRestore(post.GetStrId(), kit.NewRequest())
+		Expect(response.GetError()).To(BeNil())
+
+		restored, err := res.FindOne(post.GetId())
+		Expect(err).To(BeNil())
+		Expect(restored).ToNot(BeNil())
+	})
+})
@@ -0,0 +1,196 @@
+package resources
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/theduke/go-reflector"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// schemaType returns the schema type of a Go type. The names match the
+// argument types of methods.
+func schemaType(typ reflect.Type) string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == reflect.TypeOf(time.Time{}) {
+		return "datetime"
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return string(kit.ArgumentTypeString)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return string(kit.ArgumentTypeInt)
+	case reflect.Float32, reflect.Float64:
+		return string(kit.ArgumentTypeFloat)
+	case reflect.Bool:
+		return string(kit.ArgumentTypeBool)
+	case reflect.Map, reflect.Struct:
+		return string(kit.ArgumentTypeMap)
+	case reflect.Slice, reflect.Array:
+		return string(kit.ArgumentTypeList)
+	}
+	return string(kit.ArgumentTypeAny)
+}
+
+// applyTagConstraints sets the constraints of the db and validate tags of a
+// struct field on the attribute schema.
+func applyTagConstraints(schema *kit.AttributeSchema, field reflect.StructField) {
+	rules := append(parseRules(field.Name, field.Tag.Get("db")), parseRules(field.Name, field.Tag.Get("validate"))...)
+	for _, rule := range rules {
+		switch rule.Rule {
+		case "required":
+			schema.Required = true
+		case "unique":
+			schema.Unique = true
+		case "min", "max":
+			value, err := strconv.ParseFloat(rule.Param, 64)
+			if err != nil {
+				continue
+			}
+			if rule.Rule == "min" {
+				schema.Min = &value
+			} else {
+				schema.Max = &value
+			}
+		case "default":
+			schema.Default = rule.Param
+			if converted, err := reflector.R(rule.Param).ConvertTo(field.Type); err == nil {
+				schema.Default = converted
+			}
+		}
+	}
+}
+
+// allowedOperations checks the allow hooks of the resource with a new model
+// that is owned by the user.
+func allowedOperations(res kit.Resource, user kit.User) *kit.OperationsSchema {
+	model := res.CreateModel()
+	if userModel, ok := model.(kit.UserModel); ok && user != nil {
+		userModel.SetUserId(user.GetId())
+	}
+
+	hooks := res.Hooks()
+	ops := &kit.OperationsSchema{
+		Find:   true,
+		Create: true,
+		Update: true,
+		Delete: true,
+	}
+	if hook, ok := hooks.(AllowFindHook); ok {
+		ops.Find = hook.AllowFind(res, model, user)
+	}
+	if hook, ok := hooks.(AllowCreateHook); ok {
+		ops.Create = hook.AllowCreate(res, model, user)
+	}
+	if hook, ok := hooks.(AllowUpdateHook); ok {
+		ops.Update = hook.AllowUpdate(res, model, model, user)
+	}
+	if hook, ok := hooks.(AllowDeleteHook); ok {
+		ops.Delete = hook.AllowDelete(res, model, user)
+	}
+	return ops
+}
+
+// Schema describes the attributes, relationships and computed fields of a
+// resource, and the operations the user may perform.
+// Attributes and computed fields the user may not read are omitted.
+func Schema(res kit.Resource, user kit.User) *kit.ResourceSchema {
+	model := res.CreateModel()
+	unreadable := make(map[string]bool)
+	for _, name := range res.UnreadableFields(model, user) {
+		unreadable[name] = true
+	}
+	for _, name := range res.UnreadableComputedFields(model, user) {
+		unreadable[name] = true
+	}
+
+	schema := buildSchema(res, unreadable)
+	schema.Operations = allowedOperations(res, user)
+	return schema
+}
+
+// ModelSchema describes all attributes, relationships and computed fields of
+// a resource, independent of a user. The operations are not described.
+func ModelSchema(res kit.Resource) *kit.ResourceSchema {
+	return buildSchema(res, nil)
+}
+
+func buildSchema(res kit.Resource, unreadable map[string]bool) *kit.ResourceSchema {
+	info := res.ModelInfo()
+	pk := info.PkAttribute()
+	modelType := reflect.TypeOf(res.Model()).Elem()
+
+	schema := &kit.ResourceSchema{
+		Collection: res.Collection(),
+		Attributes: []*kit.AttributeSchema{
+			{Name: "id", Type: schemaType(pk.Type()), ReadOnly: true},
+		},
+		Relations: make([]*kit.RelationSchema, 0),
+	}
+
+	attributes := make([]*kit.AttributeSchema, 0)
+	for _, attr := range info.Attributes() {
+		if attr == pk || unreadable[attr.Name()] {
+			continue
+		}
+
+		attrSchema := &kit.AttributeSchema{
+			Name: attr.MarshalName(),
+			Type: schemaType(attr.Type()),
+		}
+		if field, ok := modelType.FieldByName(attr.Name()); ok {
+			applyTagConstraints(attrSchema, field)
+		}
+		attributes = append(attributes, attrSchema)
+	}
+	for _, name := range res.ComputedFieldNames() {
+		if unreadable[name] {
+			continue
+		}
+		attributes = append(attributes, &kit.AttributeSchema{
+			Name:     name,
+			Type:     string(kit.ArgumentTypeAny),
+			ReadOnly: true,
+		})
+	}
+	sort.Sort(attributesByName(attributes))
+	schema.Attributes = append(schema.Attributes, attributes...)
+
+	for _, rel := range info.Relations() {
+		if unreadable[rel.Name()] {
+			continue
+		}
+
+		cardinality := "one"
+		if rel.IsMany() {
+			cardinality = "many"
+		}
+		schema.Relations = append(schema.Relations, &kit.RelationSchema{
+			Name:        rel.MarshalName(),
+			Collection:  rel.RelatedModel().Collection(),
+			Cardinality: cardinality,
+		})
+	}
+	sort.Sort(relationsByName(schema.Relations))
+
+	return schema
+}
+
+type attributesByName []*kit.AttributeSchema
+
+func (a attributesByName) Len() int           { return len(a) }
+func (a attributesByName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a attributesByName) Less(i, j int) bool { return a[i].Name < a[j].Name }
+
+type relationsByName []*kit.RelationSchema
+
+func (r relationsByName) Len() int           { return len(r) }
+func (r relationsByName) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r relationsByName) Less(i, j int) bool { return r[i].Name < r[j].Name }
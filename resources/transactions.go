@@ -0,0 +1,122 @@
+package resources
+
+import (
+	"sync"
+
+	"github.com/theduke/go-apperror"
+	db "github.com/theduke/go-dukedb"
+)
+
+// Transaction wraps a backend transaction and runs callbacks once it is
+// committed, like cache invalidation and events, which must not happen
+// for writes that are rolled back.
+type Transaction struct {
+	db.Transaction
+
+	lock        sync.Mutex
+	afterCommit []func()
+}
+
+// Ensure Transaction implements db.Transaction.
+var _ db.Transaction = (*Transaction)(nil)
+
+// NewTransaction wraps a transaction. Transactions that are started outside
+// of resources should be wrapped, so that resources can defer their
+// callbacks until the commit.
+func NewTransaction(tx db.Transaction) *Transaction {
+	if wrapped, ok := tx.(*Transaction); ok {
+		return wrapped
+	}
+	return &Transaction{Transaction: tx}
+}
+
+// AfterCommit registers a callback that runs after a successful commit.
+func (t *Transaction) AfterCommit(fn func()) {
+	t.lock.Lock()
+	t.afterCommit = append(t.afterCommit, fn)
+	t.lock.Unlock()
+}
+
+// Commit commits the transaction and runs the callbacks.
+func (t *Transaction) Commit() apperror.Error {
+	if err := t.Transaction.Commit(); err != nil {
+		t.reset()
+		return err
+	}
+
+	for _, fn := range t.reset() {
+		fn()
+	}
+	return nil
+}
+
+// Rollback rolls the transaction back and discards the callbacks.
+func (t *Transaction) Rollback() apperror.Error {
+	t.reset()
+	return t.Transaction.Rollback()
+}
+
+func (t *Transaction) reset() []func() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	callbacks := t.afterCommit
+	t.afterCommit = nil
+	return callbacks
+}
+
+// afterCommit runs fn once the transaction of the resource is committed, or
+// immediately if the resource is not used inside a wrapped transaction.
+func (res *Resource) afterCommit(fn func()) {
+	if tx, ok := res.backend.(*Transaction); ok {
+		tx.AfterCommit(fn)
+		return
+	}
+	fn()
+}
+
+// beginTransaction starts a transaction if the backend supports transactions
+// and the resource is not already used inside a transaction.
+// Returns nil if no transaction was started.
+func (res *Resource) beginTransaction() (db.Transaction, apperror.Error) {
+	if _, ok := res.backend.(db.Transaction); ok {
+		return nil, nil
+	}
+
+	backend, ok := res.backend.(db.TransactionBackend)
+	if !ok {
+		return nil, nil
+	}
+
+	tx, err := backend.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return NewTransaction(tx), nil
+}
+
+// transaction runs a write and its hooks inside a transaction, if possible.
+// The hooks receive a resource that uses the transaction as its backend.
+// Any error rolls the transaction back.
+func (res *Resource) transaction(fn func(res *Resource) apperror.Error) apperror.Error {
+	tx, err := res.beginTransaction()
+	if err != nil {
+		return err
+	} else if tx == nil {
+		return fn(res)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(res.WithBackend(tx).(*Resource)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,65 @@
+package resources_test
+
+import (
+	"github.com/theduke/go-apperror"
+	db "github.com/theduke/go-dukedb"
+
+	. "github.com/app-kit/go-appkit/resources"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeTransaction only implements commit and rollback.
+type fakeTransaction struct {
+	db.Transaction
+
+	committed  bool
+	rolledBack bool
+}
+
+func (t *fakeTransaction) Commit() apperror.Error {
+	t.committed = true
+	return nil
+}
+
+func (t *fakeTransaction) Rollback() apperror.Error {
+	t.rolledBack = true
+	return nil
+}
+
+var _ = Describe("Transactions", func() {
+	It("Should run the callbacks after the commit", func() {
+		raw := &fakeTransaction{}
+		tx := NewTransaction(raw)
+
+		ran := false
+		tx.AfterCommit(func() {
+			Expect(raw.committed).To(BeTrue())
+			ran = true
+		})
+		Expect(ran).To(BeFalse())
+
+		Expect(tx.Commit()).To(BeNil())
+		Expect(ran).To(BeTrue())
+	})
+
+	It("Should discard the callbacks on rollback", func() {
+		raw := &fakeTransaction{}
+		tx := NewTransaction(raw)
+
+		ran := false
+		tx.AfterCommit(func() {
+			ran = true
+		})
+
+		Expect(tx.Rollback()).To(BeNil())
+		Expect(raw.rolledBack).To(BeTrue())
+		Expect(ran).To(BeFalse())
+	})
+
+	It("Should not wrap a transaction twice", func() {
+		tx := NewTransaction(&fakeTransaction{})
+		Expect(NewTransaction(tx)).To(BeIdenticalTo(tx))
+	})
+})
@@ -0,0 +1,332 @@
+package resources
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/theduke/go-apperror"
+	"github.com/theduke/go-reflector"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// Validator checks the value of a field, given by its struct field name.
+// param is the parameter of the rule, like 200 for max:200.
+type Validator func(res kit.Resource, model kit.Model, field string, value interface{}, param string) bool
+
+// ValidationRule validates a field of a model.
+type ValidationRule struct {
+	// Field is the struct field name.
+	Field string
+
+	// Rule is the name of the rule, reported in errors. If Validator is not
+	// set, the registered validator with this name is used.
+	Rule  string
+	Param string
+
+	// Message overrides the default error message.
+	Message string
+
+	// When makes the rule conditional. The rule is only checked if When
+	// returns true.
+	When func(model kit.Model) bool
+
+	Validator Validator
+}
+
+var validators = map[string]Validator{
+	"required": func(res kit.Resource, model kit.Model, field string, value interface{}, param string) bool {
+		return !isEmpty(value)
+	},
+	"email": func(res kit.Resource, model kit.Model, field string, value interface{}, param string) bool {
+		str := fmt.Sprintf("%v", value)
+		addr, err := mail.ParseAddress(str)
+		return err == nil && addr.Address == str
+	},
+	"url": func(res kit.Resource, model kit.Model, field string, value interface{}, param string) bool {
+		u, err := url.Parse(fmt.Sprintf("%v", value))
+		return err == nil && u.Scheme != "" && u.Host != ""
+	},
+	"min": func(res kit.Resource, model kit.Model, field string, value interface{}, param string) bool {
+		min, err := strconv.ParseFloat(param, 64)
+		return err == nil && valueSize(value) >= min
+	},
+	"max": func(res kit.Resource, model kit.Model, field string, value interface{}, param string) bool {
+		max, err := strconv.ParseFloat(param, 64)
+		return err == nil && valueSize(value) <= max
+	},
+	"regex": func(res kit.Resource, model kit.Model, field string, value interface{}, param string) bool {
+		matched, err := regexp.MatchString(param, fmt.Sprintf("%v", value))
+		return err == nil && matched
+	},
+	"in": func(res kit.Resource, model kit.Model, field string, value interface{}, param string) bool {
+		str := fmt.Sprintf("%v", value)
+		for _, option := range strings.Split(param, ",") {
+			if option == str {
+				return true
+			}
+		}
+		return false
+	},
+}
+
+// RegisterValidator registers a validator that can be used in validate
+// tags and rules by its name.
+func RegisterValidator(name string, validator Validator) {
+	validators[name] = validator
+}
+
+var validationMessages = map[string]string{
+	"required": "%v is required",
+	"email":    "%v must be a valid email address",
+	"url":      "%v must be a valid url",
+	"min":      "%v must be at least %v",
+	"max":      "%v must be at most %v",
+	"regex":    "%v has an invalid format",
+	"in":       "%v must be one of %v",
+	"unique":   "%v is already taken",
+}
+
+func isEmpty(value interface{}) bool {
+	return value == nil || reflector.R(value).IsZero()
+}
+
+// valueSize returns the length of strings, slices and maps, and the value of
+// numbers.
+func valueSize(value interface{}) float64 {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return 0
+}
+
+// isUnique checks that no other model of the collection has the value.
+// Soft deleted models are ignored.
+// The unique rule is checked by Validate instead of a Validator, so that
+// backend errors are not reported as taken values.
+func isUnique(res kit.Resource, model kit.Model, field string, value interface{}) (bool, apperror.Error) {
+	attr := res.ModelInfo().FindAttribute(field)
+	if attr == nil {
+		return false, nil
+	}
+
+	items, err := res.Query(res.Q().Filter(attr.BackendName(), value).Limit(2))
+	if err != nil {
+		return false, apperror.Wrap(err, "unique_check_failed", fmt.Sprintf("Could not check if %v is unique", field))
+	}
+
+	for _, other := range items {
+		if other.GetStrId() != model.GetStrId() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// parseRules parses rules like "required;max:200" from a struct tag.
+// Parameters may contain colons, but not semicolons.
+func parseRules(field, tag string) []*ValidationRule {
+	rules := make([]*ValidationRule, 0)
+	for _, part := range strings.Split(tag, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		rule := &ValidationRule{Field: field, Rule: part}
+		if index := strings.Index(part, ":"); index > -1 {
+			rule.Rule = part[:index]
+			rule.Param = part[index+1:]
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// requiredByDbTag returns true if the required rule of a db tag is
+// checked for fields of the kind.
+// For numbers and booleans, required only means not null in the backend, so
+// that 0 and false stay valid. Use the validate tag to reject zero values.
+func requiredByDbTag(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String, reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// tagRules builds the rules from the validate tags of the struct fields,
+// and from the required, min and max rules of the db tags.
+func tagRules(typ reflect.Type) []*ValidationRule {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	rules := make([]*ValidationRule, 0)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			rules = append(rules, tagRules(field.Type)...)
+			continue
+		}
+
+		for _, rule := range parseRules(field.Name, field.Tag.Get("db")) {
+			if rule.Rule == "required" && !requiredByDbTag(field.Type.Kind()) {
+				continue
+			}
+			if rule.Rule == "required" || rule.Rule == "min" || rule.Rule == "max" {
+				rules = append(rules, rule)
+			}
+		}
+
+		rules = append(rules, parseRules(field.Name, field.Tag.Get("validate"))...)
+	}
+
+	return rules
+}
+
+// FieldsEqual returns a rule that requires a field to have the same value as
+// another field, like a password confirmation.
+func FieldsEqual(field, other string) *ValidationRule {
+	return &ValidationRule{
+		Field:   field,
+		Rule:    "equals",
+		Param:   other,
+		Message: fmt.Sprintf("%v must be equal to %v", field, other),
+		Validator: func(res kit.Resource, model kit.Model, field string, value interface{}, param string) bool {
+			otherValue := reflect.ValueOf(model).Elem().FieldByName(param)
+			return otherValue.IsValid() && reflect.DeepEqual(value, otherValue.Interface())
+		},
+	}
+}
+
+// RequiredWhen returns a rule that requires a field if the condition is met.
+func RequiredWhen(field string, when func(model kit.Model) bool) *ValidationRule {
+	return &ValidationRule{
+		Field: field,
+		Rule:  "required",
+		When:  when,
+	}
+}
+
+func (res *Resource) fieldMarshalName(field string) string {
+	if res.modelInfo != nil {
+		if attr := res.modelInfo.FindAttribute(field); attr != nil {
+			return attr.MarshalName()
+		}
+		if relation := res.modelInfo.FindRelation(field); relation != nil {
+			return relation.MarshalName()
+		}
+	}
+	return field
+}
+
+func (res *Resource) fieldError(rule *ValidationRule) *kit.FieldError {
+	name := res.fieldMarshalName(rule.Field)
+
+	message := rule.Message
+	if message == "" {
+		if format, ok := validationMessages[rule.Rule]; ok {
+			if strings.Count(format, "%v") == 2 {
+				message = fmt.Sprintf(format, name, rule.Param)
+			} else {
+				message = fmt.Sprintf(format, name)
+			}
+		} else {
+			message = fmt.Sprintf("%v is invalid", name)
+		}
+	}
+
+	return kit.NewFieldError(name, rule.Rule, message)
+}
+
+// ValidationRules returns the rules from the struct tags of the model and
+// the ValidationRules hook.
+func (res *Resource) ValidationRules() []*ValidationRule {
+	rules := tagRules(reflect.TypeOf(res.model))
+	if hook, ok := res.hooks.(ValidationRulesHook); ok {
+		rules = append(rules, hook.ValidationRules(res)...)
+	}
+	return rules
+}
+
+// Validate checks all validation rules and returns a validation_failed error
+// with an invalid_field error for every failed rule.
+// Other rules of a field are skipped if it is empty or a rule failed.
+func (res *Resource) Validate(obj kit.Model) apperror.Error {
+	errs := make([]error, 0)
+	failed := make(map[string]bool)
+
+	modelValue := reflect.ValueOf(obj).Elem()
+
+	for _, rule := range res.ValidationRules() {
+		if failed[rule.Field] {
+			continue
+		}
+		if rule.When != nil && !rule.When(obj) {
+			continue
+		}
+
+		field := modelValue.FieldByName(rule.Field)
+		if !field.IsValid() {
+			continue
+		}
+		value := field.Interface()
+
+		// Only the required rule applies to empty values.
+		if rule.Rule != "required" && isEmpty(value) {
+			continue
+		}
+
+		validator := rule.Validator
+		if validator == nil {
+			validator = validators[rule.Rule]
+		}
+
+		valid := false
+		if validator == nil && rule.Rule == "unique" {
+			unique, err := isUnique(res, obj, rule.Field, value)
+			if err != nil {
+				return err
+			}
+			valid = unique
+		} else if validator == nil {
+			return &apperror.Err{
+				Code:    "unknown_validator",
+				Message: fmt.Sprintf("The validator %v of field %v does not exist", rule.Rule, rule.Field),
+			}
+		} else {
+			valid = validator(res, obj, rule.Field, value, rule.Param)
+		}
+
+		if !valid {
+			errs = append(errs, res.fieldError(rule))
+			failed[rule.Field] = true
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &apperror.Err{
+		Code:    "validation_failed",
+		Message: fmt.Sprintf("The %v model is invalid", res.Collection()),
+		Errors:  errs,
+		Public:  true,
+		Status:  422,
+	}
+}
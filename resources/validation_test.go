@@ -0,0 +1,83 @@
+package resources_test
+
+import (
+	db "github.com/theduke/go-dukedb"
+	"github.com/theduke/go-dukedb/backends/memory"
+
+	. "github.com/app-kit/go-appkit/resources"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type ValidatedPage struct {
+	db.IntIdModel
+
+	Title     string `db:"required;max:20"`
+	Weight    int    `db:"required"`
+	Published bool   `db:"required"`
+	Priority  int    `validate:"required"`
+}
+
+func (ValidatedPage) Collection() string {
+	return "validated_pages"
+}
+
+type UniqueTag struct {
+	db.IntIdModel
+	SoftDeletable
+
+	Slug string `validate:"unique"`
+}
+
+func (UniqueTag) Collection() string {
+	return "unique_tags"
+}
+
+var _ = Describe("Validation", func() {
+	var res *Resource
+
+	BeforeEach(func() {
+		backend := memory.New()
+		res = NewResource(&ValidatedPage{}, nil, true)
+		res.SetBackend(backend)
+		backend.Build()
+	})
+
+	It("Should accept zero numbers and booleans required by the db tag", func() {
+		page := &ValidatedPage{Title: "home", Priority: 1}
+		Expect(res.Validate(page)).To(BeNil())
+	})
+
+	It("Should reject empty strings required by the db tag", func() {
+		page := &ValidatedPage{Priority: 1}
+		err := res.Validate(page)
+		Expect(err).ToNot(BeNil())
+		Expect(err.GetCode()).To(Equal("validation_failed"))
+		Expect(err.GetErrors()).To(HaveLen(1))
+	})
+
+	It("Should reject zero numbers required by the validate tag", func() {
+		page := &ValidatedPage{Title: "home"}
+		err := res.Validate(page)
+		Expect(err).ToNot(BeNil())
+		Expect(err.GetErrors()).To(HaveLen(1))
+	})
+
+	It("Should ignore soft deleted models when checking unique fields", func() {
+		backend := memory.New()
+		res := NewResource(&UniqueTag{}, nil, true)
+		res.SetBackend(backend)
+		backend.Build()
+
+		tag := &UniqueTag{Slug: "go"}
+		Expect(res.Create(tag, nil)).To(BeNil())
+
+		err := res.Validate(&UniqueTag{Slug: "go"})
+		Expect(err).ToNot(BeNil())
+		Expect(err.GetCode()).To(Equal("validation_failed"))
+
+		Expect(res.Delete(tag, nil)).To(BeNil())
+		Expect(res.Validate(&UniqueTag{Slug: "go"})).To(BeNil())
+	})
+})
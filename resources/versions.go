@@ -0,0 +1,299 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/theduke/go-apperror"
+	db "github.com/theduke/go-dukedb"
+
+	kit "github.com/app-kit/go-appkit"
+)
+
+// ModelVersion is a snapshot of a model stored by versioned resources.
+// The versions of a resource are stored in the <collection>_versions
+// collection.
+type ModelVersion struct {
+	db.IntIdModel
+
+	collection string
+
+	ModelId   string                 `db:"required;max:255"`
+	Version   int                    `db:"required"`
+	Data      map[string]interface{} `db:"marshal"`
+	UserId    string                 `db:"max:255"`
+	CreatedAt time.Time
+}
+
+// Ensure ModelVersion implements kit.ModelVersion.
+var _ kit.ModelVersion = (*ModelVersion)(nil)
+
+func (v *ModelVersion) Collection() string {
+	return v.collection
+}
+
+func (v *ModelVersion) GetModelId() string {
+	return v.ModelId
+}
+
+func (v *ModelVersion) GetVersion() int {
+	return v.Version
+}
+
+func (v *ModelVersion) GetData() map[string]interface{} {
+	return v.Data
+}
+
+func (v *ModelVersion) GetUserId() string {
+	return v.UserId
+}
+
+func (v *ModelVersion) GetCreatedAt() time.Time {
+	return v.CreatedAt
+}
+
+type versionsByNumber []kit.ModelVersion
+
+func (v versionsByNumber) Len() int           { return len(v) }
+func (v versionsByNumber) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
+func (v versionsByNumber) Less(i, j int) bool { return v[i].GetVersion() < v[j].GetVersion() }
+
+// VersionsCollection returns the collection that stores the versions of
+// models in the given collection.
+func VersionsCollection(collection string) string {
+	return collection + "_versions"
+}
+
+func (res *Resource) IsVersioned() bool {
+	return res.versioned
+}
+
+func (res *Resource) SetVersioned(versioned bool) {
+	res.versioned = versioned
+	if versioned && res.backend != nil {
+		res.registerVersionModel()
+	}
+}
+
+func (res *Resource) registerVersionModel() {
+	collection := VersionsCollection(res.Collection())
+	if !res.backend.HasCollection(collection) {
+		res.backend.RegisterModel(&ModelVersion{collection: collection})
+	}
+}
+
+// snapshot converts a model to a map with its json representation.
+func snapshot(obj kit.Model) (map[string]interface{}, apperror.Error) {
+	js, err := json.Marshal(obj)
+	if err != nil {
+		return nil, apperror.Wrap(err, "json_marshal_error")
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(js, &data); err != nil {
+		return nil, apperror.Wrap(err, "json_unmarshal_error")
+	}
+	return data, nil
+}
+
+// saveVersion stores a snapshot of the model as a new version.
+func (res *Resource) saveVersion(obj kit.Model, user kit.User) apperror.Error {
+	if !res.versioned {
+		return nil
+	}
+
+	// The number is taken from a count query instead of loading all
+	// versions. It runs in the transaction of the write.
+	count, err := res.backend.Q(VersionsCollection(res.Collection())).Filter("model_id", obj.GetStrId()).Count()
+	if err != nil {
+		return err
+	}
+
+	data, err := snapshot(obj)
+	if err != nil {
+		return err
+	}
+
+	version := &ModelVersion{
+		collection: VersionsCollection(res.Collection()),
+		ModelId:    obj.GetStrId(),
+		Version:    count + 1,
+		Data:       data,
+		CreatedAt:  time.Now(),
+	}
+	if user != nil {
+		version.UserId = user.GetStrId()
+	}
+
+	return res.backend.Create(version)
+}
+
+func (res *Resource) Versions(id interface{}) ([]kit.ModelVersion, apperror.Error) {
+	if !res.versioned {
+		return nil, apperror.New("versioning_disabled", fmt.Sprintf("The collection %v is not versioned", res.Collection()), true)
+	}
+
+	collection := VersionsCollection(res.Collection())
+	items, err := res.backend.Q(collection).Filter("model_id", fmt.Sprintf("%v", id)).Find()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]kit.ModelVersion, 0)
+	for _, item := range items {
+		version := item.(*ModelVersion)
+		version.collection = collection
+		versions = append(versions, version)
+	}
+	sort.Sort(versionsByNumber(versions))
+
+	return versions, nil
+}
+
+func (res *Resource) Version(id interface{}, number int) (kit.ModelVersion, apperror.Error) {
+	if !res.versioned {
+		return nil, apperror.New("versioning_disabled", fmt.Sprintf("The collection %v is not versioned", res.Collection()), true)
+	}
+
+	collection := VersionsCollection(res.Collection())
+	item, err := res.backend.Q(collection).Filter("model_id", fmt.Sprintf("%v", id)).Filter("version", number).First()
+	if err != nil {
+		return nil, err
+	} else if item == nil {
+		return nil, nil
+	}
+
+	version := item.(*ModelVersion)
+	version.collection = collection
+	return version, nil
+}
+
+// copySnapshotFields copies the fields contained in the json snapshot data
+// from src to dst. Fields hidden from json, like password hashes, are not
+// part of snapshots and keep their value.
+func copySnapshotFields(dst, src reflect.Value, data map[string]interface{}) {
+	typ := dst.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			// Unexported field.
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		parts := strings.Split(tag, ",")
+		if parts[0] == "-" {
+			continue
+		}
+
+		name := field.Name
+		if parts[0] != "" {
+			name = parts[0]
+		}
+		omitEmpty := false
+		for _, option := range parts[1:] {
+			omitEmpty = omitEmpty || option == "omitempty"
+		}
+
+		// The fields of embedded structs are part of the snapshot itself.
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			copySnapshotFields(dst.Field(i), src.Field(i), data)
+			continue
+		}
+
+		// Empty values of omitempty fields are not part of the snapshot.
+		if _, ok := data[name]; ok || omitEmpty {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+}
+
+// Revert restores the fields of the snapshot of a version on the stored
+// model and updates it.
+// Fields the user may not write keep their current value, or fail the
+// revert if their policy rejects writes.
+func (res *Resource) Revert(id interface{}, number int, user kit.User) (kit.Model, apperror.Error) {
+	version, err := res.Version(id, number)
+	if err != nil {
+		return nil, err
+	} else if version == nil {
+		return nil, apperror.New("unknown_version", fmt.Sprintf("Version %v does not exist", number), true)
+	}
+
+	js, jsonErr := json.Marshal(version.GetData())
+	if jsonErr != nil {
+		return nil, apperror.Wrap(jsonErr, "json_marshal_error")
+	}
+
+	snapshotModel := res.CreateModel()
+	if err := json.Unmarshal(js, snapshotModel); err != nil {
+		return nil, apperror.Wrap(err, "json_unmarshal_error")
+	}
+
+	obj, err := res.FindOne(version.GetModelId())
+	if err != nil {
+		return nil, err
+	} else if obj == nil {
+		return nil, apperror.New("not_found", fmt.Sprintf("The model %v does not exist", version.GetModelId()), true)
+	}
+
+	// Keep a copy of the stored model for the write policies.
+	stored := reflect.New(reflect.TypeOf(obj).Elem())
+	stored.Elem().Set(reflect.ValueOf(obj).Elem())
+	old := stored.Interface().(kit.Model)
+
+	copySnapshotFields(reflect.ValueOf(obj).Elem(), reflect.ValueOf(snapshotModel).Elem(), version.GetData())
+	if err := obj.SetStrId(version.GetModelId()); err != nil {
+		return nil, apperror.Wrap(err, "invalid_id")
+	}
+
+	// The snapshot contains the lock token of the old version, which would
+	// be rejected as a conflict.
+	if lockModel, ok := obj.(kit.OptimisticLockModel); ok {
+		if err := lockModel.SetLockToken(old.(kit.OptimisticLockModel).GetLockToken()); err != nil {
+			return nil, apperror.Wrap(err, "invalid_lock_token")
+		}
+	}
+
+	if err := res.checkWritableFields(obj, old, user, false); err != nil {
+		return nil, err
+	}
+
+	if err := res.Update(obj, user); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// FieldChange describes the change of a single field between two versions.
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// DiffVersions returns the fields that differ between two versions.
+func DiffVersions(from, to kit.ModelVersion) map[string]*FieldChange {
+	return DiffVersionData(from.GetData(), to.GetData())
+}
+
+// DiffVersionData returns the fields that differ between two snapshots.
+func DiffVersionData(fromData, toData map[string]interface{}) map[string]*FieldChange {
+	changes := make(map[string]*FieldChange)
+
+	for field, val := range fromData {
+		if newVal, ok := toData[field]; !ok || !reflect.DeepEqual(val, newVal) {
+			changes[field] = &FieldChange{From: val, To: newVal}
+		}
+	}
+	for field, val := range toData {
+		if _, ok := fromData[field]; !ok {
+			changes[field] = &FieldChange{To: val}
+		}
+	}
+
+	return changes
+}
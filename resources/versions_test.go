@@ -0,0 +1,123 @@
+package resources_test
+
+import (
+	db "github.com/theduke/go-dukedb"
+	"github.com/theduke/go-dukedb/backends/memory"
+
+	kit "github.com/app-kit/go-appkit"
+	. "github.com/app-kit/go-appkit/resources"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type LockedNote struct {
+	db.IntIdModel
+	VersionLock
+
+	Title string `db:"max:100"`
+}
+
+func (LockedNote) Collection() string {
+	return "locked_notes"
+}
+
+type SecretNote struct {
+	db.IntIdModel
+
+	Title  string `db:"max:100" json:"title"`
+	Status string `db:"max:100" json:"status"`
+	Secret string `db:"max:100" json:"-"`
+}
+
+func (SecretNote) Collection() string {
+	return "secret_notes"
+}
+
+type SecretNoteHooks struct{}
+
+func (SecretNoteHooks) FieldPolicies(res kit.Resource) map[string]*FieldPolicy {
+	return map[string]*FieldPolicy{
+		"Status": &FieldPolicy{
+			Write: &FieldAccess{Roles: []string{"admin"}},
+		},
+	}
+}
+
+var _ = Describe("Versions", func() {
+	var res *Resource
+
+	BeforeEach(func() {
+		backend := memory.New()
+		res = NewResource(&LockedNote{}, nil, true)
+		res.SetVersioned(true)
+		res.SetBackend(backend)
+		backend.Build()
+	})
+
+	It("Should revert to an older version of a locked model", func() {
+		note := &LockedNote{Title: "first"}
+		Expect(res.Create(note, nil)).To(BeNil())
+
+		note.Title = "second"
+		Expect(res.Update(note, nil)).To(BeNil())
+		Expect(note.LockVersion).To(Equal(2))
+
+		reverted, err := res.Revert(note.GetId(), 1, nil)
+		Expect(err).To(BeNil())
+		Expect(reverted.(*LockedNote).Title).To(Equal("first"))
+		Expect(reverted.(*LockedNote).LockVersion).To(Equal(3))
+
+		stored, err := res.FindOne(note.GetId())
+		Expect(err).To(BeNil())
+		Expect(stored.(*LockedNote).Title).To(Equal("first"))
+	})
+
+	It("Should keep hidden and unwritable fields when reverting", func() {
+		backend := memory.New()
+		res := NewResource(&SecretNote{}, SecretNoteHooks{}, true)
+		res.SetVersioned(true)
+		res.SetBackend(backend)
+		backend.Build()
+
+		note := &SecretNote{Title: "first", Status: "draft", Secret: "hash"}
+		Expect(res.Create(note, nil)).To(BeNil())
+
+		note.Title = "second"
+		note.Status = "published"
+		note.Secret = "new hash"
+		Expect(res.Update(note, nil)).To(BeNil())
+
+		reverted, err := res.Revert(note.GetId(), 1, nil)
+		Expect(err).To(BeNil())
+
+		stored, err := res.FindOne(note.GetId())
+		Expect(err).To(BeNil())
+		for _, model := range []kit.Model{reverted, stored} {
+			Expect(model.(*SecretNote).Title).To(Equal("first"))
+			Expect(model.(*SecretNote).Status).To(Equal("published"))
+			Expect(model.(*SecretNote).Secret).To(Equal("new hash"))
+		}
+
+		versions, err := res.Versions(note.GetId())
+		Expect(err).To(BeNil())
+		Expect(versions).To(HaveLen(3))
+		Expect(versions[2].GetVersion()).To(Equal(3))
+	})
+
+	It("Should still reject stale updates", func() {
+		note := &LockedNote{Title: "first"}
+		Expect(res.Create(note, nil)).To(BeNil())
+
+		stale := &LockedNote{Title: "stale"}
+		Expect(stale.SetStrId(note.GetStrId())).To(BeNil())
+		stale.LockVersion = note.LockVersion
+
+		note.Title = "second"
+		Expect(res.Update(note, nil)).To(BeNil())
+
+		err := res.Update(stale, nil)
+		Expect(err).ToNot(BeNil())
+		Expect(err.GetCode()).To(Equal("conflict"))
+	})
+})
@@ -0,0 +1,345 @@
+package resources
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/theduke/go-apperror"
+)
+
+// XlsxContentType is the content type of xlsx files.
+const XlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// maxXlsxPartSize limits the uncompressed size of a part of an imported
+// xlsx file.
+const maxXlsxPartSize = 200 * 1024 * 1024
+
+var xlsxStaticParts = []struct {
+	name    string
+	content string
+}{
+	{
+		"[Content_Types].xml",
+		`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`,
+	},
+	{
+		"_rels/.rels",
+		`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`,
+	},
+	{
+		"xl/workbook.xml",
+		`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets>` +
+			`</workbook>`,
+	},
+	{
+		"xl/_rels/workbook.xml.rels",
+		`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`,
+	},
+}
+
+// XlsxWriter streams rows as an xlsx file with a single sheet.
+// All cells are written as strings, like in the csv export.
+type XlsxWriter struct {
+	zip   *zip.Writer
+	sheet io.Writer
+	rows  int
+	err   error
+}
+
+// NewXlsxWriter starts an xlsx file. Close must be called to finish it.
+func NewXlsxWriter(w io.Writer) *XlsxWriter {
+	writer := &XlsxWriter{zip: zip.NewWriter(w)}
+
+	for _, part := range xlsxStaticParts {
+		partWriter, err := writer.zip.Create(part.name)
+		if err != nil {
+			writer.err = err
+			return writer
+		}
+		if _, err := io.WriteString(partWriter, part.content); err != nil {
+			writer.err = err
+			return writer
+		}
+	}
+
+	// The sheet is written last, so that it can be streamed.
+	writer.sheet, writer.err = writer.zip.Create("xl/worksheets/sheet1.xml")
+	if writer.err == nil {
+		_, writer.err = io.WriteString(writer.sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+
+			`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	}
+
+	return writer
+}
+
+// xlsxColumn returns the name of a column, like A for 0 and AA for 26.
+func xlsxColumn(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// Write writes a row.
+func (w *XlsxWriter) Write(record []string) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	w.rows++
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<row r="%v">`, w.rows)
+	for index, value := range record {
+		fmt.Fprintf(&buf, `<c r="%v%v" t="inlineStr"><is><t xml:space="preserve">`, xlsxColumn(index), w.rows)
+		xml.EscapeText(&buf, []byte(value))
+		buf.WriteString(`</t></is></c>`)
+	}
+	buf.WriteString(`</row>`)
+
+	_, w.err = w.sheet.Write(buf.Bytes())
+	return w.err
+}
+
+// Flush flushes the written rows to the underlying writer. Errors are
+// reported by Error, like with csv.Writer.
+func (w *XlsxWriter) Flush() {
+	if w.err == nil {
+		w.err = w.zip.Flush()
+	}
+}
+
+// Error returns the first error that occurred while writing.
+func (w *XlsxWriter) Error() error {
+	return w.err
+}
+
+// Close finishes the sheet and the file.
+func (w *XlsxWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if _, err := io.WriteString(w.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return w.zip.Close()
+}
+
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	parts := make([]string, 0, len(t.Runs))
+	for _, run := range t.Runs {
+		parts = append(parts, run.Text)
+	}
+	return strings.Join(parts, "")
+}
+
+type xlsxCell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Value  string   `xml:"v"`
+	Inline xlsxText `xml:"is"`
+}
+
+type xlsxRow struct {
+	Ref   int        `xml:"r,attr"`
+	Cells []xlsxCell `xml:"c"`
+}
+
+type xlsxSheet struct {
+	Rows []xlsxRow `xml:"sheetData>row"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Id string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		Id     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+func invalidXlsx(err error) apperror.Error {
+	return apperror.Wrap(err, "invalid_xlsx", "The xlsx file is invalid", true)
+}
+
+// readXlsxPart decodes a part of the file. Missing parts return false.
+func readXlsxPart(files map[string]*zip.File, name string, target interface{}) (bool, apperror.Error) {
+	file, ok := files[name]
+	if !ok {
+		return false, nil
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return false, invalidXlsx(err)
+	}
+	defer reader.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(reader, maxXlsxPartSize+1))
+	if err != nil {
+		return false, invalidXlsx(err)
+	} else if len(data) > maxXlsxPartSize {
+		return false, apperror.New("xlsx_too_large", "The xlsx file is too large", true)
+	}
+
+	if err := xml.Unmarshal(data, target); err != nil {
+		return false, invalidXlsx(err)
+	}
+	return true, nil
+}
+
+// firstSheetPath returns the path of the first sheet of the workbook.
+func firstSheetPath(files map[string]*zip.File) (string, apperror.Error) {
+	var workbook xlsxWorkbook
+	if ok, err := readXlsxPart(files, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	} else if !ok || len(workbook.Sheets) == 0 {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+
+	var rels xlsxRelationships
+	if _, err := readXlsxPart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.Id != workbook.Sheets[0].Id {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+
+	return "xl/worksheets/sheet1.xml", nil
+}
+
+// cellColumn returns the column index of a cell reference like C5, or -1.
+func cellColumn(ref string) int {
+	column := 0
+	letters := 0
+	for _, char := range ref {
+		if char < 'A' || char > 'Z' {
+			break
+		}
+		column = column*26 + int(char-'A') + 1
+		letters++
+	}
+	if letters == 0 {
+		return -1
+	}
+	return column - 1
+}
+
+// ReadXlsx reads the rows of the first sheet of an xlsx file.
+// Missing cells and rows are returned as empty strings and rows.
+func ReadXlsx(reader io.ReaderAt, size int64) ([][]string, apperror.Error) {
+	archive, err := zip.NewReader(reader, size)
+	if err != nil {
+		return nil, invalidXlsx(err)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var shared xlsxSharedStrings
+	if _, err := readXlsxPart(files, "xl/sharedStrings.xml", &shared); err != nil {
+		return nil, err
+	}
+
+	sheetPath, err2 := firstSheetPath(files)
+	if err2 != nil {
+		return nil, err2
+	}
+
+	var sheet xlsxSheet
+	if ok, err := readXlsxPart(files, sheetPath, &sheet); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, apperror.New("invalid_xlsx", "The xlsx file has no sheet", true)
+	}
+
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		// Skipped row numbers are empty rows.
+		for row.Ref > len(rows)+1 {
+			rows = append(rows, []string{""})
+		}
+
+		record := make([]string, 0, len(row.Cells))
+		for _, cell := range row.Cells {
+			if column := cellColumn(cell.Ref); column > len(record) {
+				for len(record) < column {
+					record = append(record, "")
+				}
+			}
+
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(cell.Value)
+				if err != nil || index < 0 || index >= len(shared.Items) {
+					return nil, apperror.New("invalid_xlsx", "The xlsx file references an unknown string", true)
+				}
+				value = shared.Items[index].String()
+			case "inlineStr":
+				value = cell.Inline.String()
+			case "b":
+				value = strconv.FormatBool(cell.Value == "1")
+			}
+			record = append(record, value)
+		}
+		if len(record) == 0 {
+			record = append(record, "")
+		}
+		rows = append(rows, record)
+	}
+
+	if len(rows) == 0 {
+		return nil, apperror.New("invalid_xlsx", "The xlsx file has no header", true)
+	}
+	return rows, nil
+}
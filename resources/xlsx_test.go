@@ -0,0 +1,36 @@
+package resources_test
+
+import (
+	"bytes"
+
+	. "github.com/app-kit/go-appkit/resources"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Xlsx", func() {
+	It("Should read the rows of a written file", func() {
+		var buf bytes.Buffer
+		writer := NewXlsxWriter(&buf)
+		Expect(writer.Write([]string{"id", "name"})).To(BeNil())
+		Expect(writer.Write([]string{"1", "<john> & \"jane\""})).To(BeNil())
+		Expect(writer.Write([]string{"2", "", "extra"})).To(BeNil())
+		Expect(writer.Close()).To(BeNil())
+
+		rows, err := ReadXlsx(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		Expect(err).To(BeNil())
+		Expect(rows).To(Equal([][]string{
+			{"id", "name"},
+			{"1", "<john> & \"jane\""},
+			{"2", "", "extra"},
+		}))
+	})
+
+	It("Should reject files that are not xlsx", func() {
+		data := []byte("id,name\n1,john\n")
+		_, err := ReadXlsx(bytes.NewReader(data), int64(len(data)))
+		Expect(err).ToNot(BeNil())
+		Expect(err.GetCode()).To(Equal("invalid_xlsx"))
+	})
+})
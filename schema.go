@@ -0,0 +1,55 @@
+package appkit
+
+// AttributeSchema describes an attribute of a model.
+type AttributeSchema struct {
+	// Name is the marshal name of the attribute.
+	Name string `json:"name"`
+
+	// Type is string, int, float, bool, datetime, map, list or any.
+	Type string `json:"type"`
+
+	Required bool     `json:"required,omitempty"`
+	Unique   bool     `json:"unique,omitempty"`
+	Min      *float64 `json:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty"`
+
+	Default interface{} `json:"default,omitempty"`
+
+	// ReadOnly attributes like the id and computed fields can not be set by
+	// clients.
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// RelationSchema describes a relationship of a model.
+type RelationSchema struct {
+	// Name is the marshal name of the relationship.
+	Name       string `json:"name"`
+	Collection string `json:"collection"`
+
+	// Cardinality is one or many.
+	Cardinality string `json:"cardinality"`
+}
+
+// OperationsSchema lists the operations a user may perform on the models of
+// a resource.
+// Update and delete refer to models the user owns.
+type OperationsSchema struct {
+	Find   bool `json:"find"`
+	Create bool `json:"create"`
+	Update bool `json:"update"`
+	Delete bool `json:"delete"`
+}
+
+// ResourceSchema describes a resource.
+type ResourceSchema struct {
+	Collection string             `json:"collection"`
+	Attributes []*AttributeSchema `json:"attributes"`
+	Relations  []*RelationSchema  `json:"relations"`
+	Operations *OperationsSchema  `json:"operations"`
+}
+
+// Schema describes the public resources and the methods of an app.
+type Schema struct {
+	Resources []*ResourceSchema `json:"resources"`
+	Methods   []*MethodInfo     `json:"methods"`
+}
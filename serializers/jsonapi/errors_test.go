@@ -0,0 +1,106 @@
+package jsonapi_test
+
+import (
+	"github.com/theduke/go-apperror"
+	db "github.com/theduke/go-dukedb"
+	"github.com/theduke/go-dukedb/backends/memory"
+
+	kit "github.com/app-kit/go-appkit"
+	"github.com/app-kit/go-appkit/resources"
+	. "github.com/app-kit/go-appkit/serializers/jsonapi"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Signup struct {
+	db.IntIdModel
+
+	Name     string `db:"required" json:"name"`
+	Email    string `validate:"email" json:"email"`
+	Website  string `validate:"url" json:"website"`
+	Bio      string `validate:"min:5" json:"bio"`
+	Nick     string `db:"max:3" json:"nick"`
+	Slug     string `validate:"regex:^[a-z]+$" json:"slug"`
+	Plan     string `validate:"in:free,pro" json:"plan"`
+	Username string `validate:"unique" json:"username"`
+}
+
+func (Signup) Collection() string {
+	return "signups"
+}
+
+// pointers returns the source pointers of the serialized errors.
+func pointers(errs []*ApiError) []string {
+	result := make([]string, 0)
+	for _, err := range errs {
+		if err.Source != nil {
+			result = append(result, err.Source.Pointer)
+		}
+	}
+	return result
+}
+
+var _ = Describe("Errors", func() {
+	var res *resources.Resource
+
+	BeforeEach(func() {
+		backend := memory.New()
+		res = resources.NewResource(&Signup{}, nil, true)
+		res.SetBackend(backend)
+		backend.Build()
+
+		Expect(res.Create(&Signup{Name: "taken", Username: "taken"}, nil)).To(BeNil())
+	})
+
+	valid := func() *Signup {
+		return &Signup{Name: "name"}
+	}
+
+	cases := map[string]struct {
+		field  string
+		modify func(s *Signup)
+	}{
+		"required": {"name", func(s *Signup) { s.Name = "" }},
+		"email":    {"email", func(s *Signup) { s.Email = "invalid" }},
+		"url":      {"website", func(s *Signup) { s.Website = "invalid" }},
+		"min":      {"bio", func(s *Signup) { s.Bio = "bio" }},
+		"max":      {"nick", func(s *Signup) { s.Nick = "nickname" }},
+		"regex":    {"slug", func(s *Signup) { s.Slug = "Invalid Slug" }},
+		"in":       {"plan", func(s *Signup) { s.Plan = "enterprise" }},
+		"unique":   {"username", func(s *Signup) { s.Username = "taken" }},
+	}
+
+	for rule, c := range cases {
+		rule, c := rule, c
+
+		It("Should point to the field that failed the "+rule+" rule", func() {
+			signup := valid()
+			c.modify(signup)
+
+			err := res.Validate(signup)
+			Expect(err).ToNot(BeNil())
+
+			errs := SerializeError(err)
+			Expect(pointers(errs)).To(Equal([]string{"/data/attributes/" + c.field}))
+		})
+	}
+
+	It("Should point to fields of field errors", func() {
+		errs := SerializeError(kit.NewFieldError("title", "custom", "Title is invalid"))
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Code).To(Equal("invalid_field"))
+		Expect(errs[0].Source.Pointer).To(Equal("/data/attributes/title"))
+	})
+
+	It("Should not point to fields of other errors with field data", func() {
+		err := &apperror.Err{
+			Code:   "invalid_field",
+			Data:   map[string]interface{}{"field": "title"},
+			Public: true,
+		}
+		errs := SerializeError(err)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Source).To(BeNil())
+	})
+})
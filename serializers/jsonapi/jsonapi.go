@@ -3,6 +3,7 @@ package jsonapi
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/theduke/go-apperror"
@@ -12,15 +13,21 @@ import (
 	kit "github.com/app-kit/go-appkit"
 )
 
+type ApiErrorSource struct {
+	Pointer string `json:"pointer,omitempty"`
+}
+
 type ApiError struct {
-	Code    string `json:"code,omitempty"`
-	Message string `json:"message,omitempty"`
+	Code    string          `json:"code,omitempty"`
+	Message string          `json:"message,omitempty"`
+	Source  *ApiErrorSource `json:"source,omitempty"`
 }
 
 type ApiData struct {
 	Data     interface{}            `json:"data"`
 	Included []*ApiModel            `json:"included,omitempty"`
 	Meta     map[string]interface{} `json:"meta,omitempty"`
+	Links    map[string]interface{} `json:"links,omitempty"`
 	Errors   []*ApiError            `json:"errors,omitempty"`
 }
 
@@ -104,6 +111,67 @@ func (d *ApiData) ReduceIncludedDuplicates() {
 	d.Included = cleanedModels
 }
 
+// Models returns the models in the data and the included models.
+func (d *ApiData) Models() []*ApiModel {
+	models := make([]*ApiModel, 0)
+	switch data := d.Data.(type) {
+	case *ApiModel:
+		models = append(models, data)
+	case []interface{}:
+		for _, item := range data {
+			if model, ok := item.(*ApiModel); ok {
+				models = append(models, model)
+			}
+		}
+	}
+	return append(models, d.Included...)
+}
+
+// AddComputedFields adds computed values to the attributes of the models.
+// The values are keyed by collection, model id and field name.
+func (d *ApiData) AddComputedFields(computed map[string]map[string]map[string]interface{}) {
+	for _, model := range d.Models() {
+		values, ok := computed[model.Type][model.Id]
+		if !ok {
+			continue
+		}
+
+		if model.Attributes == nil {
+			model.Attributes = make(map[string]interface{})
+		}
+		for name, value := range values {
+			model.Attributes[name] = value
+		}
+	}
+}
+
+// ApplyFieldsets removes all attributes and relationships that are not in
+// the sparse fieldset of the model type.
+func (d *ApiData) ApplyFieldsets(fieldsets map[string][]string) {
+	for _, model := range d.Models() {
+		fields, ok := fieldsets[model.Type]
+		if !ok {
+			continue
+		}
+
+		allowed := make(map[string]bool)
+		for _, field := range fields {
+			allowed[field] = true
+		}
+
+		for name := range model.Attributes {
+			if !allowed[name] {
+				delete(model.Attributes, name)
+			}
+		}
+		for name := range model.Relationships {
+			if !allowed[name] {
+				delete(model.Relationships, name)
+			}
+		}
+	}
+}
+
 func (d *ApiData) AddError(errs ...apperror.Error) {
 	for _, err := range errs {
 		d.Errors = append(d.Errors, SerializeError(err)...)
@@ -403,10 +471,17 @@ func SerializeError(err error) []*ApiError {
 			errs = append(errs, &ApiError{Code: "internal_server_error"})
 		} else {
 			// Not an internal error, show details.
-			errs = append(errs, &ApiError{
+			apiErr := &ApiError{
 				Code:    appError.GetCode(),
 				Message: appError.GetMessage(),
-			})
+			}
+
+			// Point validation errors to the invalid attribute.
+			if fieldErr, ok := err.(*kit.FieldError); ok && fieldErr.Field != "" {
+				apiErr.Source = &ApiErrorSource{Pointer: "/data/attributes/" + fieldErr.Field}
+			}
+
+			errs = append(errs, apiErr)
 		}
 
 		// Add any additional errors.
@@ -422,6 +497,10 @@ func SerializeError(err error) []*ApiError {
 
 type Serializer struct {
 	backends map[string]db.Backend
+
+	// readOnly holds the attributes of each collection that are ignored
+	// when unserializing models, like computed fields.
+	readOnly map[string]map[string]bool
 }
 
 // Ensure Serializer implements kit.Serializer.
@@ -430,11 +509,21 @@ var _ kit.Serializer = (*Serializer)(nil)
 func New(backends map[string]db.Backend) *Serializer {
 	s := &Serializer{
 		backends: backends,
+		readOnly: make(map[string]map[string]bool),
 	}
 
 	return s
 }
 
+// SetReadOnlyAttributes sets attributes of a collection that are ignored
+// when unserializing models.
+func (s *Serializer) SetReadOnlyAttributes(collection string, names []string) {
+	s.readOnly[collection] = make(map[string]bool)
+	for _, name := range names {
+		s.readOnly[collection][name] = true
+	}
+}
+
 func (s *Serializer) Name() string {
 	return "jsonapi"
 }
@@ -472,6 +561,33 @@ func (s *Serializer) SerializeModel(model kit.Model) (interface{}, []interface{}
 	return m, rawExtra, nil
 }
 
+// setLockToken sets the lock_token from the meta of the model data.
+func setLockToken(model kit.Model, rawData interface{}) apperror.Error {
+	lockModel, ok := model.(kit.OptimisticLockModel)
+	if !ok {
+		return nil
+	}
+	data, _ := rawData.(map[string]interface{})
+	meta, _ := data["meta"].(map[string]interface{})
+
+	var token string
+	switch val := meta["lock_token"].(type) {
+	case nil:
+		return nil
+	case string:
+		token = val
+	case float64:
+		token = strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		return apperror.New("invalid_lock_token", "meta.lock_token must be a string", true)
+	}
+
+	if err := lockModel.SetLockToken(token); err != nil {
+		return apperror.Wrap(err, "invalid_lock_token", "meta.lock_token is invalid", true)
+	}
+	return nil
+}
+
 func (s *Serializer) UnserializeModel(collection string, rawData interface{}) (kit.Model, apperror.Error) {
 	// Fill in collection if it is not set in data.
 	if data, ok := rawData.(map[string]interface{}); ok {
@@ -525,6 +641,10 @@ func (s *Serializer) UnserializeModel(collection string, rawData interface{}) (k
 
 	fieldData := make(map[string]interface{})
 	for key, val := range data.Attributes {
+		if s.readOnly[data.Type][key] {
+			continue
+		}
+
 		attr := info.FindAttribute(key)
 		if attr == nil {
 			return nil, &apperror.Err{
@@ -548,6 +668,12 @@ func (s *Serializer) UnserializeModel(collection string, rawData interface{}) (k
 		return nil, apperror.Wrap(err, "update_model_from_dict_error", "")
 	}
 
+	// The lock token can be sent in the meta of the model. Bulk updates use
+	// it instead of the If-Match header.
+	if err := setLockToken(model, rawData); err != nil {
+		return nil, err
+	}
+
 	// Now, try to handle relationships.
 	allRelations, err := data.GetRelations()
 	if err != nil {
@@ -705,7 +831,24 @@ func (s *Serializer) SerializeTransferData(transData kit.TransferData) (interfac
 	apiData.ReduceIncludedDuplicates()
 
 	// Handle metadata.
+	// Links in the metadata are moved to the top level links.
 	apiData.Meta = transData.GetMeta()
+	if links, ok := apiData.Meta["links"].(map[string]interface{}); ok {
+		apiData.Links = links
+		delete(apiData.Meta, "links")
+	}
+
+	// Computed fields are added to the attributes.
+	if computed, ok := apiData.Meta["computed"].(map[string]map[string]map[string]interface{}); ok {
+		apiData.AddComputedFields(computed)
+		delete(apiData.Meta, "computed")
+	}
+
+	// Sparse fieldsets restrict the attributes and relationships.
+	if fields, ok := apiData.Meta["fields"].(map[string][]string); ok {
+		apiData.ApplyFieldsets(fields)
+		delete(apiData.Meta, "fields")
+	}
 
 	// Handle Errors.
 	apiData.AddError(transData.GetErrors()...)
@@ -188,7 +188,7 @@ func (t TaskIntId) GetUserId() interface{} {
 	return t.UserId
 }
 
-func (t TaskIntId) SetUserId(id interface{}) {
+func (t *TaskIntId) SetUserId(id interface{}) {
 	t.UserId = id.(uint64)
 }
 
@@ -206,6 +206,6 @@ func (t TaskStrId) GetUserId() interface{} {
 	return t.UserId
 }
 
-func (t TaskStrId) SetUserId(id interface{}) {
+func (t *TaskStrId) SetUserId(id interface{}) {
 	t.UserId = id.(string)
 }
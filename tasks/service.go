@@ -30,6 +30,13 @@ func NewService(reg kit.Registry, b db.Backend) *Service {
 	return s
 }
 
+func (s *Service) NewTask() kit.Task {
+	if s.backend.HasStringIds() {
+		return &TaskStrId{}
+	}
+	return &TaskIntId{}
+}
+
 func (s *Service) Queue(task kit.Task) apperror.Error {
 	task.SetCreatedAt(time.Now())
 
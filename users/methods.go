@@ -6,35 +6,29 @@ import (
 	"github.com/app-kit/go-appkit/app/methods"
 )
 
+type authenticateArguments struct {
+	User     string                 `json:"user"`
+	Adaptor  string                 `json:"adaptor" arg:"required"`
+	AuthData map[string]interface{} `json:"authData" arg:"required"`
+}
+
 var AuthenticateMethod kit.Method = &methods.Method{
-	Name:     "users.authenticate",
-	Blocking: true,
+	Name:            "users.authenticate",
+	Blocking:        true,
+	ArgumentsStruct: authenticateArguments{},
 	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
 		if r.GetUser() != nil {
 			return kit.NewErrorResponse("already_authenticated", "Can't authenticate a session which is already authenticated", true)
 		}
 
-		data, ok := r.GetData().(map[string]interface{})
+		args, ok := r.GetData().(*authenticateArguments)
 		if !ok {
-			return kit.NewErrorResponse("invalid_data", "Invalid data: expected dict", true)
+			return kit.NewErrorResponse(methods.UnpreparedArgumentsError())
 		}
 
 		// Find user.
-
-		userIdentifier, _ := data["user"].(string)
-
-		adaptor, _ := data["adaptor"].(string)
-		if adaptor == "" {
-			return kit.NewErrorResponse("adaptor_missing", "Expected 'adaptor' in metadata.", true)
-		}
-
-		authData, _ := data["authData"].(map[string]interface{})
-		if authData == nil {
-			kit.NewErrorResponse("no_or_invalid_auth_data", "Expected 'authData' dictionary in metadata.")
-		}
-
 		userService := registry.UserService()
-		user, err := userService.AuthenticateUser(userIdentifier, adaptor, authData)
+		user, err := userService.AuthenticateUser(args.User, args.Adaptor, args.AuthData)
 		if err != nil {
 			return kit.NewErrorResponse(err)
 		}
@@ -60,16 +54,15 @@ var AuthenticateMethod kit.Method = &methods.Method{
 }
 
 var ResumeSessionMethod kit.Method = &methods.Method{
-	Name:     "users.resume_session",
-	Blocking: true,
+	Name:            "users.resume_session",
+	Blocking:        true,
+	ArgumentsStruct: tokenArguments{},
 	Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
-		registry.Logger().Infof("data: %v", r.GetData())
-		data, _ := r.GetData().(map[string]interface{})
-		token, _ := data["token"].(string)
-
-		if token == "" {
-			return kit.NewErrorResponse("no_token", "Expected 'token' in data.", true)
+		args, ok := r.GetData().(*tokenArguments)
+		if !ok {
+			return kit.NewErrorResponse(methods.UnpreparedArgumentsError())
 		}
+		token := args.Token
 
 		user, session, err := registry.UserService().VerifySession(token)
 		if err != nil {
@@ -107,7 +107,7 @@ type User struct {
 	Active bool
 
 	Username string `db:"unique;required"`
-	Email    string `db:"unique;required"`
+	Email    string `db:"unique;required" validate:"email"`
 
 	EmailConfirmed bool
 
@@ -11,7 +11,6 @@ import (
 	kit "github.com/app-kit/go-appkit"
 	"github.com/app-kit/go-appkit/app/methods"
 	"github.com/app-kit/go-appkit/resources"
-	"github.com/app-kit/go-appkit/utils"
 )
 
 // randomToken creates a random alphanumeric string with a length of 32.
@@ -155,6 +154,24 @@ func (SessionResourceHooks) ApiDelete(res kit.Resource, id string, r kit.Request
  * User resource.
  */
 
+type tokenArguments struct {
+	Token string `json:"token" arg:"required"`
+}
+
+type requestPasswordResetArguments struct {
+	User string `json:"user" arg:"required;description:Username or email address"`
+}
+
+type passwordResetArguments struct {
+	Token    string `json:"token" arg:"required"`
+	Password string `json:"password" arg:"required"`
+}
+
+type changePasswordArguments struct {
+	UserId   string `json:"userId" arg:"required"`
+	Password string `json:"password" arg:"required"`
+}
+
 type UserResourceHooks struct {
 }
 
@@ -184,22 +201,16 @@ func (UserResourceHooks) Methods(res kit.Resource) []kit.Method {
 	}
 
 	confirmEmail := &methods.Method{
-		Name:     "users.confirm-email",
-		Blocking: false,
+		Name:            "users.confirm-email",
+		Blocking:        false,
+		ArgumentsStruct: tokenArguments{},
 		Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
-			data, ok := r.GetData().(map[string]interface{})
+			args, ok := r.GetData().(*tokenArguments)
 			if !ok {
-				return kit.NewErrorResponse("invalid_data", "Expected data dict with 'token' key")
-			}
-			token, ok := data["token"].(string)
-			if !ok {
-				return kit.NewErrorResponse("invalid_data", "Expected 'token' string key in data")
-			}
-			if token == "" {
-				return kit.NewErrorResponse("empty_token", "")
+				return kit.NewErrorResponse(methods.UnpreparedArgumentsError())
 			}
 
-			_, err := registry.UserService().ConfirmEmail(token)
+			_, err := registry.UserService().ConfirmEmail(args.Token)
 			if err != nil {
 				return kit.NewErrorResponse("confirm_failed", "Could not confirm email")
 			}
@@ -211,18 +222,15 @@ func (UserResourceHooks) Methods(res kit.Resource) []kit.Method {
 	}
 
 	requestPwReset := &methods.Method{
-		Name:     "users.request-password-reset",
-		Blocking: false,
+		Name:            "users.request-password-reset",
+		Blocking:        false,
+		ArgumentsStruct: requestPasswordResetArguments{},
 		Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
-			data, ok := r.GetData().(map[string]interface{})
+			args, ok := r.GetData().(*requestPasswordResetArguments)
 			if !ok {
-				return kit.NewErrorResponse("invalid_data", "Expected data dict with 'user' key", true)
-			}
-
-			userIdentifier, ok := data["user"].(string)
-			if !ok {
-				return kit.NewErrorResponse("invalid_data", "Expected data dict with 'user' string key", true)
+				return kit.NewErrorResponse(methods.UnpreparedArgumentsError())
 			}
+			userIdentifier := args.User
 
 			rawUser, err := res.Q().Filter("email", userIdentifier).Or("username", userIdentifier).First()
 			if err != nil {
@@ -247,32 +255,16 @@ func (UserResourceHooks) Methods(res kit.Resource) []kit.Method {
 	}
 
 	pwReset := &methods.Method{
-		Name:     "users.password-reset",
-		Blocking: false,
+		Name:            "users.password-reset",
+		Blocking:        false,
+		ArgumentsStruct: passwordResetArguments{},
 		Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
-			// Verify that token is in data.
-			data, ok := r.GetData().(map[string]interface{})
-			if !ok {
-				return kit.NewErrorResponse("invalid_data", "Expected 'token' key in data", true)
-			}
-			token, ok := data["token"].(string)
+			args, ok := r.GetData().(*passwordResetArguments)
 			if !ok {
-				return kit.NewErrorResponse("invalid_data", "Expected 'token' string key in data", true)
-			}
-			if token == "" {
-				return kit.NewErrorResponse("empty_token", "", true)
+				return kit.NewErrorResponse(methods.UnpreparedArgumentsError())
 			}
 
-			// Verify that password is in data.
-			newPw, ok := data["password"].(string)
-			if !ok {
-				return kit.NewErrorResponse("invalid_passord", "Expected 'password' string key in data", true)
-			}
-			if newPw == "" {
-				return kit.NewErrorResponse("empty_password", "Password may not be empty", true)
-			}
-
-			user, err := registry.UserService().ResetPassword(token, newPw)
+			user, err := registry.UserService().ResetPassword(args.Token, args.Password)
 			if err != nil {
 				if err.IsPublic() {
 					return kit.NewErrorResponse(err)
@@ -292,18 +284,16 @@ func (UserResourceHooks) Methods(res kit.Resource) []kit.Method {
 	}
 
 	changePassword := &methods.Method{
-		Name:     "users.change-password",
-		Blocking: false,
+		Name:            "users.change-password",
+		Blocking:        false,
+		ArgumentsStruct: changePasswordArguments{},
 		Handler: func(registry kit.Registry, r kit.Request, unblock func()) kit.Response {
-			// Get userId and password from request.
-			userId := utils.GetMapStringKey(r.GetData(), "userId")
-			if userId == "" {
-				return kit.NewErrorResponse("no_userid", "Expected userId key in data", true)
-			}
-			password := utils.GetMapStringKey(r.GetData(), "password")
-			if password == "" {
-				return kit.NewErrorResponse("no_password", "Expected password key in data", true)
+			args, ok := r.GetData().(*changePasswordArguments)
+			if !ok {
+				return kit.NewErrorResponse(methods.UnpreparedArgumentsError())
 			}
+			userId := args.UserId
+			password := args.Password
 
 			// Permission check.
 			user := r.GetUser()
@@ -404,6 +394,32 @@ func (hooks UserResourceHooks) ApiCreate(res kit.Resource, obj kit.Model, r kit.
 	}
 }
 
+func (hooks UserResourceHooks) FieldPolicies(res kit.Resource) map[string]*resources.FieldPolicy {
+	admins := []string{"admin"}
+
+	return map[string]*resources.FieldPolicy{
+		"Email": {
+			Read: &resources.FieldAccess{Owner: true, Roles: admins},
+		},
+		"Roles": {
+			Write: &resources.FieldAccess{Roles: admins},
+		},
+	}
+}
+
+// QueryPolicy prevents clients from filtering by fields like the email,
+// which would reveal fields they may not read.
+func (hooks UserResourceHooks) QueryPolicy(res kit.Resource) *resources.QueryPolicy {
+	return &resources.QueryPolicy{
+		DefaultLimit: 100,
+		MaxLimit:     1000,
+		FilterFields: []string{"id", "username", "active"},
+		SortFields:   []string{"id", "username", "created_at", "updated_at"},
+		JoinFields:   []string{"profile", "roles"},
+		MaxJoinDepth: 1,
+	}
+}
+
 func (hooks UserResourceHooks) AllowFind(res kit.Resource, obj kit.Model, user kit.User) bool {
 	/*
 		u := obj.(kit.User)
@@ -296,30 +296,46 @@ func (s *Service) CreateUser(user kit.User, adaptorName string, authData map[str
 		user.SetProfile(profile)
 	}
 
-	if err := s.Users.Create(user, nil); err != nil {
-		return err
-	}
+	// The user, the profile and the auth item are created in a transaction.
+	// Without transaction support, created models are deleted on failure.
+	err = s.registry.WithTransaction(func(tx db.Backend) error {
+		_, isTransaction := tx.(db.Transaction)
+		users := s.Users.WithBackend(tx)
 
-	// Create profile if one exists.
-
-	if profile != nil {
-		profile.SetUser(user)
-		if err := s.Profiles.Create(profile, user); err != nil {
-			s.Users.Backend().Delete(user)
-			return apperror.Wrap(err, "user_profile_create_error", "Could not create the user profile")
+		if err := users.Create(user, nil); err != nil {
+			return err
 		}
-	}
 
-	// Persist auth item.
-	if authItemUserId, ok := authItem.(kit.UserModel); ok {
-		authItemUserId.SetUserId(user.GetId())
-	}
-	if err := s.Users.Backend().Create(authItem); err != nil {
-		s.Users.Backend().Delete(user)
+		// Create profile if one exists.
+
 		if profile != nil {
-			s.Profiles.Backend().Delete(profile)
+			profile.SetUser(user)
+			if err := s.Profiles.WithBackend(tx).Create(profile, user); err != nil {
+				if !isTransaction {
+					tx.Delete(user)
+				}
+				return apperror.Wrap(err, "user_profile_create_error", "Could not create the user profile")
+			}
 		}
-		return apperror.Wrap(err, "auth_item_create_error", "")
+
+		// Persist auth item.
+		if authItemUserId, ok := authItem.(kit.UserModel); ok {
+			authItemUserId.SetUserId(user.GetId())
+		}
+		if err := tx.Create(authItem); err != nil {
+			if !isTransaction {
+				tx.Delete(user)
+				if profile != nil {
+					tx.Delete(profile)
+				}
+			}
+			return apperror.Wrap(err, "auth_item_create_error", "")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	if err := s.SendConfirmationEmail(user); err != nil {
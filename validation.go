@@ -0,0 +1,34 @@
+package appkit
+
+import (
+	"github.com/theduke/go-apperror"
+)
+
+// FieldError is the error of a single field that failed a validation rule.
+// Serializers use the field to point to the invalid value.
+type FieldError struct {
+	*apperror.Err
+
+	// Field is the marshal name of the field.
+	Field string
+
+	// Rule is the name of the failed rule.
+	Rule string
+}
+
+// NewFieldError returns a public invalid_field error for the field and rule.
+func NewFieldError(field, rule, message string) *FieldError {
+	return &FieldError{
+		Err: &apperror.Err{
+			Code:    "invalid_field",
+			Message: message,
+			Data: map[string]interface{}{
+				"field": field,
+				"rule":  rule,
+			},
+			Public: true,
+		},
+		Field: field,
+		Rule:  rule,
+	}
+}